@@ -0,0 +1,476 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package bundle provides helpers for loading policy bundles distributed as OCI artifacts.
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	policyv1 "github.com/cerbos/cerbos/api/genpb/cerbos/policy/v1"
+	"go.uber.org/multierr"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+const (
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+
+	defaultRegistry = "registry-1.docker.io"
+	defaultTag      = "latest"
+	maxBlobSize     = 64 * 1024 * 1024 // 64MiB
+)
+
+// OCIAuth holds the credentials used to authenticate to the registry.
+// If Token is set, it's sent as a bearer token. Otherwise, if Username is set, the username and password are used
+// for basic authentication or to obtain a token from the token service advertised by the registry.
+// The zero value makes anonymous requests.
+type OCIAuth struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// Opt configures LoadBundleFromOCI.
+type Opt func(*loader)
+
+// WithHTTPClient sets the HTTP client used to talk to the registry.
+func WithHTTPClient(client *http.Client) Opt {
+	return func(l *loader) {
+		l.httpClient = client
+	}
+}
+
+// WithPlainHTTP configures the loader to connect to the registry over plain HTTP instead of HTTPS.
+// It's intended for local registries used for development and testing.
+func WithPlainHTTP() Opt {
+	return func(l *loader) {
+		l.scheme = "http"
+	}
+}
+
+// WithSignatureVerificationKey requires the bundle to carry a valid cosign signature made with the private key
+// corresponding to the given ECDSA public key. Signatures are looked up using the cosign tag convention
+// (sha256-<manifest digest>.sig) in the same repository as the bundle.
+func WithSignatureVerificationKey(key *ecdsa.PublicKey) Opt {
+	return func(l *loader) {
+		l.verifyKey = key
+	}
+}
+
+// LoadBundleFromOCI pulls the OCI artifact identified by ref from a registry and loads the policies contained in it.
+// The reference has the form [registry/]repository[:tag|@digest]. If the registry is omitted, Docker Hub is used,
+// and if neither a tag nor a digest is given, the latest tag is used.
+//
+// The artifact layers must be tar archives (optionally gzip-compressed) of policy files, laid out in the same way as a
+// policy directory on disk. Files that are not policies, such as schemas and test suites, are ignored. The digest of every
+// downloaded blob is checked against the manifest. Compiled bundles produced by Cerbos Hub are encrypted and use a binary
+// format that the SDK can't decode, so they are not supported.
+//
+// If a signature verification key is provided using WithSignatureVerificationKey, the artifact must be signed with the
+// corresponding private key and the signature is verified before any policies are loaded. Otherwise, signatures are not checked.
+func LoadBundleFromOCI(ctx context.Context, ref string, auth OCIAuth, opts ...Opt) ([]*policyv1.Policy, error) {
+	r, err := parseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &loader{httpClient: http.DefaultClient, scheme: "https", auth: auth, ref: r}
+	for _, o := range opts {
+		o(l)
+	}
+
+	m, digest, err := l.fetchManifest(ctx, r.reference())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest of %s: %w", ref, err)
+	}
+
+	if r.digest != "" && r.digest != digest {
+		return nil, fmt.Errorf("manifest digest %s does not match the requested digest %s", digest, r.digest)
+	}
+
+	if l.verifyKey != nil {
+		if err := l.verifySignature(ctx, digest); err != nil {
+			return nil, fmt.Errorf("failed to verify signature of %s: %w", ref, err)
+		}
+	}
+
+	var policies []*policyv1.Policy
+	for _, layer := range m.Layers {
+		if !strings.Contains(layer.MediaType, "tar") {
+			continue
+		}
+
+		blob, err := l.fetchBlob(ctx, layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch layer %s: %w", layer.Digest, err)
+		}
+
+		lp, err := readPolicies(blob, strings.HasSuffix(layer.MediaType, "gzip"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policies from layer %s: %w", layer.Digest, err)
+		}
+
+		policies = append(policies, lp...)
+	}
+
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("no policies found in %s", ref)
+	}
+
+	return policies, nil
+}
+
+type reference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+func (r reference) reference() string {
+	if r.digest != "" {
+		return r.digest
+	}
+
+	return r.tag
+}
+
+func parseReference(ref string) (reference, error) {
+	var r reference
+	name := ref
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, r.digest = name[:i], name[i+1:]
+		if !strings.HasPrefix(r.digest, "sha256:") {
+			return r, fmt.Errorf("invalid reference %q: unsupported digest algorithm", ref)
+		}
+	}
+
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		name, r.tag = name[:i], name[i+1:]
+	}
+
+	if r.tag == "" && r.digest == "" {
+		r.tag = defaultTag
+	}
+
+	r.registry = defaultRegistry
+	if i := strings.Index(name, "/"); i >= 0 {
+		if host := name[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			r.registry, name = host, name[i+1:]
+		}
+	}
+
+	if r.registry == defaultRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	if name == "" {
+		return r, fmt.Errorf("invalid reference %q: missing repository", ref)
+	}
+
+	r.repository = name
+	return r, nil
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Size        int64             `json:"size"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+}
+
+type loader struct {
+	httpClient *http.Client
+	verifyKey  *ecdsa.PublicKey
+	ref        reference
+	scheme     string
+	token      string
+	auth       OCIAuth
+}
+
+func (l *loader) fetchManifest(ctx context.Context, reference string) (*manifest, string, error) {
+	resp, err := l.get(ctx, "manifests/"+reference, mediaTypeOCIManifest+", "+mediaTypeDockerManifest)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := readLimited(resp.Body, maxBlobSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(body, m); err != nil {
+		return nil, "", fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return m, digestOf(body), nil
+}
+
+func (l *loader) fetchBlob(ctx context.Context, desc descriptor) ([]byte, error) {
+	if desc.Size > maxBlobSize {
+		return nil, fmt.Errorf("blob size %d exceeds the maximum of %d bytes", desc.Size, maxBlobSize)
+	}
+
+	resp, err := l.get(ctx, "blobs/"+desc.Digest, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	blob, err := readLimited(resp.Body, maxBlobSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if got := digestOf(blob); got != desc.Digest {
+		return nil, fmt.Errorf("digest mismatch: expected %s, got %s", desc.Digest, got)
+	}
+
+	return blob, nil
+}
+
+var errNotFound = errors.New("not found")
+
+func (l *loader) get(ctx context.Context, p, accept string) (*http.Response, error) {
+	u := fmt.Sprintf("%s://%s/v2/%s/%s", l.scheme, l.ref.registry, l.ref.repository, p)
+
+	do := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		switch {
+		case l.auth.Token != "":
+			req.Header.Set("Authorization", "Bearer "+l.auth.Token)
+		case l.token != "":
+			req.Header.Set("Authorization", "Bearer "+l.token)
+		case l.auth.Username != "":
+			req.SetBasicAuth(l.auth.Username, l.auth.Password)
+		}
+
+		return l.httpClient.Do(req)
+	}
+
+	resp, err := do()
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && l.auth.Token == "" && l.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+			return nil, fmt.Errorf("unauthorized: %s", u)
+		}
+
+		if l.token, err = l.fetchToken(ctx, challenge); err != nil {
+			return nil, fmt.Errorf("failed to obtain registry token: %w", err)
+		}
+
+		if resp, err = do(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", u, errNotFound)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response from %s: %s", u, resp.Status)
+	}
+}
+
+// fetchToken obtains a bearer token from the token service described in the WWW-Authenticate challenge.
+func (l *loader) fetchToken(ctx context.Context, challenge string) (string, error) {
+	params := parseChallenge(challenge[len("bearer "):])
+	realm := params["realm"]
+	if realm == "" {
+		return "", errors.New("authentication challenge is missing the realm")
+	}
+
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid realm %q: %w", realm, err)
+	}
+
+	q := u.Query()
+	for _, k := range []string{"service", "scope"} {
+		if v := params[k]; v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if l.auth.Username != "" {
+		req.SetBasicAuth(l.auth.Username, l.auth.Password)
+	}
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response from token service: %s", resp.Status)
+	}
+
+	var tokenResp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBlobSize)).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	if tokenResp.Token != "" {
+		return tokenResp.Token, nil
+	}
+
+	if tokenResp.AccessToken != "" {
+		return tokenResp.AccessToken, nil
+	}
+
+	return "", errors.New("token service returned an empty token")
+}
+
+// parseChallenge parses the comma-separated key="value" parameters of an authentication challenge.
+func parseChallenge(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			break
+		}
+
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimSpace(s[eq+1:])
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, s = s[1:end+1], s[end+2:]
+		} else if comma := strings.Index(s, ","); comma >= 0 {
+			value, s = s[:comma], s[comma:]
+		} else {
+			value, s = s, ""
+		}
+
+		params[key] = value
+		s = strings.TrimLeft(s, ", ")
+	}
+
+	return params
+}
+
+func readPolicies(blob []byte, gzipped bool) ([]*policyv1.Policy, error) {
+	var src io.Reader = bytes.NewReader(blob)
+	if gzipped {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress layer: %w", err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	var policies []*policyv1.Policy
+	var errs error
+	tr := tar.NewReader(src)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg || !includeFile(hdr.Name) {
+			continue
+		}
+
+		p, err := internal.ReadPolicy(tr)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to read policy from %s: %w", hdr.Name, err))
+			continue
+		}
+
+		internal.SetPolicyProvenance(p, hdr.Name)
+		policies = append(policies, p)
+	}
+
+	return policies, errs
+}
+
+func includeFile(name string) bool {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	dirs := strings.Split(path.Dir(name), "/")
+	for _, d := range dirs {
+		if d != "." && internal.SkipPolicyDir(d) {
+			return false
+		}
+	}
+
+	return internal.IsPolicyFile(name)
+}
+
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("response exceeds the maximum size of %d bytes", limit)
+	}
+
+	return b, nil
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,238 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/bundle"
+)
+
+const (
+	resourcePolicy = `---
+apiVersion: api.cerbos.dev/v1
+resourcePolicy:
+  resource: leave_request
+  version: default
+  rules:
+    - actions: ["view"]
+      effect: EFFECT_ALLOW
+      roles: ["employee"]
+`
+	derivedRoles = `---
+apiVersion: api.cerbos.dev/v1
+derivedRoles:
+  name: common_roles
+  definitions:
+    - name: owner
+      parentRoles: ["user"]
+`
+)
+
+type registry struct {
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	username  string
+	password  string
+}
+
+func newRegistry() *registry {
+	return &registry{blobs: make(map[string][]byte), manifests: make(map[string][]byte)}
+}
+
+func (r *registry) addBlob(b []byte) string {
+	d := digestOf(b)
+	r.blobs[d] = b
+	return d
+}
+
+type layer struct {
+	Annotations map[string]string `json:"annotations,omitempty"`
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+}
+
+func (r *registry) addManifest(tag string, layers ...layer) string {
+	m, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers":        layers,
+	})
+	r.manifests[tag] = m
+	d := digestOf(m)
+	r.manifests[d] = m
+	return d
+}
+
+func (r *registry) serve(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			if u, p, _ := req.BasicAuth(); u != r.username || p != r.password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "secret-token"})
+			return
+		}
+
+		if r.username != "" && req.Header.Get("Authorization") != "Bearer secret-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="registry",scope="repository:policies:pull"`, req.Host))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		p := strings.TrimPrefix(req.URL.Path, "/v2/cerbos/policies/")
+		var body []byte
+		switch {
+		case strings.HasPrefix(p, "manifests/"):
+			body = r.manifests[strings.TrimPrefix(p, "manifests/")]
+		case strings.HasPrefix(p, "blobs/"):
+			body = r.blobs[strings.TrimPrefix(p, "blobs/")]
+		}
+
+		if body == nil {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func mkArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, contents := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestLoadBundleFromOCI(t *testing.T) {
+	archive := mkArchive(t, map[string]string{
+		"resource_policies/leave_request.yaml": resourcePolicy,
+		"derived_roles/common_roles.yaml":      derivedRoles,
+		"_schemas/principal.json":              `{"type": "object"}`,
+		"tests/leave_request_test.yaml":        "not a policy",
+	})
+
+	reg := newRegistry()
+	layerDigest := reg.addBlob(archive)
+	manifestDigest := reg.addManifest("v1", layer{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: layerDigest, Size: len(archive)})
+
+	corrupt := append([]byte{}, archive...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	reg.blobs[digestOf([]byte("other"))] = corrupt
+	reg.addManifest("corrupt", layer{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf([]byte("other")), Size: len(corrupt)})
+
+	srv := reg.serve(t)
+	repo := strings.TrimPrefix(srv.URL, "http://") + "/cerbos/policies"
+	ctx := context.Background()
+
+	t.Run("tag", func(t *testing.T) {
+		policies, err := bundle.LoadBundleFromOCI(ctx, repo+":v1", bundle.OCIAuth{}, bundle.WithPlainHTTP())
+		require.NoError(t, err)
+		require.Len(t, policies, 2)
+
+		sources := make(map[string]bool)
+		for _, p := range policies {
+			sources[p.GetMetadata().GetSourceFile()] = true
+		}
+		require.Equal(t, map[string]bool{"resource_policies/leave_request.yaml": true, "derived_roles/common_roles.yaml": true}, sources)
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		policies, err := bundle.LoadBundleFromOCI(ctx, repo+"@"+manifestDigest, bundle.OCIAuth{}, bundle.WithPlainHTTP())
+		require.NoError(t, err)
+		require.Len(t, policies, 2)
+	})
+
+	t.Run("digest_mismatch", func(t *testing.T) {
+		_, err := bundle.LoadBundleFromOCI(ctx, repo+":corrupt", bundle.OCIAuth{}, bundle.WithPlainHTTP())
+		require.ErrorContains(t, err, "digest mismatch")
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := bundle.LoadBundleFromOCI(ctx, repo+":missing", bundle.OCIAuth{}, bundle.WithPlainHTTP())
+		require.Error(t, err)
+	})
+
+	t.Run("token_auth", func(t *testing.T) {
+		authReg := newRegistry()
+		authReg.username, authReg.password = "john", "hunter2"
+		authReg.blobs = reg.blobs
+		authReg.manifests = reg.manifests
+		authRepo := strings.TrimPrefix(authReg.serve(t).URL, "http://") + "/cerbos/policies"
+
+		policies, err := bundle.LoadBundleFromOCI(ctx, authRepo+":v1", bundle.OCIAuth{Username: "john", Password: "hunter2"}, bundle.WithPlainHTTP())
+		require.NoError(t, err)
+		require.Len(t, policies, 2)
+
+		_, err = bundle.LoadBundleFromOCI(ctx, authRepo+":v1", bundle.OCIAuth{Username: "john", Password: "wrong"}, bundle.WithPlainHTTP())
+		require.ErrorContains(t, err, "failed to obtain registry token")
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		_, err = bundle.LoadBundleFromOCI(ctx, repo+":v1", bundle.OCIAuth{}, bundle.WithPlainHTTP(), bundle.WithSignatureVerificationKey(&key.PublicKey))
+		require.ErrorIs(t, err, bundle.ErrSignatureNotFound)
+
+		payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`, repo, manifestDigest))
+		hash := sha256.Sum256(payload)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		require.NoError(t, err)
+
+		reg.addManifest(strings.Replace(manifestDigest, ":", "-", 1)+".sig", layer{
+			MediaType:   "application/vnd.dev.cosign.simplesigning.v1+json",
+			Digest:      reg.addBlob(payload),
+			Size:        len(payload),
+			Annotations: map[string]string{"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString(sig)},
+		})
+
+		policies, err := bundle.LoadBundleFromOCI(ctx, repo+":v1", bundle.OCIAuth{}, bundle.WithPlainHTTP(), bundle.WithSignatureVerificationKey(&key.PublicKey))
+		require.NoError(t, err)
+		require.Len(t, policies, 2)
+
+		_, err = bundle.LoadBundleFromOCI(ctx, repo+":v1", bundle.OCIAuth{}, bundle.WithPlainHTTP(), bundle.WithSignatureVerificationKey(&otherKey.PublicKey))
+		require.ErrorContains(t, err, "signature does not match the verification key")
+	})
+}
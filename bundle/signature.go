@@ -0,0 +1,93 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+// ErrSignatureNotFound is returned when signature verification is required but the artifact is not signed.
+var ErrSignatureNotFound = errors.New("signature not found")
+
+// simpleSigningPayload is the subset of the cosign simple signing payload that binds a signature to a manifest.
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// verifySignature checks that at least one of the cosign signatures attached to the manifest with the given digest
+// is valid for the verification key.
+func (l *loader) verifySignature(ctx context.Context, manifestDigest string) error {
+	sigTag := strings.Replace(manifestDigest, ":", "-", 1) + ".sig"
+	m, _, err := l.fetchManifest(ctx, sigTag)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return ErrSignatureNotFound
+		}
+		return err
+	}
+
+	var errs error
+	for _, layer := range m.Layers {
+		sig, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+
+		if err := l.verifyLayer(ctx, layer, sig, manifestDigest); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("invalid signature in layer %s: %w", layer.Digest, err))
+			continue
+		}
+
+		return nil
+	}
+
+	if errs == nil {
+		return ErrSignatureNotFound
+	}
+
+	return errs
+}
+
+func (l *loader) verifyLayer(ctx context.Context, layer descriptor, encodedSig, manifestDigest string) error {
+	sig, err := base64.StdEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	payload, err := l.fetchBlob(ctx, layer)
+	if err != nil {
+		return fmt.Errorf("failed to fetch payload: %w", err)
+	}
+
+	hash := sha256.Sum256(payload)
+	if !ecdsa.VerifyASN1(l.verifyKey, hash[:], sig) {
+		return errors.New("signature does not match the verification key")
+	}
+
+	var p simpleSigningPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("failed to parse payload: %w", err)
+	}
+
+	if p.Critical.Image.DockerManifestDigest != manifestDigest {
+		return fmt.Errorf("signature is for manifest %s", p.Critical.Image.DockerManifestDigest)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	// latencyWindowSize is the number of recent latencies used to compute the adaptive timeout.
+	latencyWindowSize = 512
+	// latencyMinSamples is the number of latencies to observe before the adaptive timeout is used.
+	latencyMinSamples = 16
+	// latencyRecomputeInterval is the number of latencies to observe between recomputations of the adaptive timeout.
+	latencyRecomputeInterval = 16
+)
+
+type adaptiveTimeoutConf struct {
+	percentile float64
+	multiplier float64
+	floor      time.Duration
+	ceiling    time.Duration
+}
+
+func (atc *adaptiveTimeoutConf) validate() error {
+	if atc.percentile <= 0 || atc.percentile > 1 {
+		return fmt.Errorf("adaptive timeout percentile must be greater than 0 and at most 1: %v", atc.percentile)
+	}
+
+	if atc.multiplier < 1 {
+		return fmt.Errorf("adaptive timeout multiplier must be at least 1: %v", atc.multiplier)
+	}
+
+	if atc.floor <= 0 || atc.ceiling < atc.floor {
+		return errors.New("adaptive timeout floor must be positive and not greater than the ceiling")
+	}
+
+	return nil
+}
+
+// WithAdaptiveTimeout sets a deadline on each unary call based on the latencies of recent calls, instead of relying on
+// a fixed timeout. The timeout is the given percentile (between 0 and 1, for example 0.99) of the latencies of the last
+// few hundred successful calls multiplied by multiplier, bounded by floor and ceiling. This keeps timeouts tight while
+// the server responds quickly, so that a stalled call fails fast, and relaxes them when the server slows down.
+//
+// The latency of a call includes any retries. Calls that time out because of the adaptive deadline are counted as
+// taking the full timeout, so that the timeout grows if the server becomes consistently slower. Until enough calls
+// have been observed, the ceiling is used. The deadline is only applied if it's earlier than the deadline of the
+// call context. It has no effect on clients created with NewGRPCWeb.
+func WithAdaptiveTimeout(percentile, multiplier float64, floor, ceiling time.Duration) Opt {
+	return func(c *config) {
+		c.adaptiveTimeout = &adaptiveTimeoutConf{
+			percentile: percentile,
+			multiplier: multiplier,
+			floor:      floor,
+			ceiling:    ceiling,
+		}
+	}
+}
+
+// latencyHistogram keeps a rolling window of call latencies and derives the adaptive timeout from them.
+type latencyHistogram struct {
+	conf    *adaptiveTimeoutConf
+	window  []time.Duration
+	next    int
+	count   uint64
+	timeout time.Duration
+	mu      sync.RWMutex
+}
+
+func newLatencyHistogram(conf *adaptiveTimeoutConf) *latencyHistogram {
+	return &latencyHistogram{
+		conf:    conf,
+		window:  make([]time.Duration, 0, latencyWindowSize),
+		timeout: conf.ceiling,
+	}
+}
+
+// currentTimeout returns the timeout to use for the next call.
+func (lh *latencyHistogram) currentTimeout() time.Duration {
+	lh.mu.RLock()
+	defer lh.mu.RUnlock()
+
+	return lh.timeout
+}
+
+// record adds a latency to the window.
+func (lh *latencyHistogram) record(latency time.Duration) {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+
+	if len(lh.window) < latencyWindowSize {
+		lh.window = append(lh.window, latency)
+	} else {
+		lh.window[lh.next] = latency
+	}
+	lh.next = (lh.next + 1) % latencyWindowSize
+	lh.count++
+
+	if lh.count >= latencyMinSamples && lh.count%latencyRecomputeInterval == 0 {
+		lh.timeout = lh.compute()
+	}
+}
+
+func (lh *latencyHistogram) compute() time.Duration {
+	sorted := make([]time.Duration, len(lh.window))
+	copy(sorted, lh.window)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// Nearest-rank percentile.
+	rank := int(math.Ceil(lh.conf.percentile*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	timeout := time.Duration(float64(sorted[rank]) * lh.conf.multiplier)
+	if timeout < lh.conf.floor {
+		return lh.conf.floor
+	}
+
+	if timeout > lh.conf.ceiling {
+		return lh.conf.ceiling
+	}
+
+	return timeout
+}
+
+// adaptiveTimeoutInterceptor applies the adaptive timeout to unary calls and records their latencies.
+func adaptiveTimeoutInterceptor(conf *adaptiveTimeoutConf) grpc.UnaryClientInterceptor {
+	histogram := newLatencyHistogram(conf)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		timeout := histogram.currentTimeout()
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		start := time.Now()
+		err := invoker(callCtx, method, req, reply, cc, opts...)
+		switch {
+		case err == nil:
+			histogram.record(time.Since(start))
+		case ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded):
+			// The call was cut short by the adaptive timeout, so its latency is at least the timeout.
+			histogram.record(timeout)
+		}
+
+		return err
+	}
+}
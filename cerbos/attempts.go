@@ -0,0 +1,116 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// ErrMaxAttemptsExceeded is returned when a call fails after reaching the limit set using WithMaxTotalAttempts.
+// The error returned by the last attempt is wrapped alongside it.
+var ErrMaxAttemptsExceeded = errors.New("maximum number of attempts exceeded")
+
+// WithMaxTotalAttempts sets a hard limit on the number of times a single unary call is sent to the server,
+// bounding the worst-case latency of the call.
+//
+// Without it, the number of attempts is determined by the combination of features that may resend a call:
+// WithMaxRetries allows up to n+1 attempts, and WithCredentialRefresh may repeat all of them after refreshing the credentials.
+// Attempts made by any of these features count towards the limit. Once the limit is reached, the call fails immediately
+// with an error wrapping both ErrMaxAttemptsExceeded and the error returned by the last attempt, without waiting for the retry backoff.
+// Transparent retries performed internally by gRPC for calls that never reached the server are not counted.
+// Streaming calls are not affected.
+func WithMaxTotalAttempts(n int) Opt {
+	return func(c *config) {
+		c.maxTotalAttempts = n
+	}
+}
+
+type attemptCounterKey struct{}
+
+// attemptCounter tracks the attempts made for a single logical call.
+type attemptCounter struct {
+	lastErr  error
+	cancel   context.CancelFunc
+	max      int
+	attempts int
+	mu       sync.Mutex
+}
+
+// next registers a new attempt and returns false if the limit has already been reached.
+func (ac *attemptCounter) next() bool {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if ac.attempts >= ac.max {
+		return false
+	}
+
+	ac.attempts++
+	return true
+}
+
+// done records the outcome of an attempt and stops the call if it was the last allowed attempt.
+func (ac *attemptCounter) done(err error) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if err == nil {
+		return
+	}
+
+	ac.lastErr = err
+	if ac.attempts >= ac.max {
+		ac.cancel()
+	}
+}
+
+func (ac *attemptCounter) exhausted() (bool, error) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	return ac.attempts >= ac.max && ac.lastErr != nil, ac.lastErr
+}
+
+// mkMaxAttemptsInterceptors returns interceptors that enforce the limit on the total number of attempts of a call.
+// The first interceptor must be placed outside and the second inside all interceptors that may resend a call.
+func mkMaxAttemptsInterceptors(maxAttempts int) []grpc.UnaryClientInterceptor {
+	return []grpc.UnaryClientInterceptor{
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			callCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			counter := &attemptCounter{max: maxAttempts, cancel: cancel}
+			err := invoker(context.WithValue(callCtx, attemptCounterKey{}, counter), method, req, reply, cc, opts...)
+			if err == nil || ctx.Err() != nil {
+				return err
+			}
+
+			if exhausted, lastErr := counter.exhausted(); exhausted {
+				return fmt.Errorf("%w (%d attempts): %w", ErrMaxAttemptsExceeded, maxAttempts, lastErr)
+			}
+
+			return err
+		},
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			counter, ok := ctx.Value(attemptCounterKey{}).(*attemptCounter)
+			if !ok {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+
+			if !counter.next() {
+				_, lastErr := counter.exhausted()
+				return lastErr
+			}
+
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			counter.done(err)
+			return err
+		},
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+const (
+	defaultBatchSize        = 50 // matches the default request limit of the Cerbos server
+	defaultMinBatchSize     = 1
+	defaultDeadlineFraction = 0.5
+	defaultChunkParallelism = 4
+	// latencySmoothing is the weight given to the latest observation in the moving average of per-entry latency.
+	latencySmoothing = 0.2
+)
+
+// AdaptiveBatchConfig holds the tuning parameters for adaptive batching. Zero values are replaced with the defaults.
+type AdaptiveBatchConfig struct {
+	// DefaultBatchSize is the number of resources sent in each sub-request when there's no latency history yet
+	// or when the call has no deadline. Defaults to 50.
+	DefaultBatchSize int
+	// MinBatchSize is the smallest number of resources sent in a sub-request, however little time is left. Defaults to 1.
+	MinBatchSize int
+	// MaxBatchSize is the largest number of resources sent in a sub-request. It should not exceed the maximum number of
+	// resources per request allowed by the server. Defaults to DefaultBatchSize.
+	MaxBatchSize int
+	// DeadlineFraction is the fraction of the time remaining until the deadline that a single sub-request is sized to use,
+	// leaving the rest for the remaining sub-requests and for latency spikes. Must be between 0 and 1. Defaults to 0.5.
+	DeadlineFraction float64
+}
+
+// WithAdaptiveBatching splits CheckResources calls with large numbers of resources into sequential sub-requests whose sizes
+// adapt to the observed latency, so that each sub-request is likely to complete before the deadline of the call.
+// The results of the sub-requests are combined into a single response, which carries the server call ID of the first sub-request.
+//
+// The client keeps a moving average of the time taken to check a single resource entry, based on previous sub-requests.
+// Each sub-request is sized to take roughly DeadlineFraction of the time remaining until the deadline, clamped between MinBatchSize
+// and MaxBatchSize. When the call has no deadline or no latency has been observed yet, DefaultBatchSize is used.
+// If any sub-request fails, the whole call fails. This option has no effect on IsAllowed and PlanResources.
+func WithAdaptiveBatching(batchConf AdaptiveBatchConfig) Opt {
+	return func(c *config) {
+		if batchConf.DefaultBatchSize <= 0 {
+			batchConf.DefaultBatchSize = defaultBatchSize
+		}
+
+		if batchConf.MinBatchSize <= 0 {
+			batchConf.MinBatchSize = defaultMinBatchSize
+		}
+
+		if batchConf.MaxBatchSize <= 0 {
+			batchConf.MaxBatchSize = batchConf.DefaultBatchSize
+		}
+
+		if batchConf.DeadlineFraction <= 0 || batchConf.DeadlineFraction > 1 {
+			batchConf.DeadlineFraction = defaultDeadlineFraction
+		}
+
+		c.batchConf = &batchConf
+	}
+}
+
+// adaptiveBatcher decides the size of sub-requests based on the latency of previous sub-requests.
+type adaptiveBatcher struct {
+	conf     AdaptiveBatchConfig
+	perEntry time.Duration
+	mu       sync.Mutex
+}
+
+func newAdaptiveBatcher(conf AdaptiveBatchConfig) *adaptiveBatcher {
+	return &adaptiveBatcher{conf: conf}
+}
+
+func (b *adaptiveBatcher) nextSize(ctx context.Context) int {
+	b.mu.Lock()
+	perEntry := b.perEntry
+	b.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok || perEntry <= 0 {
+		return b.conf.DefaultBatchSize
+	}
+
+	budget := time.Duration(float64(time.Until(deadline)) * b.conf.DeadlineFraction)
+	size := int(budget / perEntry)
+	switch {
+	case size < b.conf.MinBatchSize:
+		return b.conf.MinBatchSize
+	case size > b.conf.MaxBatchSize:
+		return b.conf.MaxBatchSize
+	default:
+		return size
+	}
+}
+
+func (b *adaptiveBatcher) observe(entries int, elapsed time.Duration) {
+	if entries <= 0 {
+		return
+	}
+
+	latest := elapsed / time.Duration(entries)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.perEntry <= 0 {
+		b.perEntry = latest
+		return
+	}
+
+	b.perEntry = time.Duration(latencySmoothing*float64(latest) + (1-latencySmoothing)*float64(b.perEntry))
+}
+
+func (c *GRPCClient) checkResourcesInBatches(ctx context.Context, req *requestv1.CheckResourcesRequest) (*CheckResourcesResponse, error) {
+	callCtx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	merged := &responsev1.CheckResourcesResponse{RequestId: req.RequestId}
+
+	for remaining := req.Resources; len(remaining) > 0; {
+		size := c.batcher.nextSize(callCtx)
+		if size > len(remaining) {
+			size = len(remaining)
+		}
+
+		subReq := &requestv1.CheckResourcesRequest{
+			RequestId:   req.RequestId,
+			Principal:   req.Principal,
+			Resources:   remaining[:size],
+			AuxData:     req.AuxData,
+			IncludeMeta: req.IncludeMeta,
+		}
+
+		start := time.Now()
+		result, err := c.stub.CheckResources(callCtx, subReq, c.checkCallOpts(subReq.Resources)...)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", c.classifyErr(err))
+		}
+		c.batcher.observe(size, time.Since(start))
+
+		if merged.CerbosCallId == "" {
+			merged.CerbosCallId = result.CerbosCallId
+		}
+		merged.Results = append(merged.Results, result.Results...)
+		remaining = remaining[size:]
+	}
+
+	return &CheckResourcesResponse{CheckResourcesResponse: merged}, nil
+}
+
+// WithChunkParallelism sets the maximum number of chunks that CheckResourcesInChunks sends to the server at the same time.
+// Defaults to 4.
+func WithChunkParallelism(n int) Opt {
+	return func(c *config) {
+		if n > 0 {
+			c.chunkParallelism = n
+		}
+	}
+}
+
+// CheckResourcesInChunks checks a large batch of resources by splitting it into chunks of at most chunkSize resources,
+// which are sent to the server concurrently, bounded by the parallelism set using WithChunkParallelism.
+// Use it when a batch is too large to be sent in a single request because of the message size limit or the
+// maximum number of resources per request configured on the server.
+//
+// The results of all chunks are merged into a single response, in the same order as the resources in the batch.
+// The merged response carries the server call ID of the first chunk. If any chunk fails, the outstanding chunks are
+// cancelled and the error of the first failure is returned.
+func (c *GRPCClient) CheckResourcesInChunks(ctx context.Context, principal *Principal, resourceBatch *ResourceBatch, chunkSize int) (*CheckResourcesResponse, error) {
+	done, err := c.lifecycle.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d: must be greater than zero", chunkSize)
+	}
+
+	req, err := c.newCheckResourcesRequest(ctx, principal, resourceBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	callCtx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	numChunks := (len(req.Resources) + chunkSize - 1) / chunkSize
+	results := make([]*responsev1.CheckResourcesResponse, numChunks)
+	sem := make(chan struct{}, c.chunkParallelism)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for i := 0; i < numChunks; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-callCtx.Done():
+			fail(callCtx.Err())
+		}
+
+		// The semaphore may have been acquired even though the context was cancelled.
+		if err := callCtx.Err(); err != nil {
+			fail(err)
+			break
+		}
+
+		start := i * chunkSize
+		end := start + chunkSize
+		if end > len(req.Resources) {
+			end = len(req.Resources)
+		}
+
+		subReq := &requestv1.CheckResourcesRequest{
+			RequestId:   req.RequestId,
+			Principal:   req.Principal,
+			Resources:   req.Resources[start:end],
+			AuxData:     req.AuxData,
+			IncludeMeta: req.IncludeMeta,
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			result, err := c.stub.CheckResources(callCtx, subReq, c.checkCallOpts(subReq.Resources)...)
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			results[i] = result
+		}(i)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("request failed: %w", c.classifyErr(firstErr))
+	}
+
+	merged := &responsev1.CheckResourcesResponse{RequestId: req.RequestId, CerbosCallId: results[0].GetCerbosCallId()}
+	for _, result := range results {
+		merged.Results = append(merged.Results, result.Results...)
+	}
+
+	resp := &CheckResourcesResponse{CheckResourcesResponse: merged}
+	if err := c.checkStrictValidation(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
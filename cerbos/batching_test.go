@@ -0,0 +1,185 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+func TestAdaptiveBatching(t *testing.T) {
+	const perEntryLatency = 5 * time.Millisecond
+
+	batchSizes := make(chan int, 100)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			batchSizes <- len(req.Resources)
+			time.Sleep(time.Duration(len(req.Resources)) * perEntryLatency)
+
+			resp := &responsev1.CheckResourcesResponse{RequestId: req.RequestId, CerbosCallId: fmt.Sprintf("call-%d", len(batchSizes))}
+			for _, entry := range req.Resources {
+				resp.Results = append(resp.Results, &responsev1.CheckResourcesResponse_ResultEntry{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+					Actions:  map[string]effectv1.Effect{"view": effectv1.Effect_EFFECT_ALLOW},
+				})
+			}
+			return resp, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithAdaptiveBatching(cerbos.AdaptiveBatchConfig{
+		DefaultBatchSize: 4,
+		MaxBatchSize:     20,
+		DeadlineFraction: 0.1,
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	mkBatch := func(n int) *cerbos.ResourceBatch {
+		batch := cerbos.NewResourceBatch()
+		for i := 0; i < n; i++ {
+			batch.Add(cerbos.NewResource("leave_request", fmt.Sprintf("XX%03d", i)), "view")
+		}
+		return batch
+	}
+
+	drain := func() []int {
+		var sizes []int
+		for len(batchSizes) > 0 {
+			sizes = append(sizes, <-batchSizes)
+		}
+		return sizes
+	}
+
+	t.Run("default_size_without_deadline", func(t *testing.T) {
+		resp, err := c.CheckResources(context.Background(), principal, mkBatch(10))
+		require.NoError(t, err)
+		require.Equal(t, []int{4, 4, 2}, drain())
+		require.Len(t, resp.Results, 10)
+		require.Equal(t, "call-1", resp.ServerTraceID())
+		for i, r := range resp.Results {
+			require.Equal(t, fmt.Sprintf("XX%03d", i), r.Resource.Id)
+		}
+	})
+
+	t.Run("sized_to_deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+		defer cancel()
+
+		resp, err := c.CheckResources(ctx, principal, mkBatch(30))
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 30)
+		require.True(t, resp.GetResource("XX029").IsAllowed("view"))
+
+		sizes := drain()
+		// About 40ms of the remaining time at 5ms per entry, so well below the maximum.
+		require.Less(t, sizes[0], 20)
+		total := 0
+		for _, s := range sizes {
+			total += s
+		}
+		require.Equal(t, 30, total)
+	})
+}
+
+func TestCheckResourcesInChunks(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+
+			first := req.Resources[0].Resource.Id
+			if first == "XX000" {
+				// Delay the first chunk so that it completes last.
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			if first == "fail" {
+				return nil, status.Error(codes.Internal, "boom")
+			}
+
+			resp := &responsev1.CheckResourcesResponse{RequestId: req.RequestId}
+			for _, entry := range req.Resources {
+				resp.Results = append(resp.Results, &responsev1.CheckResourcesResponse_ResultEntry{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+					Actions:  map[string]effectv1.Effect{"view": effectv1.Effect_EFFECT_ALLOW},
+				})
+			}
+			return resp, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithChunkParallelism(2))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+
+	t.Run("merged_in_order", func(t *testing.T) {
+		batch := cerbos.NewResourceBatch()
+		for i := 0; i < 25; i++ {
+			batch.Add(cerbos.NewResource("leave_request", fmt.Sprintf("XX%03d", i)), "view")
+		}
+
+		resp, err := c.CheckResourcesInChunks(context.Background(), principal, batch, 4)
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 25)
+		for i, r := range resp.Results {
+			require.Equal(t, fmt.Sprintf("XX%03d", i), r.Resource.Id)
+		}
+		require.LessOrEqual(t, maxInFlight.Load(), int32(2))
+	})
+
+	t.Run("chunk_failure", func(t *testing.T) {
+		batch := cerbos.NewResourceBatch().
+			Add(cerbos.NewResource("leave_request", "XX000"), "view").
+			Add(cerbos.NewResource("leave_request", "fail"), "view")
+
+		_, err := c.CheckResourcesInChunks(context.Background(), principal, batch, 1)
+		require.Error(t, err)
+		require.Equal(t, codes.Internal, status.Code(errors.Unwrap(err)))
+	})
+
+	t.Run("cancelled_context", func(t *testing.T) {
+		batch := cerbos.NewResourceBatch().
+			Add(cerbos.NewResource("leave_request", "XX000"), "view").
+			Add(cerbos.NewResource("leave_request", "XX001"), "view")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := c.CheckResourcesInChunks(ctx, principal, batch, 1)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("invalid_chunk_size", func(t *testing.T) {
+		batch := cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX000"), "view")
+
+		_, err := c.CheckResourcesInChunks(context.Background(), principal, batch, 0)
+		require.ErrorContains(t, err, "invalid chunk size")
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	channelzsvc "google.golang.org/grpc/channelz/service"
+)
+
+// WithGRPCChannelzRegistration starts a gRPC server listening on the given address that exposes the channelz service.
+// Channelz provides detailed runtime information about the client's connection to Cerbos, such as the connectivity state
+// of each subchannel, the number of started, succeeded and failed calls, and the last error seen by the transport.
+// The server is stopped when the client is closed. It has no effect on clients created with NewGRPCWeb.
+//
+// The channelz service can be queried using tools such as grpcdebug (https://github.com/grpc-ecosystem/grpcdebug).
+// For example, to list the channels created by the process:
+//
+//	grpcdebug localhost:3595 channelz channels
+//
+// Channelz data is process-wide, so the service reports all the gRPC channels and servers in the process and not just the Cerbos client.
+// Only one client in the process needs to enable this option. The service exposes internal details and should only be reachable by operators.
+func WithGRPCChannelzRegistration(listenAddr string) Opt {
+	return func(c *config) {
+		c.channelzAddr = listenAddr
+	}
+}
+
+// startChannelzServer starts a gRPC server exposing the channelz service and returns a function to stop it.
+func startChannelzServer(addr string) (func() error, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	srv := grpc.NewServer()
+	channelzsvc.RegisterChannelzServiceToServer(srv)
+	go func() { _ = srv.Serve(lis) }()
+
+	return func() error {
+		srv.Stop()
+		return nil
+	}, nil
+}
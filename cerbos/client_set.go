@@ -0,0 +1,62 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/multierr"
+)
+
+// ClientSetConf holds the addresses and credentials used by NewClientSet.
+type ClientSetConf struct {
+	// DecisionAddress is the address of the Cerbos API used for checks, query planning and server information.
+	DecisionAddress string
+	// AdminAddress is the address of the Cerbos Admin API. Defaults to DecisionAddress.
+	AdminAddress string
+	// AdminUsername and AdminPassword are the Admin API credentials. If they are not set, the credentials are
+	// looked up in the same way as NewAdminClient.
+	AdminUsername string
+	AdminPassword string
+}
+
+// ClientSet holds a decision client and an admin client created with the same options.
+type ClientSet struct {
+	Decision *GRPCClient
+	Admin    *GRPCAdminClient
+}
+
+// NewClientSet creates a decision client and an admin client that share the same options, such as TLS settings,
+// timeouts and interceptors. This is useful in deployments where the Admin API is exposed on a different address
+// to the decision API, for example when it's only reachable from an internal network.
+// Each client has its own connection to the server, even if both addresses are the same. Call Close to close both.
+func NewClientSet(conf ClientSetConf, opts ...Opt) (*ClientSet, error) {
+	if conf.DecisionAddress == "" {
+		return nil, errors.New("decision address must be provided")
+	}
+
+	adminAddr := conf.AdminAddress
+	if adminAddr == "" {
+		adminAddr = conf.DecisionAddress
+	}
+
+	decision, err := New(conf.DecisionAddress, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decision client: %w", err)
+	}
+
+	admin, err := NewAdminClientWithCredentials(adminAddr, conf.AdminUsername, conf.AdminPassword, opts...)
+	if err != nil {
+		_ = decision.Close()
+		return nil, fmt.Errorf("failed to create admin client: %w", err)
+	}
+
+	return &ClientSet{Decision: decision, Admin: admin}, nil
+}
+
+// Close closes the connections of both clients.
+func (cs *ClientSet) Close() error {
+	return multierr.Append(cs.Decision.Close(), cs.Admin.Close())
+}
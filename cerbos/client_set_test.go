@@ -0,0 +1,67 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+func TestClientSet(t *testing.T) {
+	recordMethod := func(methods chan<- string) grpc.ServerOption {
+		return grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			methods <- info.FullMethod
+			return handler(ctx, req)
+		})
+	}
+
+	serverInfo := func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+		return &responsev1.ServerInfoResponse{Version: "0.34.0"}, nil
+	}
+
+	decisionMethods := make(chan string, 1)
+	decisionAddr := startFakeServer(t, &fakeServer{serverInfo: serverInfo}, recordMethod(decisionMethods))
+
+	adminMethods := make(chan string, 1)
+	adminAddr := startFakeServer(t, &fakeServer{serverInfo: serverInfo}, grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		adminMethods <- method
+		return status.Error(codes.Unimplemented, "admin API is not implemented")
+	}))
+
+	cs, err := cerbos.NewClientSet(cerbos.ClientSetConf{
+		DecisionAddress: decisionAddr,
+		AdminAddress:    adminAddr,
+		AdminUsername:   "cerbos",
+		AdminPassword:   "cerbosAdmin",
+	}, cerbos.WithPlaintext())
+	require.NoError(t, err)
+
+	_, err = cs.Decision.ServerInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "/cerbos.svc.v1.CerbosService/ServerInfo", <-decisionMethods)
+
+	_, err = cs.Admin.ListPolicies(context.Background())
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+	require.Equal(t, "/cerbos.svc.v1.CerbosAdminService/ListPolicies", <-adminMethods)
+
+	require.NoError(t, cs.Close())
+
+	_, err = cs.Decision.ServerInfo(context.Background())
+	require.Error(t, err)
+
+	_, err = cerbos.NewClientSet(cerbos.ClientSetConf{})
+	require.Error(t, err)
+}
@@ -0,0 +1,76 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"time"
+)
+
+const defaultCompatCheckTimeout = 30 * time.Second
+
+// ServerFeature is a server feature that a client relies on, declared using WithCompatibilityCheck.
+// Policy features can't be detected from the requests made by the client, so they have to be declared.
+type ServerFeature uint8
+
+const (
+	// ScopedPolicies is declared by clients that send principals or resources with a scope.
+	ScopedPolicies ServerFeature = iota
+	// PolicyOutputs is declared by clients that read the outputs of policy rules from responses.
+	PolicyOutputs
+	// ExportedVariables is declared by clients whose policies import exported variables.
+	ExportedVariables
+)
+
+type compatRequirement struct {
+	feature    string
+	minVersion string
+}
+
+// compatRequirements lists the minimum server versions required by the features that clients can declare.
+var compatRequirements = map[ServerFeature]compatRequirement{
+	ScopedPolicies:    {feature: "scoped policies", minVersion: "0.18.0"},
+	PolicyOutputs:     {feature: "policy outputs", minVersion: "0.27.0"},
+	ExportedVariables: {feature: "exported variables", minVersion: "0.29.0"},
+}
+
+func checkCompatibility(c *GRPCClient, logger Logger, features []ServerFeature, timeout time.Duration) {
+	if len(features) == 0 {
+		return
+	}
+
+	if timeout <= 0 {
+		timeout = defaultCompatCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	info, err := c.ServerInfo(ctx)
+	if err != nil {
+		logger.Warn("Unable to determine Cerbos server version for compatibility check", "error", err)
+		return
+	}
+
+	for _, feature := range features {
+		req, ok := compatRequirements[feature]
+		if !ok {
+			continue
+		}
+
+		supported, err := info.AtLeast(req.minVersion)
+		if err != nil {
+			logger.Warn("Unable to parse Cerbos server version for compatibility check", "version", info.Version, "error", err)
+			return
+		}
+
+		if !supported {
+			logger.Warn("Cerbos server version does not support a feature used by the client",
+				"feature", req.feature,
+				"server_version", info.Version,
+				"required_version", req.minVersion,
+			)
+		}
+	}
+}
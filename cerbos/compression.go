@@ -0,0 +1,167 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+)
+
+// WithCompression compresses all calls made by the client using the named compressor, such as gzip. Compressors other
+// than gzip must be registered in the client process (see google.golang.org/grpc/encoding) and supported by the server.
+// Use "identity" to send calls uncompressed, which is the default.
+// Compressors set by WithCompressionForLargeResponsesOnly and WithAutoCompression take precedence for the calls they apply to.
+// It has no effect on clients created with NewGRPCWeb.
+func WithCompression(name string) Opt {
+	return func(c *config) {
+		c.compressor = name
+	}
+}
+
+// WithoutCompression sends all calls uncompressed, regardless of the other compression options of the client.
+// Use it for latency-sensitive clients making small calls, for which compression only adds overhead.
+func WithoutCompression() Opt {
+	return func(c *config) {
+		c.noCompression = true
+	}
+}
+
+// compressionCallOpts returns the call options to apply to every call made by the client.
+func (conf *config) compressionCallOpts() ([]grpc.CallOption, error) {
+	if conf.noCompression || conf.compressor == "" || conf.compressor == encoding.Identity {
+		return nil, nil
+	}
+
+	if encoding.GetCompressor(conf.compressor) == nil {
+		return nil, fmt.Errorf("compressor %q is not registered", conf.compressor)
+	}
+
+	return []grpc.CallOption{grpc.UseCompressor(conf.compressor)}, nil
+}
+
+// WithCompressionForLargeResponsesOnly enables gzip compression for CheckResources calls that check at least minChecks
+// resource and action combinations. Responses to such calls are large enough to benefit from compression, while compressing
+// small requests and responses only adds CPU overhead and latency. Calls with fewer checks are always sent uncompressed.
+//
+// The client can't ask the server to compress responses directly. Instead, this relies on the behaviour of gRPC servers
+// (including Cerbos) that compress a response using the same compressor as the request. The server must support gzip:
+// servers that don't reject compressed calls with an Unimplemented error. The client always accepts gzip-compressed responses,
+// regardless of this option. It has no effect on clients created with NewGRPCWeb.
+func WithCompressionForLargeResponsesOnly(minChecks int) Opt {
+	return func(c *config) {
+		c.compressMinChecks = minChecks
+	}
+}
+
+// checkCallOpts returns the call options to use for a CheckResources call with the given resources.
+func (c *GRPCClient) checkCallOpts(resources []*requestv1.CheckResourcesRequest_ResourceEntry) []grpc.CallOption {
+	if c.compressMinChecks <= 0 {
+		return nil
+	}
+
+	checks := 0
+	for _, r := range resources {
+		checks += len(r.Actions)
+	}
+
+	if checks < c.compressMinChecks {
+		return nil
+	}
+
+	return []grpc.CallOption{grpc.UseCompressor(gzip.Name)}
+}
+
+// preferredCompressors lists the compressors considered by WithAutoCompression, from the most to the least preferred.
+var preferredCompressors = []string{"zstd", "snappy", gzip.Name}
+
+// WithAutoCompression enables compression of unary calls using the best compressor supported by both the client and the server.
+//
+// The first call made by the client is sent uncompressed and the compressors advertised by the server in the grpc-accept-encoding
+// response header are recorded. Subsequent calls use the first compressor from zstd, snappy and gzip that the server advertises
+// and that is registered in the client process (see google.golang.org/grpc/encoding). Only gzip is registered by default:
+// other compressors must be registered by importing a package that provides them. If the server advertises compressors but none
+// of them are available to the client, calls are sent uncompressed. If the server doesn't advertise any compressors, which is
+// the case for current versions of Cerbos, gzip is used.
+//
+// Compressors set by WithCompressionForLargeResponsesOnly take precedence for the calls they apply to.
+// It has no effect on clients created with NewGRPCWeb.
+func WithAutoCompression() Opt {
+	return func(c *config) {
+		c.autoCompression = true
+	}
+}
+
+// compressorNegotiator holds the compressor selected by WithAutoCompression.
+type compressorNegotiator struct {
+	selected string
+	done     bool
+	mu       sync.RWMutex
+}
+
+func (n *compressorNegotiator) compressor() (string, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.selected, n.done
+}
+
+func (n *compressorNegotiator) negotiate(acceptEncoding []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.done {
+		return
+	}
+
+	n.done = true
+	if len(acceptEncoding) == 0 {
+		n.selected = gzip.Name
+		return
+	}
+
+	supported := make(map[string]struct{})
+	for _, v := range acceptEncoding {
+		for _, name := range strings.Split(v, ",") {
+			supported[strings.TrimSpace(name)] = struct{}{}
+		}
+	}
+
+	for _, name := range preferredCompressors {
+		if _, ok := supported[name]; ok && encoding.GetCompressor(name) != nil {
+			n.selected = name
+			return
+		}
+	}
+}
+
+func autoCompressionInterceptor() grpc.UnaryClientInterceptor {
+	n := &compressorNegotiator{}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if name, ok := n.compressor(); ok {
+			if name == "" {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+
+			// Call options set by the caller are applied later and take precedence.
+			return invoker(ctx, method, req, reply, cc, append([]grpc.CallOption{grpc.UseCompressor(name)}, opts...)...)
+		}
+
+		var header metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+		if err == nil {
+			n.negotiate(header.Get("grpc-accept-encoding"))
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/multierr"
+	"google.golang.org/grpc"
+)
+
+// WithConnectionPool makes the client open size connections to the server and spread calls across them in a round-robin fashion.
+// All calls made over a single connection share one HTTP/2 connection, which can limit throughput under heavy load. A pool of
+// connections lifts that limit, and also spreads calls across the servers behind a load balancer that balances connections
+// rather than requests. The connections share the options of the client, including its interceptors.
+// Connection state reported by the client, such as in errors and to WithOnConnect callbacks, is that of the first connection.
+// The default size is 1. It has no effect on clients created with NewGRPCWeb and can't be used with NewFromConn.
+func WithConnectionPool(size int) Opt {
+	return func(c *config) {
+		c.poolSize = size
+	}
+}
+
+// connPool spreads calls across a set of connections.
+type connPool struct {
+	conns []*grpc.ClientConn
+	next  atomic.Uint32
+}
+
+func (p *connPool) pick() *grpc.ClientConn {
+	return p.conns[(p.next.Add(1)-1)%uint32(len(p.conns))]
+}
+
+func (p *connPool) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return p.pick().Invoke(ctx, method, args, reply, opts...)
+}
+
+func (p *connPool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return p.pick().NewStream(ctx, desc, method, opts...)
+}
+
+func (p *connPool) Close() error {
+	var err error
+	for _, conn := range p.conns {
+		err = multierr.Append(err, conn.Close())
+	}
+
+	return err
+}
+
+// mkConnPool creates the connections for a client. There's at least one connection.
+func mkConnPool(address string, opts ...Opt) (*connPool, *config, error) {
+	conf := mkConfig(address, opts...)
+	if conf.poolSize < 1 {
+		return nil, nil, fmt.Errorf("connection pool size must be at least 1: %d", conf.poolSize)
+	}
+
+	dialOpts, err := mkDialOpts(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pool := &connPool{conns: make([]*grpc.ClientConn, 0, conf.poolSize)}
+	for i := 0; i < conf.poolSize; i++ {
+		conn, err := grpc.NewClient(conf.address, dialOpts...)
+		if err != nil {
+			_ = pool.Close()
+			return nil, nil, fmt.Errorf("failed to dial gRPC: %w", err)
+		}
+
+		pool.conns = append(pool.conns, conn)
+	}
+
+	return pool, conf, nil
+}
@@ -6,6 +6,11 @@ package cerbos
 import (
 	"context"
 	"encoding/base64"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/credentials"
 
 	"github.com/cerbos/cerbos-sdk-go/internal"
 )
@@ -23,6 +28,28 @@ func newBasicAuthCredentials(username, password string) basicAuthCredentials {
 	return basicAuthCredentials{headerVal: "Basic " + enc, requireTLS: true}
 }
 
+// mkBasicAuthCredentials returns the basic auth credentials configured for the client, or nil if basic auth is not enabled.
+func mkBasicAuthCredentials(conf *config) (credentials.PerRPCCredentials, error) {
+	username, password := conf.basicAuthUsername, conf.basicAuthPassword
+	if conf.basicAuthNetrc {
+		var err error
+		if _, username, password, err = internal.LoadBasicAuthDataForMachine(internal.OSEnvironment{}, conf.address, conf.netrcMachine, username, password); err != nil {
+			return nil, fmt.Errorf("failed to load basic auth credentials: %w", err)
+		}
+	}
+
+	if username == "" && password == "" {
+		return nil, nil
+	}
+
+	basicAuth := newBasicAuthCredentials(username, password)
+	if conf.plaintext {
+		return basicAuth.Insecure(), nil
+	}
+
+	return basicAuth, nil
+}
+
 // Insecure relaxes the TLS requirement for using the credential.
 func (ba basicAuthCredentials) Insecure() basicAuthCredentials {
 	return basicAuthCredentials{headerVal: ba.headerVal, requireTLS: false}
@@ -51,3 +78,24 @@ func (pic playgroundInstanceCredentials) GetRequestMetadata(_ context.Context, _
 func (playgroundInstanceCredentials) RequireTransportSecurity() bool {
 	return false
 }
+
+// handshakeTimeoutCredentials enforces a deadline on the client handshake of the wrapped transport credentials.
+type handshakeTimeoutCredentials struct {
+	credentials.TransportCredentials
+	timeout time.Duration
+}
+
+func newHandshakeTimeoutCredentials(creds credentials.TransportCredentials, timeout time.Duration) handshakeTimeoutCredentials {
+	return handshakeTimeoutCredentials{TransportCredentials: creds, timeout: timeout}
+}
+
+func (htc handshakeTimeoutCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, htc.timeout)
+	defer cancel()
+
+	return htc.TransportCredentials.ClientHandshake(ctx, authority, rawConn)
+}
+
+func (htc handshakeTimeoutCredentials) Clone() credentials.TransportCredentials {
+	return newHandshakeTimeoutCredentials(htc.TransportCredentials.Clone(), htc.timeout)
+}
@@ -0,0 +1,79 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/protovalidate-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+var (
+	// ErrDeadlineExceededWhileConnecting indicates that a call timed out before a connection to the server could be established.
+	ErrDeadlineExceededWhileConnecting = errors.New("deadline exceeded while connecting to the server")
+	// ErrDeadlineExceededAwaitingResponse indicates that a call timed out while waiting for the server to respond.
+	ErrDeadlineExceededAwaitingResponse = errors.New("deadline exceeded while awaiting response from the server")
+	// ErrSchemaValidationFailed indicates that the server reported schema validation errors for a request made with the StrictValidation request option.
+	ErrSchemaValidationFailed = errors.New("schema validation failed")
+	// ErrPolicyFileTooLarge indicates that a policy couldn't be read because it exceeds the maximum size of 4MiB.
+	ErrPolicyFileTooLarge = internal.ErrFileTooLarge
+)
+
+// IsUnavailable returns true if the error indicates that the Cerbos server could not be reached.
+func IsUnavailable(err error) bool {
+	return hasStatusCode(err, codes.Unavailable)
+}
+
+// IsInvalidArgument returns true if the request was rejected because it was invalid.
+// This includes requests that failed validation on the client before being sent to the server.
+func IsInvalidArgument(err error) bool {
+	var verr *protovalidate.ValidationError
+	if errors.As(err, &verr) {
+		return true
+	}
+
+	return hasStatusCode(err, codes.InvalidArgument)
+}
+
+// IsUnauthenticated returns true if the server rejected the credentials presented by the client.
+func IsUnauthenticated(err error) bool {
+	return hasStatusCode(err, codes.Unauthenticated)
+}
+
+func hasStatusCode(err error, code codes.Code) bool {
+	if err == nil {
+		return false
+	}
+
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &se) {
+		return se.GRPCStatus().Code() == code
+	}
+
+	return false
+}
+
+// classifyErr annotates deadline errors with the phase of the call in which the deadline was exceeded.
+func (c *GRPCClient) classifyErr(err error) error {
+	if c.conn == nil || !isDeadlineExceeded(err) {
+		return err
+	}
+
+	if state := c.conn.GetState(); state != connectivity.Ready {
+		return fmt.Errorf("%w (connection state: %s): %w", ErrDeadlineExceededWhileConnecting, state, err)
+	}
+
+	return fmt.Errorf("%w: %w", ErrDeadlineExceededAwaitingResponse, err)
+}
+
+func isDeadlineExceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || hasStatusCode(err, codes.DeadlineExceeded)
+}
@@ -0,0 +1,90 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+func TestErrorPredicates(t *testing.T) {
+	wrap := func(code codes.Code) error {
+		return fmt.Errorf("request failed: %w", status.Error(code, "boom"))
+	}
+
+	require.True(t, cerbos.IsUnavailable(wrap(codes.Unavailable)))
+	require.False(t, cerbos.IsUnavailable(wrap(codes.InvalidArgument)))
+	require.True(t, cerbos.IsInvalidArgument(wrap(codes.InvalidArgument)))
+	require.True(t, cerbos.IsUnauthenticated(wrap(codes.Unauthenticated)))
+	require.False(t, cerbos.IsUnauthenticated(errors.New("boom")))
+	require.False(t, cerbos.IsUnavailable(nil))
+
+	t.Run("client_side_validation", func(t *testing.T) {
+		c, err := cerbos.New("passthrough:///127.0.0.1:1", cerbos.WithPlaintext())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.IsAllowed(context.Background(), cerbos.NewPrincipal(""), cerbos.NewResource("leave_request", "XX125"), "view")
+		require.Error(t, err)
+		require.True(t, cerbos.IsInvalidArgument(err))
+	})
+}
+
+func TestDeadlineClassification(t *testing.T) {
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	t.Run("connecting", func(t *testing.T) {
+		// The listener never accepts connections, so the HTTP/2 handshake can never complete.
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = lis.Close() })
+
+		c, err := cerbos.New("passthrough:///"+lis.Addr().String(), cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		_, err = c.IsAllowed(ctx, principal, resource, "view")
+		require.ErrorIs(t, err, cerbos.ErrDeadlineExceededWhileConnecting)
+	})
+
+	t.Run("awaiting_response", func(t *testing.T) {
+		addr := startFakeServer(t, &fakeServer{
+			checkResources: func(ctx context.Context, _ *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		})
+
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.ServerInfo(context.Background())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		_, err = c.IsAllowed(ctx, principal, resource, "view")
+		require.ErrorIs(t, err, cerbos.ErrDeadlineExceededAwaitingResponse)
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+	svcv1 "github.com/cerbos/cerbos/api/genpb/cerbos/svc/v1"
+)
+
+// fakeServer is an in-process Cerbos service with pluggable handlers for exercising client behaviour.
+type fakeServer struct {
+	svcv1.UnimplementedCerbosServiceServer
+	checkResources func(context.Context, *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error)
+	planResources  func(context.Context, *requestv1.PlanResourcesRequest) (*responsev1.PlanResourcesResponse, error)
+	serverInfo     func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error)
+}
+
+func (fs *fakeServer) CheckResources(ctx context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+	if fs.checkResources == nil {
+		return fs.UnimplementedCerbosServiceServer.CheckResources(ctx, req)
+	}
+
+	return fs.checkResources(ctx, req)
+}
+
+func (fs *fakeServer) PlanResources(ctx context.Context, req *requestv1.PlanResourcesRequest) (*responsev1.PlanResourcesResponse, error) {
+	if fs.planResources == nil {
+		return fs.UnimplementedCerbosServiceServer.PlanResources(ctx, req)
+	}
+
+	return fs.planResources(ctx, req)
+}
+
+func (fs *fakeServer) ServerInfo(ctx context.Context, req *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+	if fs.serverInfo == nil {
+		return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+	}
+
+	return fs.serverInfo(ctx, req)
+}
+
+// startFakeServer starts the given service on a random local port and returns a passthrough address to connect to it.
+func startFakeServer(t *testing.T, srv svcv1.CerbosServiceServer, opts ...grpc.ServerOption) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer(opts...)
+	svcv1.RegisterCerbosServiceServer(s, srv)
+
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	return "passthrough:///" + lis.Addr().String()
+}
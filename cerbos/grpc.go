@@ -7,20 +7,35 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
+	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials/oauth"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
 	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
 	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	schemav1 "github.com/cerbos/cerbos/api/genpb/cerbos/schema/v1"
 	svcv1 "github.com/cerbos/cerbos/api/genpb/cerbos/svc/v1"
 
 	"github.com/cerbos/cerbos-sdk-go/internal"
@@ -30,20 +45,66 @@ var _ Client[*GRPCClient, PrincipalCtx] = (*GRPCClient)(nil)
 
 type config struct {
 	statsHandler       stats.Handler
+	defaultAuxData     *requestv1.AuxData
+	batchConf          *AdaptiveBatchConfig
+	adaptiveTimeout    *adaptiveTimeoutConf
+	retryBudget        *retryBudgetConf
+	keepAlive          *keepalive.ClientParameters
+	maxConnIdleTime    *time.Duration
+	credentialRefresh  func(context.Context) error
+	retryBackoff       grpc_retry.BackoffFunc
+	tokenSource        oauth2.TokenSource
+	tracerProvider     trace.TracerProvider
+	metricsRegisterer  prometheus.Registerer
+	ctxDecorator       func(context.Context) context.Context
+	panicHandler       func(any)
+	onConnect          func(*ServerInfo)
+	compatLogger       Logger
+	reqIDLogger        Logger
+	logger             Logger
 	address            string
+	authority          string
+	basicAuthUsername  string
+	basicAuthPassword  string
+	channelzAddr       string
 	tlsAuthority       string
 	tlsCACert          string
 	tlsClientCert      string
 	tlsClientKey       string
 	userAgent          string
 	playgroundInstance string
+	policyVersion      string
+	compressor         string
+	netrcMachine       string
+	serviceConfig      string
+	lbPolicy           string
 	streamInterceptors []grpc.StreamClientInterceptor
+	tlsCACertPEM       []byte
+	tlsClientCertPEM   []byte
+	tlsClientKeyPEM    []byte
 	unaryInterceptors  []grpc.UnaryClientInterceptor
+	retryCodes         []codes.Code
+	compatFeatures     []ServerFeature
+	compressMinChecks  int
+	chunkParallelism   int
 	connectTimeout     time.Duration
+	defaultCallTimeout time.Duration
+	handshakeTimeout   time.Duration
 	retryTimeout       time.Duration
 	maxRetries         uint
+	maxTotalAttempts   int
+	poolSize           int
 	plaintext          bool
 	tlsInsecure        bool
+	noServiceConfig    bool
+	autoCompression    bool
+	noCompression      bool
+	responseValidation bool
+	logPrincipalAttrs  bool
+	waitForReady       bool
+	basicAuthNetrc     bool
+	reqIDCounter       bool
+	grpcWebText        bool
 }
 
 type Opt func(*config)
@@ -62,6 +123,15 @@ func WithTLSAuthority(authority string) Opt {
 	}
 }
 
+// WithAuthority overrides the authority (the :authority pseudo-header) sent with every call made by the client,
+// regardless of whether TLS is used. When TLS is enabled, it's also used as the server name for certificate verification.
+// It takes precedence over WithTLSAuthority.
+func WithAuthority(authority string) Opt {
+	return func(c *config) {
+		c.authority = authority
+	}
+}
+
 // WithTLSInsecure enables skipping TLS certificate verification.
 func WithTLSInsecure() Opt {
 	return func(c *config) {
@@ -76,6 +146,15 @@ func WithTLSCACert(certPath string) Opt {
 	}
 }
 
+// WithTLSCACertPEM sets the PEM-encoded CA certificate chain to use for certificate verification.
+// Use it instead of WithTLSCACert when the certificates are not stored in a file, for example when they are obtained from a secret store.
+// Only one of WithTLSCACert and WithTLSCACertPEM can be used.
+func WithTLSCACertPEM(pem []byte) Opt {
+	return func(c *config) {
+		c.tlsCACertPEM = pem
+	}
+}
+
 // WithTLSClientCert sets the client certificate to use to authenticate to the server.
 func WithTLSClientCert(cert, key string) Opt {
 	return func(c *config) {
@@ -84,6 +163,16 @@ func WithTLSClientCert(cert, key string) Opt {
 	}
 }
 
+// WithTLSClientCertPEM sets the PEM-encoded client certificate and key to use to authenticate to the server.
+// Use it instead of WithTLSClientCert when the certificate and key are not stored in files.
+// Only one of WithTLSClientCert and WithTLSClientCertPEM can be used.
+func WithTLSClientCertPEM(cert, key []byte) Opt {
+	return func(c *config) {
+		c.tlsClientCertPEM = cert
+		c.tlsClientKeyPEM = key
+	}
+}
+
 // WithConnectTimeout sets the connection establishment timeout.
 func WithConnectTimeout(timeout time.Duration) Opt {
 	return func(c *config) {
@@ -91,7 +180,58 @@ func WithConnectTimeout(timeout time.Duration) Opt {
 	}
 }
 
+// WithDefaultCallTimeout sets the timeout for calls made with a context that has no deadline, so that a call to an
+// unresponsive server doesn't block forever. It applies to CheckResources, IsAllowed, PlanResources, ServerInfo and
+// the other calls made by the decision client. A deadline set on the context passed to a call always takes precedence,
+// as does the timeout set using the DetachedContext request option.
+func WithDefaultCallTimeout(timeout time.Duration) Opt {
+	return func(c *config) {
+		c.defaultCallTimeout = timeout
+	}
+}
+
+// WithWaitForReady makes calls wait for the connection to the server to become ready instead of failing immediately
+// when the server is unreachable, for example while it's being restarted during a rolling deployment.
+// Calls wait until the deadline of their context expires, so make sure that calls are made with a deadline
+// (see WithDefaultCallTimeout). By default, calls fail fast with an Unavailable error when the connection is not ready.
+func WithWaitForReady() Opt {
+	return func(c *config) {
+		c.waitForReady = true
+	}
+}
+
+// WithKeepAlive configures the client to send HTTP/2 pings on idle connections. This keeps connections open through
+// load balancers and proxies that silently drop idle connections and detects broken connections before the next call is made.
+// The server must be configured to accept pings at the chosen rate, otherwise it will close the connection.
+// With the default gRPC-Go server settings, pings sent more often than every 5 minutes or while there are no active calls are rejected.
+// See https://pkg.go.dev/google.golang.org/grpc/keepalive#ClientParameters for details of the parameters.
+func WithKeepAlive(params keepalive.ClientParameters) Opt {
+	return func(c *config) {
+		c.keepAlive = &params
+	}
+}
+
+// WithMaxConnectionIdleTime closes the connections to the server once no calls have been made for the given duration.
+// The next call transparently reconnects, possibly to a different backend when the address resolves to multiple servers or
+// the server is behind a load balancer, which helps to rebalance long-lived clients after backends are added. Calls in progress
+// count as activity, so they are never disrupted.
+// By default, gRPC closes connections after 30 minutes of inactivity. Set to zero to keep idle connections open indefinitely.
+func WithMaxConnectionIdleTime(d time.Duration) Opt {
+	return func(c *config) {
+		c.maxConnIdleTime = &d
+	}
+}
+
+// WithTLSHandshakeTimeout sets the maximum time allowed for completing the TLS handshake once the TCP connection is established.
+// It has no effect when the client is configured with WithPlaintext.
+func WithTLSHandshakeTimeout(timeout time.Duration) Opt {
+	return func(c *config) {
+		c.handshakeTimeout = timeout
+	}
+}
+
 // WithMaxRetries sets the maximum number of retries per call.
+// Use WithMaxTotalAttempts to set a hard limit that also covers attempts made by other features such as WithCredentialRefresh.
 func WithMaxRetries(retries uint) Opt {
 	return func(c *config) {
 		c.maxRetries = retries
@@ -105,6 +245,25 @@ func WithRetryTimeout(timeout time.Duration) Opt {
 	}
 }
 
+// WithRetryableStatusCodes sets the gRPC status codes that cause a call to be retried, replacing the default of
+// Unavailable and ResourceExhausted. Only include codes for which retrying is safe: Cerbos API calls are idempotent,
+// but codes such as InvalidArgument indicate a problem with the request that retrying won't fix.
+func WithRetryableStatusCodes(retryCodes ...codes.Code) Opt {
+	return func(c *config) {
+		c.retryCodes = retryCodes
+	}
+}
+
+// WithRetryBackoff sets the function used to compute the delay before each retry, replacing the default constant
+// backoff with jitter. For example, use grpc_retry.BackoffExponentialWithJitter from
+// github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry for exponential backoff.
+// Delays requested by the server using the retry-after trailer take precedence for unary calls.
+func WithRetryBackoff(backoff grpc_retry.BackoffFunc) Opt {
+	return func(c *config) {
+		c.retryBackoff = backoff
+	}
+}
+
 // WithUserAgent sets the user agent string.
 func WithUserAgent(ua string) Opt {
 	return func(c *config) {
@@ -112,6 +271,23 @@ func WithUserAgent(ua string) Opt {
 	}
 }
 
+// WithBearerToken configures the client to send the given token as a bearer token in the authorization header of every call.
+// The token is only sent over TLS connections, so this option can't be combined with WithPlaintext.
+func WithBearerToken(token string) Opt {
+	return func(c *config) {
+		c.tokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token, TokenType: "Bearer"})
+	}
+}
+
+// WithTokenSource configures the client to obtain an OAuth2 access token from the given token source and send it in the
+// authorization header of every call. Tokens are cached and a new one is requested from the token source when the current
+// one expires. The token is only sent over TLS connections, so this option can't be combined with WithPlaintext.
+func WithTokenSource(ts oauth2.TokenSource) Opt {
+	return func(c *config) {
+		c.tokenSource = oauth2.ReuseTokenSource(nil, ts)
+	}
+}
+
 // WithPlaygroundInstance sets the Cerbos playground instance to use as the source of policies.
 // Note that Playground instances are for demonstration purposes only and do not provide any
 // performance or availability guarantees.
@@ -142,29 +318,265 @@ func WithStatsHandler(handler stats.Handler) Opt {
 	}
 }
 
+// WithDisableServiceConfig prevents the client from using service configs provided by the name resolver
+// (such as those published in DNS TXT records). Use this option to avoid lookup delays in environments where
+// such records are unavailable. A service config provided explicitly by the client still applies.
+func WithDisableServiceConfig() Opt {
+	return func(c *config) {
+		c.noServiceConfig = true
+	}
+}
+
+// WithServiceConfig sets the service config used by the client, given in the JSON format described in
+// https://github.com/grpc/grpc/blob/master/doc/service_config.md. It's used unless the name resolver provides a
+// service config of its own, which can be prevented with WithDisableServiceConfig. Can't be combined with WithLoadBalancingPolicy.
+func WithServiceConfig(serviceConfig string) Opt {
+	return func(c *config) {
+		c.serviceConfig = serviceConfig
+	}
+}
+
+// WithLoadBalancingPolicy sets the gRPC load balancing policy used by the client, such as round_robin to spread calls
+// across all the addresses the server name resolves to. The default policy, pick_first, sends all calls to a single address.
+// Can't be combined with WithServiceConfig.
+func WithLoadBalancingPolicy(policy string) Opt {
+	return func(c *config) {
+		c.lbPolicy = policy
+	}
+}
+
+// WithCompatibilityCheck enables a one-off check of the server version when the client is created.
+// A warning is logged for each of the given features that the connected server is too old to support.
+// Features that aren't declared are not checked.
+func WithCompatibilityCheck(logger Logger, features ...ServerFeature) Opt {
+	return func(c *config) {
+		c.compatLogger = logger
+		c.compatFeatures = features
+	}
+}
+
+// WithCredentialRefresh sets a function to be invoked when the server rejects a call with an Unauthenticated error.
+// If the function returns successfully, the call is retried once more. It's intended for refreshing short-lived
+// tokens attached to requests by per-RPC credentials or interceptors.
+func WithCredentialRefresh(refresh func(context.Context) error) Opt {
+	return func(c *config) {
+		c.credentialRefresh = refresh
+	}
+}
+
+// WithContextDecorator sets a function to modify the context of every call made by the client.
+// The function is applied after the SDK has set up the outgoing context (including any headers set using request options)
+// and can be used to attach values or metadata that the SDK doesn't have dedicated options for.
+func WithContextDecorator(decorator func(context.Context) context.Context) Opt {
+	return func(c *config) {
+		c.ctxDecorator = decorator
+	}
+}
+
+// WithPanicRecovery recovers from panics raised by the interceptors of the client, such as those set using WithUnaryInterceptors.
+// The handler is invoked with the recovered value and the call fails with an Internal error instead of crashing the calling goroutine.
+// For streaming calls, only panics raised while the stream is being created are recovered.
+func WithPanicRecovery(handler func(recovered any)) Opt {
+	return func(c *config) {
+		c.panicHandler = handler
+	}
+}
+
+// WithPolicyVersion sets the default policy version to use for all requests made by the client.
+// The version set on an individual resource takes precedence over the version set using the PolicyVersion request option,
+// which in turn takes precedence over the version set using this option.
+func WithPolicyVersion(version string) Opt {
+	return func(c *config) {
+		c.policyVersion = version
+	}
+}
+
+// WithDefaultAuxData sets the auxiliary data to send with every CheckResources, IsAllowed and PlanResources request made by the client.
+// This is useful for attaching a service identity token to all calls without passing it to each one.
+// Auxiliary data set on a request using a request option such as AuxDataJWT takes precedence: each field set on the request replaces
+// the corresponding field of the default. Fields not set on the request are taken from the default.
+func WithDefaultAuxData(auxData *requestv1.AuxData) Opt {
+	return func(c *config) {
+		c.defaultAuxData = proto.Clone(auxData).(*requestv1.AuxData) //nolint:forcetypeassert
+	}
+}
+
+// WithRequestIDReuseDetection enables checking that request IDs are not reused across requests made by the client.
+// A warning is logged whenever a request ID matching one of the recently sent requests is detected.
+// This is useful for finding bugs in custom request ID generators.
+func WithRequestIDReuseDetection(logger Logger) Opt {
+	return func(c *config) {
+		c.reqIDLogger = logger
+	}
+}
+
+// WithRequestIDCounter appends a monotonically increasing sequence number to every request ID sent by the client.
+func WithRequestIDCounter() Opt {
+	return func(c *config) {
+		c.reqIDCounter = true
+	}
+}
+
 // New creates a new Cerbos client.
 func New(address string, opts ...Opt) (*GRPCClient, error) {
-	grpcConn, _, err := mkConn(address, opts...)
+	pool, conf, err := mkConnPool(address, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &GRPCClient{stub: svcv1.NewCerbosServiceClient(grpcConn)}, nil
+	var cc grpc.ClientConnInterface = pool
+	if len(pool.conns) == 1 {
+		cc = pool.conns[0]
+	}
+
+	client := newClient(cc, conf)
+	client.conn = pool.conns[0]
+	client.closeFn = pool.Close
+	if conf.channelzAddr != "" {
+		stopChannelz, err := startChannelzServer(conf.channelzAddr)
+		if err != nil {
+			_ = pool.Close()
+			return nil, fmt.Errorf("failed to start channelz service: %w", err)
+		}
+
+		client.closeFn = func() error {
+			return multierr.Append(stopChannelz(), pool.Close())
+		}
+	}
+
+	if conf.compatLogger != nil {
+		go checkCompatibility(client, conf.compatLogger, conf.compatFeatures, conf.connectTimeout)
+	}
+
+	if conf.onConnect != nil {
+		stopWatcher := startOnConnectWatcher(client, conf.onConnect, conf.connectTimeout)
+		closeConn := client.closeFn
+		client.closeFn = func() error {
+			stopWatcher()
+			return closeConn()
+		}
+	}
+
+	return client, nil
 }
 
-func mkConn(address string, opts ...Opt) (*grpc.ClientConn, *config, error) {
+// NewFromConn creates a new Cerbos client that uses an existing connection, such as one shared with other parts of the application.
+// The connection is not closed when the client is closed: the caller remains responsible for its lifecycle.
+// Options that configure how the connection is established, such as TLS, plaintext, keepalive and stats handler options,
+// can't be used and result in an error. Unary interceptors, retries, call options and request options apply as they do
+// for clients created using New. Stream interceptors are not applied.
+func NewFromConn(conn grpc.ClientConnInterface, opts ...Opt) (*GRPCClient, error) {
+	if conn == nil {
+		return nil, errors.New("connection is nil")
+	}
+
+	var address string
+	if grpcConn, ok := conn.(*grpc.ClientConn); ok {
+		address = grpcConn.Target()
+	}
+
+	conf := mkConfig(address, opts...)
+	if err := conf.validateForConn(); err != nil {
+		return nil, err
+	}
+
+	interceptors, err := mkUnaryInterceptors(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	callOpts, err := conf.compressionCallOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.waitForReady {
+		callOpts = append(callOpts, grpc.WaitForReady(true))
+	}
+
+	if conf.tokenSource != nil {
+		callOpts = append(callOpts, grpc.PerRPCCredentials(oauth.TokenSource{TokenSource: conf.tokenSource}))
+	}
+
+	basicAuth, err := mkBasicAuthCredentials(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if basicAuth != nil {
+		callOpts = append(callOpts, grpc.PerRPCCredentials(basicAuth))
+	}
+
+	// The capacity is capped so that appending call options for a call never modifies the shared slice.
+	client := newClient(&interceptedConn{conn: conn, interceptors: interceptors, callOpts: callOpts[:len(callOpts):len(callOpts)]}, conf)
+	if grpcConn, ok := conn.(*grpc.ClientConn); ok {
+		client.conn = grpcConn
+		if conf.onConnect != nil {
+			stopWatcher := startOnConnectWatcher(client, conf.onConnect, conf.connectTimeout)
+			client.closeFn = func() error {
+				stopWatcher()
+				return nil
+			}
+		}
+	}
+
+	if conf.compatLogger != nil {
+		go checkCompatibility(client, conf.compatLogger, conf.compatFeatures, conf.connectTimeout)
+	}
+
+	return client, nil
+}
+
+func newClient(cc grpc.ClientConnInterface, conf *config) *GRPCClient {
+	client := &GRPCClient{
+		stub:               svcv1.NewCerbosServiceClient(cc),
+		health:             healthpb.NewHealthClient(cc),
+		lifecycle:          newLifecycle(),
+		ctxDecorator:       conf.ctxDecorator,
+		defaultAuxData:     conf.defaultAuxData,
+		policyVersion:      conf.policyVersion,
+		compressMinChecks:  conf.compressMinChecks,
+		chunkParallelism:   conf.chunkParallelism,
+		defaultCallTimeout: conf.defaultCallTimeout,
+	}
+
+	if conf.batchConf != nil {
+		client.batcher = newAdaptiveBatcher(*conf.batchConf)
+	}
+
+	if conf.reqIDLogger != nil || conf.reqIDCounter {
+		client.reqIDTracker = newRequestIDTracker(conf.reqIDLogger, conf.reqIDCounter)
+	}
+
+	return client
+}
+
+func mkConfig(address string, opts ...Opt) *config {
 	conf := &config{
-		address:        address,
-		connectTimeout: 30 * time.Second, //nolint:mnd
-		maxRetries:     3,                //nolint:mnd
-		retryTimeout:   2 * time.Second,  //nolint:mnd
-		userAgent:      internal.UserAgent("grpc"),
+		address:          address,
+		chunkParallelism: defaultChunkParallelism,
+		connectTimeout:   30 * time.Second, //nolint:mnd
+		maxRetries:       3,                //nolint:mnd
+		poolSize:         1,
+		retryTimeout:     2 * time.Second, //nolint:mnd
+		userAgent:        internal.UserAgent("grpc"),
 	}
 
 	for _, o := range opts {
 		o(conf)
 	}
 
+	if conf.noCompression {
+		conf.autoCompression = false
+		conf.compressMinChecks = 0
+	}
+
+	return conf
+}
+
+func mkConn(address string, opts ...Opt) (*grpc.ClientConn, *config, error) {
+	conf := mkConfig(address, opts...)
+
 	dialOpts, err := mkDialOpts(conf)
 	if err != nil {
 		return nil, nil, err
@@ -185,33 +597,61 @@ func mkDialOpts(conf *config) ([]grpc.DialOption, error) {
 		dialOpts = append(dialOpts, grpc.WithStatsHandler(conf.statsHandler))
 	}
 
+	if conf.noServiceConfig {
+		dialOpts = append(dialOpts, grpc.WithDisableServiceConfig())
+	}
+
+	serviceConfig, err := conf.effectiveServiceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if serviceConfig != "" {
+		dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(serviceConfig))
+	}
+
 	if conf.connectTimeout > 0 {
 		dialOpts = append(dialOpts, grpc.WithConnectParams(grpc.ConnectParams{MinConnectTimeout: conf.connectTimeout}))
 	}
 
-	streamInterceptors := conf.streamInterceptors
-	unaryInterceptors := conf.unaryInterceptors
+	if conf.keepAlive != nil {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(*conf.keepAlive))
+	}
+
+	if conf.maxConnIdleTime != nil {
+		dialOpts = append(dialOpts, grpc.WithIdleTimeout(*conf.maxConnIdleTime))
+	}
+
+	if conf.waitForReady {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.WaitForReady(true)))
+	}
+
+	compressionOpts, err := conf.compressionCallOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(compressionOpts) > 0 {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(compressionOpts...))
+	}
 
+	streamInterceptors := conf.streamInterceptors
 	if conf.maxRetries > 0 && conf.retryTimeout > 0 {
 		streamInterceptors = append(
 			[]grpc.StreamClientInterceptor{
-				grpc_retry.StreamClientInterceptor(
-					grpc_retry.WithMax(conf.maxRetries),
-					grpc_retry.WithPerRetryTimeout(conf.retryTimeout),
-				),
+				grpc_retry.StreamClientInterceptor(mkRetryCallOpts(conf, conf.retryBackoff)...),
 			},
 			streamInterceptors...,
 		)
+	}
 
-		unaryInterceptors = append(
-			[]grpc.UnaryClientInterceptor{
-				grpc_retry.UnaryClientInterceptor(
-					grpc_retry.WithMax(conf.maxRetries),
-					grpc_retry.WithPerRetryTimeout(conf.retryTimeout),
-				),
-			},
-			unaryInterceptors...,
-		)
+	if conf.panicHandler != nil {
+		streamInterceptors = append([]grpc.StreamClientInterceptor{panicRecoveryStreamInterceptor(conf.panicHandler)}, streamInterceptors...)
+	}
+
+	unaryInterceptors, err := mkUnaryInterceptors(conf)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(streamInterceptors) > 0 {
@@ -230,19 +670,224 @@ func mkDialOpts(conf *config) ([]grpc.DialOption, error) {
 			return nil, fmt.Errorf("failed to create TLS config: %w", err)
 		}
 
-		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
-		if conf.tlsAuthority != "" {
-			dialOpts = append(dialOpts, grpc.WithAuthority(conf.tlsAuthority))
+		var creds credentials.TransportCredentials = credentials.NewTLS(tlsConf)
+		if conf.handshakeTimeout > 0 {
+			creds = newHandshakeTimeoutCredentials(creds, conf.handshakeTimeout)
 		}
+
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
+	}
+
+	if authority := conf.effectiveAuthority(); authority != "" {
+		dialOpts = append(dialOpts, grpc.WithAuthority(authority))
 	}
 
 	if conf.playgroundInstance != "" {
 		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(newPlaygroundInstanceCredentials(conf.playgroundInstance)))
 	}
 
+	basicAuth, err := mkBasicAuthCredentials(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if basicAuth != nil {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(basicAuth))
+	}
+
+	if conf.tokenSource != nil {
+		if conf.plaintext {
+			return nil, errors.New("bearer token credentials can't be used with WithPlaintext because tokens are only sent over TLS")
+		}
+
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: conf.tokenSource}))
+	}
+
 	return dialOpts, nil
 }
 
+// effectiveServiceConfig returns the service config set using WithServiceConfig or WithLoadBalancingPolicy, if any.
+func (conf *config) effectiveServiceConfig() (string, error) {
+	if conf.lbPolicy == "" {
+		return conf.serviceConfig, nil
+	}
+
+	if conf.serviceConfig != "" {
+		return "", errors.New("WithServiceConfig and WithLoadBalancingPolicy can't be used together: set the policy in the service config instead")
+	}
+
+	sc, err := json.Marshal(map[string]any{"loadBalancingConfig": []map[string]any{{conf.lbPolicy: struct{}{}}}})
+	if err != nil {
+		return "", fmt.Errorf("failed to create service config: %w", err)
+	}
+
+	return string(sc), nil
+}
+
+// validateForConn checks that no options that only apply when dialling a new connection have been set.
+func (conf *config) validateForConn() error {
+	var dialOnly []string
+	for name, set := range map[string]bool{
+		"WithPlaintext":                conf.plaintext,
+		"WithTLSInsecure":              conf.tlsInsecure,
+		"WithTLSAuthority":             conf.tlsAuthority != "",
+		"WithAuthority":                conf.authority != "",
+		"WithTLSCACert":                conf.tlsCACert != "",
+		"WithTLSCACertPEM":             conf.tlsCACertPEM != nil,
+		"WithTLSClientCert":            conf.tlsClientCert != "",
+		"WithTLSClientCertPEM":         conf.tlsClientCertPEM != nil,
+		"WithTLSHandshakeTimeout":      conf.handshakeTimeout > 0,
+		"WithKeepAlive":                conf.keepAlive != nil,
+		"WithMaxConnectionIdleTime":    conf.maxConnIdleTime != nil,
+		"WithStatsHandler":             conf.statsHandler != nil,
+		"WithDisableServiceConfig":     conf.noServiceConfig,
+		"WithServiceConfig":            conf.serviceConfig != "",
+		"WithLoadBalancingPolicy":      conf.lbPolicy != "",
+		"WithGRPCChannelzRegistration": conf.channelzAddr != "",
+		"WithPlaygroundInstance":       conf.playgroundInstance != "",
+		"WithStreamInterceptors":       len(conf.streamInterceptors) > 0,
+		"WithConnectionPool":           conf.poolSize != 1,
+		"WithUserAgent":                conf.userAgent != internal.UserAgent("grpc"),
+	} {
+		if set {
+			dialOnly = append(dialOnly, name)
+		}
+	}
+
+	if len(dialOnly) > 0 {
+		sort.Strings(dialOnly)
+		return fmt.Errorf("options that configure the connection can't be used with an existing connection: %s", strings.Join(dialOnly, ", "))
+	}
+
+	return nil
+}
+
+// interceptedConn applies the unary interceptors and call options of a client to calls made using a connection that it doesn't own.
+type interceptedConn struct {
+	conn         grpc.ClientConnInterface
+	interceptors []grpc.UnaryClientInterceptor
+	callOpts     []grpc.CallOption
+}
+
+func (ic *interceptedConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	invoker := func(ctx context.Context, method string, req, reply any, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return ic.conn.Invoke(ctx, method, req, reply, opts...)
+	}
+
+	return chainUnaryInterceptors(ic.interceptors, invoker)(ctx, method, args, reply, nil, append(ic.callOpts, opts...)...)
+}
+
+func (ic *interceptedConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return ic.conn.NewStream(ctx, desc, method, append(ic.callOpts, opts...)...)
+}
+
+// chainUnaryInterceptors returns an invoker that runs the interceptors in order before calling the given invoker.
+func chainUnaryInterceptors(interceptors []grpc.UnaryClientInterceptor, invoker grpc.UnaryInvoker) grpc.UnaryInvoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return interceptor(ctx, method, req, reply, cc, next, opts...)
+		}
+	}
+
+	return invoker
+}
+
+// effectiveAuthority returns the authority to use for the connection, if it has been overridden.
+func (conf *config) effectiveAuthority() string {
+	if conf.authority != "" {
+		return conf.authority
+	}
+
+	if !conf.plaintext {
+		return conf.tlsAuthority
+	}
+
+	return ""
+}
+
+// mkUnaryInterceptors returns the full chain of unary interceptors including those added by the SDK.
+func mkUnaryInterceptors(conf *config) ([]grpc.UnaryClientInterceptor, error) {
+	if conf.adaptiveTimeout != nil {
+		if err := conf.adaptiveTimeout.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.retryBudget != nil {
+		if err := conf.retryBudget.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	unaryInterceptors := conf.unaryInterceptors
+
+	var metricsInterceptors []grpc.UnaryClientInterceptor
+	if conf.metricsRegisterer != nil {
+		metrics, err := newClientMetrics(conf.metricsRegisterer)
+		if err != nil {
+			return nil, err
+		}
+
+		// The attempt counting interceptor is placed innermost so that retries are counted.
+		metricsInterceptors = metrics.interceptors()
+		unaryInterceptors = append(append([]grpc.UnaryClientInterceptor{}, unaryInterceptors...), metricsInterceptors[1])
+	}
+
+	var maxAttemptsInterceptors []grpc.UnaryClientInterceptor
+	if conf.maxTotalAttempts > 0 {
+		// The counting interceptor is placed innermost so that every attempt is seen, including those made by retries.
+		maxAttemptsInterceptors = mkMaxAttemptsInterceptors(conf.maxTotalAttempts)
+		unaryInterceptors = append(append([]grpc.UnaryClientInterceptor{}, unaryInterceptors...), maxAttemptsInterceptors[1])
+	}
+
+	if conf.maxRetries > 0 && conf.retryTimeout > 0 {
+		unaryInterceptors = append(mkRetryInterceptors(conf), unaryInterceptors...)
+	}
+
+	if conf.credentialRefresh != nil {
+		// Placed outermost so that the refresh happens at most once per call regardless of retries.
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{credentialRefreshInterceptor(conf.credentialRefresh)}, unaryInterceptors...)
+	}
+
+	if maxAttemptsInterceptors != nil {
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{maxAttemptsInterceptors[0]}, unaryInterceptors...)
+	}
+
+	if conf.adaptiveTimeout != nil {
+		// Placed outside the retries so that the timeout applies to the call as a whole.
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{adaptiveTimeoutInterceptor(conf.adaptiveTimeout)}, unaryInterceptors...)
+	}
+
+	if conf.autoCompression {
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{autoCompressionInterceptor()}, unaryInterceptors...)
+	}
+
+	if conf.responseValidation {
+		// Placed outside the retries so that only the final response is validated.
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{responseValidationInterceptor()}, unaryInterceptors...)
+	}
+
+	if metricsInterceptors != nil {
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{metricsInterceptors[0]}, unaryInterceptors...)
+	}
+
+	if conf.tracerProvider != nil {
+		// Placed outside the retries so that the span covers the call as a whole.
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{tracingInterceptor(conf.tracerProvider)}, unaryInterceptors...)
+	}
+
+	if conf.logger != nil {
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{loggingInterceptor(conf.logger, conf.logPrincipalAttrs)}, unaryInterceptors...)
+	}
+
+	if conf.panicHandler != nil {
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{panicRecoveryInterceptor(conf.panicHandler)}, unaryInterceptors...)
+	}
+
+	return unaryInterceptors, nil
+}
+
 func mkTLSConfig(conf *config) (*tls.Config, error) {
 	tlsConf := internal.DefaultTLSConfig()
 
@@ -250,25 +895,54 @@ func mkTLSConfig(conf *config) (*tls.Config, error) {
 		tlsConf.InsecureSkipVerify = true
 	}
 
+	if conf.tlsCACert != "" && conf.tlsCACertPEM != nil {
+		return nil, errors.New("CA certificate set using both WithTLSCACert and WithTLSCACertPEM")
+	}
+
+	caPEM, caSource := conf.tlsCACertPEM, "CA certificate PEM"
 	if conf.tlsCACert != "" {
-		bs, err := os.ReadFile(conf.tlsCACert)
+		bs, err := readTLSFile("CA certificate", conf.tlsCACert)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load CA certificate from %s: %w", conf.tlsCACert, err)
+			return nil, err
 		}
 
+		caPEM, caSource = bs, "CA certificate file "+conf.tlsCACert
+	}
+
+	if caPEM != nil {
 		certPool := x509.NewCertPool()
-		ok := certPool.AppendCertsFromPEM(bs)
+		ok := certPool.AppendCertsFromPEM(caPEM)
 		if !ok {
-			return nil, errors.New("failed to append CA certificates to the pool")
+			return nil, fmt.Errorf("failed to parse %s: no PEM-encoded certificates found", caSource)
 		}
 
 		tlsConf.RootCAs = certPool
 	}
 
-	if conf.tlsClientCert != "" && conf.tlsClientKey != "" {
-		certificate, err := tls.LoadX509KeyPair(conf.tlsClientCert, conf.tlsClientKey)
+	hasClientCertFile := conf.tlsClientCert != "" && conf.tlsClientKey != ""
+	if hasClientCertFile && conf.tlsClientCertPEM != nil {
+		return nil, errors.New("client certificate set using both WithTLSClientCert and WithTLSClientCertPEM")
+	}
+
+	certPEM, keyPEM := conf.tlsClientCertPEM, conf.tlsClientKeyPEM
+	clientCertSource := "client certificate PEM and key PEM"
+	if hasClientCertFile {
+		var err error
+		if certPEM, err = readTLSFile("client certificate", conf.tlsClientCert); err != nil {
+			return nil, err
+		}
+
+		if keyPEM, err = readTLSFile("client key", conf.tlsClientKey); err != nil {
+			return nil, err
+		}
+
+		clientCertSource = fmt.Sprintf("client certificate file %s and key file %s", conf.tlsClientCert, conf.tlsClientKey)
+	}
+
+	if certPEM != nil {
+		certificate, err := tls.X509KeyPair(certPEM, keyPEM)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load client certificate and key from [%s, %s]: %w", conf.tlsClientCert, conf.tlsClientKey, err)
+			return nil, fmt.Errorf("failed to parse %s: %w", clientCertSource, err)
 		}
 		tlsConf.Certificates = []tls.Certificate{certificate}
 	}
@@ -276,12 +950,45 @@ func mkTLSConfig(conf *config) (*tls.Config, error) {
 	return tlsConf, nil
 }
 
+// readTLSFile reads the given TLS file and produces an error that distinguishes between missing and unreadable files.
+func readTLSFile(desc, path string) ([]byte, error) {
+	bs, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		return bs, nil
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("%s file %s does not exist: %w", desc, path, err)
+	case errors.Is(err, fs.ErrPermission):
+		return nil, fmt.Errorf("%s file %s is not readable: permission denied: %w", desc, path, err)
+	default:
+		return nil, fmt.Errorf("failed to read %s file %s: %w", desc, path, err)
+	}
+}
+
 type GRPCClient struct {
-	stub svcv1.CerbosServiceClient
-	opts *internal.ReqOpt
+	stub               svcv1.CerbosServiceClient
+	health             healthpb.HealthClient
+	opts               *internal.ReqOpt
+	conn               *grpc.ClientConn
+	closeFn            func() error
+	lifecycle          *lifecycle
+	ctxDecorator       func(context.Context) context.Context
+	reqIDTracker       *requestIDTracker
+	batcher            *adaptiveBatcher
+	defaultAuxData     *requestv1.AuxData
+	policyVersion      string
+	compressMinChecks  int
+	chunkParallelism   int
+	defaultCallTimeout time.Duration
 }
 
 func (c *GRPCClient) PlanResources(ctx context.Context, principal *Principal, resource *Resource, action string) (*PlanResourcesResponse, error) {
+	done, err := c.lifecycle.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	if err := internal.IsValid(principal); err != nil {
 		return nil, fmt.Errorf("invalid principal: %w", err)
 	}
@@ -297,32 +1004,55 @@ func (c *GRPCClient) PlanResources(ctx context.Context, principal *Principal, re
 		return nil, fmt.Errorf("invalid resource: %w", err)
 	}
 
+	if action == "" && c.opts != nil {
+		action = c.opts.DefaultAction
+	}
+
 	req := &requestv1.PlanResourcesRequest{
-		RequestId: c.opts.RequestID(ctx),
+		RequestId: c.requestID(ctx),
 		Action:    action,
 		Principal: principal.Obj,
 		Resource: &enginev1.PlanResourcesInput_Resource{
 			Kind:          resource.Obj.Kind,
 			Attr:          resource.Obj.Attr,
-			PolicyVersion: resource.Obj.PolicyVersion,
+			PolicyVersion: c.resolvePolicyVersion(resource.Obj.PolicyVersion),
 			Scope:         resource.Obj.Scope,
 		},
 	}
 
 	if c.opts != nil {
-		req.AuxData = c.opts.AuxData
+		req.AuxData = c.opts.AuxDataFor(ctx)
 		req.IncludeMeta = c.opts.IncludeMeta
 	}
+	req.AuxData = c.resolveAuxData(req.AuxData)
 
-	result, err := c.stub.PlanResources(c.opts.Context(ctx), req)
+	callCtx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	result, err := c.stub.PlanResources(callCtx, req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", c.classifyErr(err))
 	}
 
 	return &PlanResourcesResponse{PlanResourcesResponse: result}, nil
 }
 
 func (c *GRPCClient) CheckResources(ctx context.Context, principal *Principal, resourceBatch *ResourceBatch) (*CheckResourcesResponse, error) {
+	done, err := c.lifecycle.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	req, err := c.newCheckResourcesRequest(ctx, principal, resourceBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.checkResources(ctx, req)
+}
+
+func (c *GRPCClient) newCheckResourcesRequest(ctx context.Context, principal *Principal, resourceBatch *ResourceBatch) (*requestv1.CheckResourcesRequest, error) {
 	if err := internal.IsValid(principal); err != nil {
 		return nil, fmt.Errorf("invalid principal: %w", err)
 	}
@@ -332,25 +1062,106 @@ func (c *GRPCClient) CheckResources(ctx context.Context, principal *Principal, r
 	}
 
 	req := &requestv1.CheckResourcesRequest{
-		RequestId: c.opts.RequestID(ctx),
+		RequestId: c.requestID(ctx),
 		Principal: principal.Obj,
-		Resources: resourceBatch.Batch,
+		Resources: c.withPolicyVersion(resourceBatch.Batch),
 	}
 
 	if c.opts != nil {
-		req.AuxData = c.opts.AuxData
+		req.AuxData = c.opts.AuxDataFor(ctx)
 		req.IncludeMeta = c.opts.IncludeMeta
 	}
+	req.AuxData = c.resolveAuxData(req.AuxData)
+
+	return req, nil
+}
+
+// CheckResourcesJSON sends a check request given as JSON in the same shape as the request body accepted by the
+// Cerbos CheckResources API. It's intended for proxies and gateways that receive check requests from elsewhere
+// and need to forward them without converting to Principal and Resource objects first.
+// The request is validated before sending. Request ID, policy version and request options configured on the client
+// are applied only when the JSON does not set them.
+func (c *GRPCClient) CheckResourcesJSON(ctx context.Context, raw json.RawMessage) (*CheckResourcesResponse, error) {
+	done, err := c.lifecycle.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	req := &requestv1.CheckResourcesRequest{}
+	if err := protojson.Unmarshal(raw, req); err != nil {
+		return nil, fmt.Errorf("invalid request JSON: %w", err)
+	}
+
+	if req.RequestId == "" {
+		req.RequestId = c.requestID(ctx)
+	}
+	req.Resources = c.withPolicyVersion(req.Resources)
+
+	if c.opts != nil {
+		if req.AuxData == nil {
+			req.AuxData = c.opts.AuxDataFor(ctx)
+		}
+		req.IncludeMeta = req.IncludeMeta || c.opts.IncludeMeta
+	}
+	req.AuxData = c.resolveAuxData(req.AuxData)
+
+	if err := internal.Validate(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	return c.checkResources(ctx, req)
+}
+
+func (c *GRPCClient) checkResources(ctx context.Context, req *requestv1.CheckResourcesRequest) (*CheckResourcesResponse, error) {
+	resp, err := c.sendCheckResources(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkStrictValidation(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// checkStrictValidation returns an error if the StrictValidation request option is set and the response has validation errors.
+func (c *GRPCClient) checkStrictValidation(resp *CheckResourcesResponse) error {
+	if c.opts == nil || !c.opts.StrictValidation {
+		return nil
+	}
+
+	if err := resp.Errors(); err != nil {
+		return fmt.Errorf("%w: %w", ErrSchemaValidationFailed, err)
+	}
 
-	result, err := c.stub.CheckResources(c.opts.Context(ctx), req)
+	return nil
+}
+
+func (c *GRPCClient) sendCheckResources(ctx context.Context, req *requestv1.CheckResourcesRequest) (*CheckResourcesResponse, error) {
+	if c.batcher != nil {
+		return c.checkResourcesInBatches(ctx, req)
+	}
+
+	callCtx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	result, err := c.stub.CheckResources(callCtx, req, c.checkCallOpts(req.Resources)...)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", c.classifyErr(err))
 	}
 
 	return &CheckResourcesResponse{CheckResourcesResponse: result}, nil
 }
 
 func (c *GRPCClient) IsAllowed(ctx context.Context, principal *Principal, resource *Resource, action string) (bool, error) {
+	done, err := c.lifecycle.begin()
+	if err != nil {
+		return false, err
+	}
+	defer done()
+
 	if err := internal.IsValid(principal); err != nil {
 		return false, fmt.Errorf("invalid principal: %w", err)
 	}
@@ -360,35 +1171,256 @@ func (c *GRPCClient) IsAllowed(ctx context.Context, principal *Principal, resour
 	}
 
 	req := &requestv1.CheckResourcesRequest{
-		RequestId: c.opts.RequestID(ctx),
+		RequestId: c.requestID(ctx),
 		Principal: principal.Obj,
-		Resources: []*requestv1.CheckResourcesRequest_ResourceEntry{
+		Resources: c.withPolicyVersion([]*requestv1.CheckResourcesRequest_ResourceEntry{
 			{Actions: []string{action}, Resource: resource.Obj},
-		},
+		}),
 	}
 
 	if c.opts != nil {
-		req.AuxData = c.opts.AuxData
+		req.AuxData = c.opts.AuxDataFor(ctx)
 		req.IncludeMeta = c.opts.IncludeMeta
 	}
+	req.AuxData = c.resolveAuxData(req.AuxData)
+
+	callCtx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	result, err := c.stub.CheckResources(callCtx, req)
+	if err != nil {
+		return false, fmt.Errorf("request failed: %w", c.classifyErr(err))
+	}
+
+	resp := &CheckResourcesResponse{CheckResourcesResponse: result}
+	if err := c.checkStrictValidation(resp); err != nil {
+		return false, err
+	}
+
+	rr := resp.GetResource(resource.ID(), MatchResourceKind(resource.Kind()))
+	if err := rr.Err(); err != nil {
+		return false, fmt.Errorf("unexpected response from server: %w", err)
+	}
+
+	return rr.Actions[action] == effectv1.Effect_EFFECT_ALLOW, nil
+}
+
+// CheckOne checks a single action against the resource and returns its effect along with any validation errors reported
+// by the server for the resource. Unlike IsAllowed, it distinguishes between actions that were denied by a policy (EFFECT_DENY)
+// and actions that were denied because no policy matched them (EFFECT_NO_MATCH). The metadata needed to tell them apart is
+// always requested.
+func (c *GRPCClient) CheckOne(ctx context.Context, principal *Principal, resource *Resource, action string) (effectv1.Effect, []*schemav1.ValidationError, error) {
+	resp, err := c.withIncludeMeta().CheckResources(ctx, principal, NewResourceBatch().Add(resource, action))
+	if err != nil {
+		return effectv1.Effect_EFFECT_UNSPECIFIED, nil, err
+	}
+
+	result := resp.GetResource(resource.ID(), MatchResourceKind(resource.Kind()))
+	if err := result.Err(); err != nil {
+		return effectv1.Effect_EFFECT_UNSPECIFIED, nil, fmt.Errorf("unexpected response from server: %w", err)
+	}
+
+	return result.Effect(action), result.ValidationErrors, nil
+}
+
+// CheckResourceActions checks each of the actions against the resource in a single request and returns the outcome keyed by action.
+func (c *GRPCClient) CheckResourceActions(ctx context.Context, principal *Principal, resource *Resource, actions ...string) (map[string]bool, error) {
+	if len(actions) == 0 {
+		return nil, errors.New("at least one action is required")
+	}
 
-	result, err := c.stub.CheckResources(c.opts.Context(ctx), req)
+	resp, err := c.CheckResources(ctx, principal, NewResourceBatch().Add(resource, actions...))
 	if err != nil {
-		return false, fmt.Errorf("request failed: %w", err)
+		return nil, err
+	}
+
+	result := resp.GetResource(resource.ID(), MatchResourceKind(resource.Kind()))
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("unexpected response from server: %w", err)
+	}
+
+	out := make(map[string]bool, len(actions))
+	for _, a := range actions {
+		out[a] = result.IsAllowed(a)
+	}
+
+	return out, nil
+}
+
+// CheckMatrix checks each of the actions against each of the resources in a single request.
+// Resources of different kinds can be mixed in the same call.
+func (c *GRPCClient) CheckMatrix(ctx context.Context, principal *Principal, resources []*Resource, actions []string) (Matrix, error) {
+	batch := NewResourceBatch()
+	for _, r := range resources {
+		batch.Add(r, actions...)
 	}
 
-	if len(result.Results) == 0 {
-		return false, fmt.Errorf("unexpected response from server")
+	resp, err := c.CheckResources(ctx, principal, batch)
+	if err != nil {
+		return Matrix{}, err
 	}
 
-	return result.Results[0].Actions[action] == effectv1.Effect_EFFECT_ALLOW, nil
+	return Matrix{resp: resp}, nil
+}
+
+// AccessibleKinds returns the resource kinds the principal is allowed to access, in the order of the given candidates.
+// A kind is included if the principal is allowed to perform the action of at least one of the candidates of that kind.
+// All the candidates are checked in a single request.
+//
+// Because Cerbos evaluates policies against concrete resources, each candidate is checked against a probe resource with
+// a placeholder ID and no attributes. The result does not take into account rules with conditions that depend on resource
+// attributes, which are likely to evaluate to false for the probe. Use it for coarse-grained decisions such as building
+// navigation menus and always check access to individual resources before acting on them.
+func (c *GRPCClient) AccessibleKinds(ctx context.Context, principal *Principal, candidates []KindAction) ([]string, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	batch := NewResourceBatch()
+	for i, ka := range candidates {
+		batch.Add(NewResource(ka.Kind, probeResourceID(i)), ka.Action)
+	}
+
+	resp, err := c.CheckResources(ctx, principal, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	var kinds []string
+	seen := make(map[string]struct{}, len(candidates))
+	for i, ka := range candidates {
+		if _, ok := seen[ka.Kind]; ok {
+			continue
+		}
+
+		if resp.GetResource(probeResourceID(i), MatchResourceKind(ka.Kind)).IsAllowed(ka.Action) {
+			seen[ka.Kind] = struct{}{}
+			kinds = append(kinds, ka.Kind)
+		}
+	}
+
+	return kinds, nil
+}
+
+// CompareVersions checks whether the principal is allowed to perform the action on the resource under each of the given
+// policy versions, and returns the outcome keyed by version. It's intended for validating a new version of the policies
+// before promoting it: all versions are checked in a single request so that the results are consistent with each other.
+// The policy version set on the resource, if any, is ignored.
+func (c *GRPCClient) CompareVersions(ctx context.Context, principal *Principal, resource *Resource, action string, versions ...string) (map[string]bool, error) {
+	if len(versions) == 0 {
+		return nil, errors.New("at least one policy version is required")
+	}
+
+	if err := internal.IsValid(resource); err != nil {
+		return nil, fmt.Errorf("invalid resource: %w", err)
+	}
+
+	batch := NewResourceBatch()
+	for _, v := range versions {
+		if v == "" {
+			return nil, errors.New("policy version must not be empty")
+		}
+
+		versioned := &Resource{Obj: proto.Clone(resource.Obj).(*enginev1.Resource), timeEncoding: resource.timeEncoding} //nolint:forcetypeassert
+		versioned.Obj.PolicyVersion = v
+		batch.Add(versioned, action)
+	}
+
+	resp, err := c.CheckResources(ctx, principal, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		result := resp.GetResource(resource.ID(), MatchResourceKind(resource.Kind()), MatchResourcePolicyVersion(v))
+		if err := result.Err(); err != nil {
+			return nil, fmt.Errorf("no result for policy version %q: %w", v, err)
+		}
+		out[v] = result.IsAllowed(action)
+	}
+
+	return out, nil
+}
+
+// EffectiveRoles returns the derived roles activated for the principal when checking the given actions on the resource.
+// It's intended for administrative tooling that needs to explain why a principal has been granted or denied access.
+//
+// Cerbos only evaluates the derived roles referenced by the rules that match the requested actions, so the result depends
+// on the actions being checked. Pass all the actions of interest to get a complete picture. The principal's own roles are not included.
+func (c *GRPCClient) EffectiveRoles(ctx context.Context, principal *Principal, resource *Resource, actions ...string) ([]string, error) {
+	if len(actions) == 0 {
+		return nil, errors.New("at least one action is required")
+	}
+
+	resp, err := c.withIncludeMeta().CheckResources(ctx, principal, NewResourceBatch().Add(resource, actions...))
+	if err != nil {
+		return nil, err
+	}
+
+	result := resp.GetResource(resource.ID(), MatchResourceKind(resource.Kind()))
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	return result.EffectiveDerivedRoles(), nil
+}
+
+// PlanResourcesDebug produces a query plan like PlanResources and returns it along with information about how the server arrived at it.
+// It's intended for use during development to understand why a plan has a certain filter. The metadata needed to produce
+// the debug information is always requested, but it's only kept in the returned response if the IncludeMeta request option is set.
+func (c *GRPCClient) PlanResourcesDebug(ctx context.Context, principal *Principal, resource *Resource, action string) (*PlanResourcesResponse, *PlanDebugInfo, error) {
+	resp, err := c.withIncludeMeta().PlanResources(ctx, principal, resource, action)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info := resp.DebugInfo()
+	if !c.includeMeta() {
+		resp.Meta = nil
+	}
+
+	return resp, info, nil
+}
+
+// includeMeta returns true if the user has asked for metadata to be included in responses.
+func (c *GRPCClient) includeMeta() bool {
+	return c.opts != nil && c.opts.IncludeMeta
+}
+
+// withIncludeMeta returns a copy of the client that requests metadata in responses, for helpers that need the metadata
+// to produce their results. Helpers that return the response must strip the metadata unless includeMeta is true.
+// The request options of this client (such as aux data and headers) are kept as they can affect the outcome.
+func (c *GRPCClient) withIncludeMeta() *GRPCClient {
+	opts := internal.ReqOpt{}
+	if c.opts != nil {
+		opts = *c.opts
+	}
+	opts.IncludeMeta = true
+
+	client := *c
+	client.opts = &opts
+	return &client
+}
+
+func probeResourceID(i int) string {
+	return fmt.Sprintf("probe-%d", i)
 }
 
 func (c *GRPCClient) ServerInfo(ctx context.Context) (*ServerInfo, error) {
-	resp, err := c.stub.ServerInfo(c.opts.Context(ctx), &requestv1.ServerInfoRequest{})
+	done, err := c.lifecycle.begin()
 	if err != nil {
 		return nil, err
 	}
+	defer done()
+
+	callCtx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	resp, err := c.stub.ServerInfo(callCtx, &requestv1.ServerInfoRequest{})
+	if err != nil {
+		return nil, c.classifyErr(err)
+	}
 	return &ServerInfo{
 		ServerInfoResponse: resp,
 	}, nil
@@ -400,7 +1432,94 @@ func (c *GRPCClient) With(reqOpts ...RequestOpt) *GRPCClient {
 		ro(opts)
 	}
 
-	return &GRPCClient{opts: opts, stub: c.stub}
+	client := *c
+	client.opts = opts
+	return &client
+}
+
+// callContext returns the context to use for making an RPC. The returned function must be called when the RPC completes.
+func (c *GRPCClient) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	cancel := func() {}
+	if c.opts != nil && c.opts.DetachedTimeout > 0 {
+		ctx, cancel = context.WithTimeout(internal.DetachContext(ctx), c.opts.DetachedTimeout)
+	} else if _, ok := ctx.Deadline(); !ok && c.defaultCallTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.defaultCallTimeout)
+	}
+
+	ctx = c.opts.Context(ctx)
+	if c.ctxDecorator != nil {
+		ctx = c.ctxDecorator(ctx)
+	}
+
+	return ctx, cancel
+}
+
+func (c *GRPCClient) requestID(ctx context.Context) string {
+	id := c.opts.RequestID(ctx)
+	if c.reqIDTracker == nil {
+		return id
+	}
+
+	return c.reqIDTracker.track(id)
+}
+
+// resolvePolicyVersion returns the policy version to use for a resource that has the given version set on it.
+func (c *GRPCClient) resolvePolicyVersion(resourceVersion string) string {
+	if resourceVersion != "" {
+		return resourceVersion
+	}
+
+	if c.opts != nil && c.opts.PolicyVersion != "" {
+		return c.opts.PolicyVersion
+	}
+
+	return c.policyVersion
+}
+
+// resolveAuxData fills in the fields of the request auxiliary data that are not set using the client default.
+func (c *GRPCClient) resolveAuxData(auxData *requestv1.AuxData) *requestv1.AuxData {
+	if c.defaultAuxData == nil {
+		return auxData
+	}
+
+	if auxData.GetJwt() == nil {
+		return c.defaultAuxData
+	}
+
+	return auxData
+}
+
+// withPolicyVersion fills in the default policy version for resources that don't have one.
+// Entries are copied before modification so that the caller's objects are left untouched.
+func (c *GRPCClient) withPolicyVersion(entries []*requestv1.CheckResourcesRequest_ResourceEntry) []*requestv1.CheckResourcesRequest_ResourceEntry {
+	version := c.resolvePolicyVersion("")
+	if version == "" {
+		return entries
+	}
+
+	out := make([]*requestv1.CheckResourcesRequest_ResourceEntry, len(entries))
+	for i, entry := range entries {
+		if entry.GetResource() == nil || entry.Resource.PolicyVersion != "" {
+			out[i] = entry
+			continue
+		}
+
+		resource := proto.Clone(entry.Resource).(*enginev1.Resource) //nolint:forcetypeassert
+		resource.PolicyVersion = version
+		out[i] = &requestv1.CheckResourcesRequest_ResourceEntry{Actions: entry.Actions, Resource: resource}
+	}
+
+	return out
+}
+
+// Close closes the underlying connection to the server.
+// Clients derived from this client using With share the same connection and must not be used after calling Close.
+func (c *GRPCClient) Close() error {
+	if c.closeFn == nil {
+		return nil
+	}
+
+	return c.closeFn()
 }
 
 func (c *GRPCClient) WithPrincipal(p *Principal) PrincipalCtx {
@@ -33,21 +33,62 @@ const (
 // - Environment: CERBOS_USERNAME and CERBOS_PASSWORD
 // - Netrc file (~/.netrc if an override is not defined in the NETRC environment variable)
 //
-// Note that Unix domain socket connections cannot fallback to netrc and require either the
-// environment variables to be defined or the credentials to provided explicitly via the
-// NewAdminClientWithCredentials function.
+// Note that Unix domain socket connections cannot fallback to netrc unless the machine name to look up is set
+// using WithNetrcMachine. Otherwise, they require either the environment variables to be defined or the credentials
+// to provided explicitly via the NewAdminClientWithCredentials function.
 func NewAdminClient(address string, opts ...Opt) (*GRPCAdminClient, error) {
 	return NewAdminClientWithCredentials(address, "", "", opts...)
 }
 
+// WithBasicAuth sets the credentials used to authenticate using HTTP basic authentication.
+// Clients created using New, NewGRPCWeb and NewFromConn send the credentials with every call. They are only sent over TLS
+// connections unless WithPlaintext is used.
+// For admin clients, credentials passed as arguments to NewAdminClientWithCredentials take precedence, while credentials set
+// using this option take precedence over those defined in the environment or the netrc file.
+func WithBasicAuth(username, password string) Opt {
+	return func(c *config) {
+		c.basicAuthUsername = username
+		c.basicAuthPassword = password
+	}
+}
+
+// WithBasicAuthFromNetrc configures clients created using New, NewGRPCWeb and NewFromConn to authenticate using
+// HTTP basic authentication with credentials resolved in the same way as NewAdminClient. Credentials set using WithBasicAuth
+// take precedence, followed by the CERBOS_USERNAME and CERBOS_PASSWORD environment variables and the entry for the server
+// address in the netrc file (~/.netrc if an override is not defined in the NETRC environment variable).
+// Use WithNetrcMachine to look up a different netrc entry.
+func WithBasicAuthFromNetrc() Opt {
+	return func(c *config) {
+		c.basicAuthNetrc = true
+	}
+}
+
+// WithNetrcMachine sets the machine name used to look up credentials in the netrc file, instead of deriving it from the
+// server address. It's required for looking up credentials for Unix domain socket addresses.
+func WithNetrcMachine(name string) Opt {
+	return func(c *config) {
+		c.netrcMachine = name
+	}
+}
+
 // NewAdminClientWithCredentials creates a new admin client using credentials explicitly passed as arguments.
 func NewAdminClientWithCredentials(address, username, password string, opts ...Opt) (*GRPCAdminClient, error) {
+	conf := mkConfig(address, opts...)
+	if username == "" && password == "" {
+		username, password = conf.basicAuthUsername, conf.basicAuthPassword
+	}
+
 	// TODO: handle this in call site
-	target, user, pass, err := internal.LoadBasicAuthData(internal.OSEnvironment{}, address, username, password)
+	target, user, pass, err := internal.LoadBasicAuthDataForMachine(internal.OSEnvironment{}, address, conf.netrcMachine, username, password)
 	if err != nil {
 		return nil, err
 	}
 
+	// The admin client attaches the credentials to each call itself.
+	opts = append(opts[:len(opts):len(opts)], func(c *config) {
+		c.basicAuthUsername, c.basicAuthPassword, c.basicAuthNetrc = "", "", false
+	})
+
 	grpcConn, conf, err := mkConn(target, opts...)
 	if err != nil {
 		return nil, err
@@ -58,11 +99,12 @@ func NewAdminClientWithCredentials(address, username, password string, opts ...O
 		basicAuth = basicAuth.Insecure()
 	}
 
-	return &GRPCAdminClient{client: svcv1.NewCerbosAdminServiceClient(grpcConn), creds: basicAuth}, nil
+	return &GRPCAdminClient{client: svcv1.NewCerbosAdminServiceClient(grpcConn), conn: grpcConn, creds: basicAuth}, nil
 }
 
 type GRPCAdminClient struct {
 	client  svcv1.CerbosAdminServiceClient
+	conn    *grpc.ClientConn
 	creds   credentials.PerRPCCredentials
 	headers []string
 }
@@ -70,11 +112,22 @@ type GRPCAdminClient struct {
 func (c *GRPCAdminClient) WithHeaders(keyValues ...string) *GRPCAdminClient {
 	return &GRPCAdminClient{
 		client:  c.client,
+		conn:    c.conn,
 		creds:   c.creds,
 		headers: keyValues,
 	}
 }
 
+// Close closes the underlying connection to the server.
+// Clients derived from this client using WithHeaders share the same connection and must not be used after calling Close.
+func (c *GRPCAdminClient) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	return c.conn.Close()
+}
+
 func (c *GRPCAdminClient) AddOrUpdatePolicy(ctx context.Context, policies *PolicySet) error {
 	if err := policies.Validate(); err != nil {
 		return err
@@ -267,6 +320,10 @@ func (c *GRPCAdminClient) EnablePolicy(ctx context.Context, ids ...string) (uint
 }
 
 func (c *GRPCAdminClient) AddOrUpdateSchema(ctx context.Context, schemas *SchemaSet) error {
+	if err := schemas.Validate(); err != nil {
+		return err
+	}
+
 	all := schemas.GetSchemas()
 	for bs := 0; bs < len(all); bs += addSchemaBatchSize {
 		be := bs + addSchemaBatchSize
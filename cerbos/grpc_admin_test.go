@@ -7,6 +7,7 @@ package cerbos
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -335,3 +336,29 @@ func TestAdminClient(t *testing.T) {
 		}
 	})
 }
+
+func TestWithBasicAuth(t *testing.T) {
+	authHeader := func(t *testing.T, c *GRPCAdminClient) string {
+		t.Helper()
+
+		md, err := c.creds.GetRequestMetadata(context.Background())
+		require.NoError(t, err)
+		return md["authorization"]
+	}
+
+	t.Run("option", func(t *testing.T) {
+		c, err := NewAdminClient("passthrough:///127.0.0.1:3593", WithPlaintext(), WithBasicAuth("john", "hunter2"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		require.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("john:hunter2")), authHeader(t, c))
+	})
+
+	t.Run("explicit_credentials_take_precedence", func(t *testing.T) {
+		c, err := NewAdminClientWithCredentials("passthrough:///127.0.0.1:3593", adminUsername, adminPassword, WithPlaintext(), WithBasicAuth("john", "hunter2"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		require.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte(adminUsername+":"+adminPassword)), authHeader(t, c))
+	})
+}
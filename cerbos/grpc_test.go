@@ -7,17 +7,45 @@ package cerbos_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
 
 	"github.com/cerbos/cerbos-sdk-go/cerbos"
 	"github.com/cerbos/cerbos-sdk-go/internal/tests"
 	"github.com/cerbos/cerbos-sdk-go/testutil"
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+	schemav1 "github.com/cerbos/cerbos/api/genpb/cerbos/schema/v1"
+	svcv1 "github.com/cerbos/cerbos/api/genpb/cerbos/svc/v1"
 )
 
 const (
@@ -133,3 +161,1811 @@ func TestGRPCClient(t *testing.T) {
 		})
 	}
 }
+
+func TestPolicyVersion(t *testing.T) {
+	versions := make(chan []string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			got := make([]string, len(req.Resources))
+			for i, r := range req.Resources {
+				got[i] = r.Resource.PolicyVersion
+			}
+			versions <- got
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithPolicyVersion("client"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	unversioned := cerbos.NewResource("leave_request", "XX125")
+	versioned := cerbos.NewResource("leave_request", "XX225").WithPolicyVersion("resource")
+	batch := cerbos.NewResourceBatch().Add(unversioned, "view").Add(versioned, "view")
+
+	_, err = c.CheckResources(context.Background(), principal, batch)
+	require.NoError(t, err)
+	require.Equal(t, []string{"client", "resource"}, <-versions)
+
+	_, err = c.With(cerbos.PolicyVersion("request")).CheckResources(context.Background(), principal, batch)
+	require.NoError(t, err)
+	require.Equal(t, []string{"request", "resource"}, <-versions)
+
+	require.Empty(t, unversioned.Obj.PolicyVersion, "Caller's resource should not be modified")
+}
+
+func TestDefaultAuxData(t *testing.T) {
+	tokens := make(chan string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			tokens <- req.GetAuxData().GetJwt().GetToken()
+			return &responsev1.CheckResourcesResponse{
+				Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+					{Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Kind: "leave_request", Id: "XX125"}},
+				},
+			}, nil
+		},
+		planResources: func(_ context.Context, req *requestv1.PlanResourcesRequest) (*responsev1.PlanResourcesResponse, error) {
+			tokens <- req.GetAuxData().GetJwt().GetToken()
+			return &responsev1.PlanResourcesResponse{}, nil
+		},
+	})
+
+	defaultAuxData := &requestv1.AuxData{Jwt: &requestv1.AuxData_JWT{Token: "service-token"}}
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithDefaultAuxData(defaultAuxData))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	_, err = c.IsAllowed(context.Background(), principal, resource, "view")
+	require.NoError(t, err)
+	require.Equal(t, "service-token", <-tokens)
+
+	_, err = c.PlanResources(context.Background(), principal, resource, "view")
+	require.NoError(t, err)
+	require.Equal(t, "service-token", <-tokens)
+
+	_, err = c.With(cerbos.AuxDataJWT("user-token", "")).CheckResources(context.Background(), principal, cerbos.NewResourceBatch().Add(resource, "view"))
+	require.NoError(t, err)
+	require.Equal(t, "user-token", <-tokens)
+}
+
+func TestAuxDataJWTFromContext(t *testing.T) {
+	type tokenKey struct{}
+
+	tokens := make(chan string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		planResources: func(_ context.Context, req *requestv1.PlanResourcesRequest) (*responsev1.PlanResourcesResponse, error) {
+			tokens <- req.GetAuxData().GetJwt().GetToken()
+			return &responsev1.PlanResourcesResponse{}, nil
+		},
+	})
+
+	defaultAuxData := &requestv1.AuxData{Jwt: &requestv1.AuxData_JWT{Token: "service-token"}}
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithDefaultAuxData(defaultAuxData))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+	ctx := context.WithValue(context.Background(), tokenKey{}, "user-token")
+
+	_, err = c.With(cerbos.AuxDataJWTFromContext(tokenKey{})).PlanResources(ctx, principal, resource, "view")
+	require.NoError(t, err)
+	require.Equal(t, "user-token", <-tokens)
+
+	_, err = c.With(cerbos.AuxDataJWTFromContext(tokenKey{})).PlanResources(context.Background(), principal, resource, "view")
+	require.NoError(t, err)
+	require.Equal(t, "service-token", <-tokens, "Default should be used when the context has no token")
+
+	_, err = c.With(cerbos.AuxDataJWTFromContext(tokenKey{}), cerbos.AuxDataJWT("explicit-token", "")).PlanResources(ctx, principal, resource, "view")
+	require.NoError(t, err)
+	require.Equal(t, "explicit-token", <-tokens)
+}
+
+func TestAuthority(t *testing.T) {
+	authorities := make(chan string, 2)
+	recordAuthority := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		authorities <- strings.Join(md.Get(":authority"), ",")
+		return handler(ctx, req)
+	}
+
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(context.Context, *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	}, grpc.UnaryInterceptor(recordAuthority))
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithAuthority("cerbos.example.com"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	_, err = c.ServerInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "cerbos.example.com", <-authorities)
+
+	_, err = c.CheckResources(context.Background(), cerbos.NewPrincipal("john", "employee"), cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view"))
+	require.NoError(t, err)
+	require.Equal(t, "cerbos.example.com", <-authorities)
+}
+
+func TestContextDecorator(t *testing.T) {
+	tenants := make(chan []string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(ctx context.Context, _ *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			tenants <- md.Get("x-tenant")
+			return &responsev1.ServerInfoResponse{}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithContextDecorator(func(ctx context.Context) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "x-tenant", "acme")
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	// Headers set by request options must be preserved.
+	_, err = c.With(cerbos.Headers("x-tenant", "default")).ServerInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"default", "acme"}, <-tenants)
+}
+
+func TestRequestMetadata(t *testing.T) {
+	received := make(chan metadata.MD, 1)
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(ctx context.Context, _ *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			received <- md
+			return &responsev1.ServerInfoResponse{}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-trace-id", "abc", "x-tenant", "default")
+	_, err = c.With(
+		cerbos.Headers("x-source", "sdk"),
+		cerbos.Metadata(map[string]string{"x-tenant": "acme", "baggage": "team=identity"}),
+	).ServerInfo(ctx)
+	require.NoError(t, err)
+
+	md := <-received
+	require.Equal(t, []string{"abc"}, md.Get("x-trace-id"))
+	require.Equal(t, []string{"acme"}, md.Get("x-tenant"))
+	require.Equal(t, []string{"team=identity"}, md.Get("baggage"))
+	require.Equal(t, []string{"sdk"}, md.Get("x-source"))
+}
+
+func TestDefaultAction(t *testing.T) {
+	actions := make(chan string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		planResources: func(_ context.Context, req *requestv1.PlanResourcesRequest) (*responsev1.PlanResourcesResponse, error) {
+			actions <- req.Action
+			return &responsev1.PlanResourcesResponse{}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "")
+	dc := c.With(cerbos.DefaultAction("view"))
+
+	_, err = dc.PlanResources(context.Background(), principal, resource, "")
+	require.NoError(t, err)
+	require.Equal(t, "view", <-actions)
+
+	_, err = dc.PlanResources(context.Background(), principal, resource, "approve")
+	require.NoError(t, err)
+	require.Equal(t, "approve", <-actions)
+}
+
+func TestChannelzRegistration(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	channelzAddr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithGRPCChannelzRegistration(channelzAddr))
+	require.NoError(t, err)
+
+	_, err = c.ServerInfo(context.Background())
+	require.NoError(t, err)
+
+	conn, err := grpc.NewClient("passthrough:///"+channelzAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	resp, err := channelzpb.NewChannelzClient(conn).GetTopChannels(context.Background(), &channelzpb.GetTopChannelsRequest{})
+	require.NoError(t, err)
+
+	var found bool
+	for _, ch := range resp.Channel {
+		if ch.GetData().GetTarget() == addr {
+			found = true
+			require.NotZero(t, ch.GetData().GetCallsSucceeded())
+		}
+	}
+	require.True(t, found, "Client channel not found")
+
+	require.NoError(t, c.Close())
+	_, err = net.Dial("tcp", channelzAddr)
+	require.Error(t, err, "Channelz service still running after close")
+}
+
+func TestAccessibleKinds(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			resp := &responsev1.CheckResourcesResponse{RequestId: req.RequestId}
+			for _, entry := range req.Resources {
+				if len(entry.Resource.Attr) > 0 {
+					return nil, fmt.Errorf("unexpected attributes on probe resource %q", entry.Resource.Id)
+				}
+
+				actions := make(map[string]effectv1.Effect, len(entry.Actions))
+				for _, a := range entry.Actions {
+					actions[a] = effectv1.Effect_EFFECT_DENY
+					if entry.Resource.Kind == "leave_request" || (entry.Resource.Kind == "purchase_order" && a == "approve") {
+						actions[a] = effectv1.Effect_EFFECT_ALLOW
+					}
+				}
+
+				resp.Results = append(resp.Results, &responsev1.CheckResourcesResponse_ResultEntry{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+					Actions:  actions,
+				})
+			}
+			return resp, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	kinds, err := c.AccessibleKinds(context.Background(), cerbos.NewPrincipal("john", "employee"), []cerbos.KindAction{
+		{Kind: "salary_record", Action: "view"},
+		{Kind: "purchase_order", Action: "view"},
+		{Kind: "leave_request", Action: "view"},
+		{Kind: "purchase_order", Action: "approve"},
+		{Kind: "leave_request", Action: "approve"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"leave_request", "purchase_order"}, kinds)
+
+	kinds, err = c.AccessibleKinds(context.Background(), cerbos.NewPrincipal("john", "employee"), nil)
+	require.NoError(t, err)
+	require.Empty(t, kinds)
+}
+
+func TestServerTraceID(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			return &responsev1.CheckResourcesResponse{RequestId: req.RequestId, CerbosCallId: "01HGJXT2P8K1FGNHCFXCHAB6QM"}, nil
+		},
+		planResources: func(_ context.Context, req *requestv1.PlanResourcesRequest) (*responsev1.PlanResourcesResponse, error) {
+			return &responsev1.PlanResourcesResponse{RequestId: req.RequestId, CerbosCallId: "01HGJXT2P8K1FGNHCFXCHAB6QN"}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	checkResp, err := c.CheckResources(context.Background(), principal, cerbos.NewResourceBatch().Add(resource, "view"))
+	require.NoError(t, err)
+	require.Equal(t, "01HGJXT2P8K1FGNHCFXCHAB6QM", checkResp.ServerTraceID())
+
+	planResp, err := c.PlanResources(context.Background(), principal, resource, "view")
+	require.NoError(t, err)
+	require.Equal(t, "01HGJXT2P8K1FGNHCFXCHAB6QN", planResp.ServerTraceID())
+
+	var nilResp *cerbos.CheckResourcesResponse
+	require.Empty(t, nilResp.ServerTraceID())
+}
+
+func TestCheckResourcesJSON(t *testing.T) {
+	received := make(chan *requestv1.CheckResourcesRequest, 1)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			received <- req
+			return &responsev1.CheckResourcesResponse{RequestId: req.RequestId}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	t.Run("valid", func(t *testing.T) {
+		raw := json.RawMessage(`{
+			"requestId": "test-request",
+			"principal": {"id": "john", "roles": ["employee"], "attr": {"department": "marketing"}},
+			"resources": [{"actions": ["view", "approve"], "resource": {"kind": "leave_request", "id": "XX125"}}]
+		}`)
+
+		resp, err := c.CheckResourcesJSON(context.Background(), raw)
+		require.NoError(t, err)
+		require.Equal(t, "test-request", resp.RequestId)
+
+		req := <-received
+		require.Equal(t, "john", req.Principal.Id)
+		require.Equal(t, "marketing", req.Principal.Attr["department"].GetStringValue())
+		require.Equal(t, []string{"view", "approve"}, req.Resources[0].Actions)
+	})
+
+	t.Run("generated_request_id", func(t *testing.T) {
+		raw := json.RawMessage(`{
+			"principal": {"id": "john", "roles": ["employee"]},
+			"resources": [{"actions": ["view"], "resource": {"kind": "leave_request", "id": "XX125"}}]
+		}`)
+
+		_, err := c.CheckResourcesJSON(context.Background(), raw)
+		require.NoError(t, err)
+		require.NotEmpty(t, (<-received).RequestId)
+	})
+
+	t.Run("invalid_json", func(t *testing.T) {
+		_, err := c.CheckResourcesJSON(context.Background(), json.RawMessage(`{"principal": {"id": "john", "wibble": 1}}`))
+		require.ErrorContains(t, err, "invalid request JSON")
+	})
+
+	t.Run("invalid_request", func(t *testing.T) {
+		raw := json.RawMessage(`{"principal": {"id": "john", "roles": ["employee"]}, "resources": []}`)
+
+		_, err := c.CheckResourcesJSON(context.Background(), raw)
+		require.Error(t, err)
+		require.True(t, cerbos.IsInvalidArgument(err))
+	})
+}
+
+func TestCompressionForLargeResponsesOnly(t *testing.T) {
+	encodings := make(chan string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			return &responsev1.CheckResourcesResponse{RequestId: req.RequestId}, nil
+		},
+	}, grpc.StatsHandler(encodingRecorder(encodings)))
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithCompressionForLargeResponsesOnly(3))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+
+	_, err = c.CheckResources(context.Background(), principal, cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view", "approve"))
+	require.NoError(t, err)
+	require.Equal(t, "", <-encodings)
+
+	_, err = c.CheckResources(context.Background(), principal, cerbos.NewResourceBatch().
+		Add(cerbos.NewResource("leave_request", "XX125"), "view", "approve").
+		Add(cerbos.NewResource("leave_request", "XX225"), "view"))
+	require.NoError(t, err)
+	require.Equal(t, "gzip", <-encodings)
+}
+
+func TestAutoCompression(t *testing.T) {
+	testCases := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{name: "not_advertised", want: "gzip"},
+		{name: "gzip_advertised", acceptEncoding: "identity, gzip", want: "gzip"},
+		{name: "none_supported", acceptEncoding: "identity,deflate", want: ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			encodings := make(chan string, 1)
+			addr := startFakeServer(t, &fakeServer{
+				serverInfo: func(ctx context.Context, _ *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+					if tc.acceptEncoding != "" {
+						if err := grpc.SetHeader(ctx, metadata.Pairs("grpc-accept-encoding", tc.acceptEncoding)); err != nil {
+							return nil, err
+						}
+					}
+					return &responsev1.ServerInfoResponse{}, nil
+				},
+			}, grpc.StatsHandler(encodingRecorder(encodings)))
+
+			c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithAutoCompression())
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = c.Close() })
+
+			_, err = c.ServerInfo(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, "", <-encodings, "Probe call should not be compressed")
+
+			_, err = c.ServerInfo(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, tc.want, <-encodings)
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []cerbos.Opt
+		want string
+	}{
+		{name: "default", want: ""},
+		{name: "gzip", opts: []cerbos.Opt{cerbos.WithCompression("gzip")}, want: "gzip"},
+		{name: "identity", opts: []cerbos.Opt{cerbos.WithCompression("identity")}, want: ""},
+		{name: "disabled", opts: []cerbos.Opt{cerbos.WithCompression("gzip"), cerbos.WithAutoCompression(), cerbos.WithoutCompression()}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			encodings := make(chan string, 1)
+			addr := startFakeServer(t, &fakeServer{
+				serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+					return &responsev1.ServerInfoResponse{}, nil
+				},
+			}, grpc.StatsHandler(encodingRecorder(encodings)))
+
+			c, err := cerbos.New(addr, append(tc.opts, cerbos.WithPlaintext())...)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = c.Close() })
+
+			for i := 0; i < 2; i++ {
+				_, err = c.ServerInfo(context.Background())
+				require.NoError(t, err)
+				require.Equal(t, tc.want, <-encodings)
+			}
+		})
+	}
+
+	t.Run("unregistered", func(t *testing.T) {
+		_, err := cerbos.New("passthrough:///127.0.0.1:1", cerbos.WithPlaintext(), cerbos.WithCompression("lz4"))
+		require.ErrorContains(t, err, `compressor "lz4" is not registered`)
+	})
+}
+
+// encodingRecorder is a server stats handler that reports the compression used by each incoming call.
+type encodingRecorder chan<- string
+
+func (encodingRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r encodingRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if h, ok := s.(*stats.InHeader); ok {
+		r <- h.Compression
+	}
+}
+
+func (encodingRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (encodingRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestTLSFileErrors(t *testing.T) {
+	certsDir := tests.PathToTestDataDir(t, "certs")
+	certFile := filepath.Join(certsDir, "tls.crt")
+	keyFile := filepath.Join(certsDir, "tls.key")
+
+	dir := t.TempDir()
+	missingFile := filepath.Join(dir, "missing.crt")
+	garbageFile := filepath.Join(dir, "garbage.crt")
+	require.NoError(t, os.WriteFile(garbageFile, []byte("not a certificate"), 0o600))
+	unreadableFile := filepath.Join(dir, "unreadable.crt")
+	require.NoError(t, os.WriteFile(unreadableFile, []byte("not a certificate"), 0o000))
+
+	testCases := []struct {
+		name    string
+		opt     cerbos.Opt
+		wantIs  error
+		wantErr string
+		root    bool
+	}{
+		{
+			name:    "ca_not_found",
+			opt:     cerbos.WithTLSCACert(missingFile),
+			wantIs:  fs.ErrNotExist,
+			wantErr: fmt.Sprintf("CA certificate file %s does not exist", missingFile),
+		},
+		{
+			name:    "ca_permission_denied",
+			opt:     cerbos.WithTLSCACert(unreadableFile),
+			wantIs:  fs.ErrPermission,
+			wantErr: fmt.Sprintf("CA certificate file %s is not readable", unreadableFile),
+			root:    true,
+		},
+		{
+			name:    "ca_parse_failure",
+			opt:     cerbos.WithTLSCACert(garbageFile),
+			wantErr: fmt.Sprintf("failed to parse CA certificate file %s", garbageFile),
+		},
+		{
+			name:    "client_cert_not_found",
+			opt:     cerbos.WithTLSClientCert(missingFile, keyFile),
+			wantIs:  fs.ErrNotExist,
+			wantErr: fmt.Sprintf("client certificate file %s does not exist", missingFile),
+		},
+		{
+			name:    "client_key_permission_denied",
+			opt:     cerbos.WithTLSClientCert(certFile, unreadableFile),
+			wantIs:  fs.ErrPermission,
+			wantErr: fmt.Sprintf("client key file %s is not readable", unreadableFile),
+			root:    true,
+		},
+		{
+			name:    "client_cert_parse_failure",
+			opt:     cerbos.WithTLSClientCert(certFile, garbageFile),
+			wantErr: fmt.Sprintf("failed to parse client certificate file %s and key file %s", certFile, garbageFile),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.root && os.Geteuid() == 0 {
+				t.Skip("File permissions are not enforced for root")
+			}
+
+			_, err := cerbos.New("passthrough:///127.0.0.1:1", tc.opt)
+			require.ErrorContains(t, err, tc.wantErr)
+			if tc.wantIs != nil {
+				require.ErrorIs(t, err, tc.wantIs)
+			}
+		})
+	}
+
+	c, err := cerbos.New("passthrough:///127.0.0.1:1", cerbos.WithTLSCACert(certFile), cerbos.WithTLSClientCert(certFile, keyFile))
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+}
+
+func TestTLSPEM(t *testing.T) {
+	certsDir := tests.PathToTestDataDir(t, "certs")
+	certFile := filepath.Join(certsDir, "tls.crt")
+	keyFile := filepath.Join(certsDir, "tls.key")
+
+	certPEM, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	keyPEM, err := os.ReadFile(keyFile)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		opts    []cerbos.Opt
+		wantErr string
+	}{
+		{
+			name: "valid",
+			opts: []cerbos.Opt{cerbos.WithTLSCACertPEM(certPEM), cerbos.WithTLSClientCertPEM(certPEM, keyPEM)},
+		},
+		{
+			name:    "ca_parse_failure",
+			opts:    []cerbos.Opt{cerbos.WithTLSCACertPEM([]byte("not a certificate"))},
+			wantErr: "failed to parse CA certificate PEM",
+		},
+		{
+			name:    "client_cert_parse_failure",
+			opts:    []cerbos.Opt{cerbos.WithTLSClientCertPEM(certPEM, []byte("not a key"))},
+			wantErr: "failed to parse client certificate PEM and key PEM",
+		},
+		{
+			name:    "ca_path_and_pem",
+			opts:    []cerbos.Opt{cerbos.WithTLSCACert(certFile), cerbos.WithTLSCACertPEM(certPEM)},
+			wantErr: "CA certificate set using both WithTLSCACert and WithTLSCACertPEM",
+		},
+		{
+			name:    "client_cert_path_and_pem",
+			opts:    []cerbos.Opt{cerbos.WithTLSClientCert(certFile, keyFile), cerbos.WithTLSClientCertPEM(certPEM, keyPEM)},
+			wantErr: "client certificate set using both WithTLSClientCert and WithTLSClientCertPEM",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := cerbos.New("passthrough:///127.0.0.1:1", tc.opts...)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, c.Close())
+		})
+	}
+}
+
+func TestEffectiveRoles(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(ctx context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			if !req.IncludeMeta {
+				return nil, errors.New("meta not requested")
+			}
+
+			if md, _ := metadata.FromIncomingContext(ctx); len(md.Get("x-tenant")) == 0 {
+				return nil, errors.New("request options not preserved")
+			}
+
+			entry := req.Resources[0]
+			var roles []string
+			for _, a := range entry.Actions {
+				if a == "approve" {
+					roles = append(roles, "direct_manager")
+				}
+			}
+
+			return &responsev1.CheckResourcesResponse{
+				RequestId: req.RequestId,
+				Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+					{
+						Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+						Meta:     &responsev1.CheckResourcesResponse_ResultEntry_Meta{EffectiveDerivedRoles: roles},
+					},
+				},
+			}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	tc := c.With(cerbos.Headers("x-tenant", "acme"))
+	principal := cerbos.NewPrincipal("john", "manager")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	roles, err := tc.EffectiveRoles(context.Background(), principal, resource, "view", "approve")
+	require.NoError(t, err)
+	require.Equal(t, []string{"direct_manager"}, roles)
+
+	roles, err = tc.EffectiveRoles(context.Background(), principal, resource, "view")
+	require.NoError(t, err)
+	require.Empty(t, roles)
+
+	_, err = tc.EffectiveRoles(context.Background(), principal, resource)
+	require.Error(t, err)
+}
+
+func TestStrictValidation(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			results := make([]*responsev1.CheckResourcesResponse_ResultEntry, len(req.Resources))
+			for i, entry := range req.Resources {
+				results[i] = &responsev1.CheckResourcesResponse_ResultEntry{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+					Actions:  map[string]effectv1.Effect{"view": effectv1.Effect_EFFECT_ALLOW},
+				}
+
+				if entry.Resource.Id == "XX225" {
+					results[i].ValidationErrors = []*schemav1.ValidationError{
+						{Path: "/owner", Message: "expected string", Source: schemav1.ValidationError_SOURCE_RESOURCE},
+					}
+				}
+			}
+
+			return &responsev1.CheckResourcesResponse{RequestId: req.RequestId, Results: results}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	valid := cerbos.NewResource("leave_request", "XX125")
+	invalid := cerbos.NewResource("leave_request", "XX225")
+	batch := cerbos.NewResourceBatch().Add(valid, "view").Add(invalid, "view")
+
+	resp, err := c.CheckResources(context.Background(), principal, batch)
+	require.NoError(t, err)
+	require.Empty(t, resp.ValidationErrors("XX125"))
+	require.Len(t, resp.ValidationErrors("XX225"), 1)
+	require.Len(t, resp.AllValidationErrors(), 1)
+
+	strict := c.With(cerbos.StrictValidation())
+	_, err = strict.CheckResources(context.Background(), principal, batch)
+	require.ErrorIs(t, err, cerbos.ErrSchemaValidationFailed)
+	require.ErrorContains(t, err, `resource "XX225" failed validation`)
+
+	_, err = strict.IsAllowed(context.Background(), principal, invalid, "view")
+	require.ErrorIs(t, err, cerbos.ErrSchemaValidationFailed)
+
+	allowed, err := strict.IsAllowed(context.Background(), principal, valid, "view")
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	_, err = c.CheckResourcesInChunks(context.Background(), principal, batch, 1)
+	require.NoError(t, err)
+
+	_, err = strict.CheckResourcesInChunks(context.Background(), principal, batch, 1)
+	require.ErrorIs(t, err, cerbos.ErrSchemaValidationFailed)
+}
+
+func TestCheckOne(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			require.True(t, req.IncludeMeta)
+
+			entry := req.Resources[0]
+			action := entry.Actions[0]
+			result := &responsev1.CheckResourcesResponse_ResultEntry{
+				Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+				Actions:  map[string]effectv1.Effect{action: effectv1.Effect_EFFECT_DENY},
+				Meta: &responsev1.CheckResourcesResponse_ResultEntry_Meta{
+					Actions: map[string]*responsev1.CheckResourcesResponse_ResultEntry_Meta_EffectMeta{
+						action: {MatchedPolicy: "resource.leave_request.vdefault"},
+					},
+				},
+			}
+
+			switch action {
+			case "view":
+				result.Actions[action] = effectv1.Effect_EFFECT_ALLOW
+				result.ValidationErrors = []*schemav1.ValidationError{
+					{Path: "/owner", Message: "expected string", Source: schemav1.ValidationError_SOURCE_RESOURCE},
+				}
+			case "archive":
+				result.Meta.Actions[action].MatchedPolicy = "NO_MATCH"
+			case "delete":
+				result.Meta.Actions[action].MatchedPolicy = "NO_MATCH_FOR_SCOPE_PERMISSIONS"
+			}
+
+			return &responsev1.CheckResourcesResponse{RequestId: req.RequestId, Results: []*responsev1.CheckResourcesResponse_ResultEntry{result}}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	effect, verrs, err := c.CheckOne(context.Background(), principal, resource, "view")
+	require.NoError(t, err)
+	require.Equal(t, effectv1.Effect_EFFECT_ALLOW, effect)
+	require.Len(t, verrs, 1)
+	require.Equal(t, "/owner", verrs[0].Path)
+
+	effect, verrs, err = c.CheckOne(context.Background(), principal, resource, "approve")
+	require.NoError(t, err)
+	require.Equal(t, effectv1.Effect_EFFECT_DENY, effect)
+	require.Empty(t, verrs)
+
+	effect, _, err = c.CheckOne(context.Background(), principal, resource, "archive")
+	require.NoError(t, err)
+	require.Equal(t, effectv1.Effect_EFFECT_NO_MATCH, effect)
+
+	effect, _, err = c.CheckOne(context.Background(), principal, resource, "delete")
+	require.NoError(t, err)
+	require.Equal(t, effectv1.Effect_EFFECT_NO_MATCH, effect)
+}
+
+func TestCheckMatrix(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			results := make([]*responsev1.CheckResourcesResponse_ResultEntry, len(req.Resources))
+			for i, entry := range req.Resources {
+				actions := make(map[string]effectv1.Effect, len(entry.Actions))
+				for _, a := range entry.Actions {
+					actions[a] = effectv1.Effect_EFFECT_DENY
+					if entry.Resource.Kind == "leave_request" && a == "view" {
+						actions[a] = effectv1.Effect_EFFECT_ALLOW
+					}
+				}
+
+				results[i] = &responsev1.CheckResourcesResponse_ResultEntry{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+					Actions:  actions,
+				}
+			}
+
+			return &responsev1.CheckResourcesResponse{RequestId: req.RequestId, Results: results}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	resources := []*cerbos.Resource{cerbos.NewResource("leave_request", "1"), cerbos.NewResource("expense", "1")}
+	m, err := c.CheckMatrix(context.Background(), cerbos.NewPrincipal("john", "employee"), resources, []string{"view", "approve"})
+	require.NoError(t, err)
+	require.Len(t, m.Response().Results, 2)
+
+	// Without a matcher, the first resource with the ID is used.
+	require.True(t, m.Allowed("1", "view"))
+	require.False(t, m.Allowed("1", "approve"))
+
+	require.True(t, m.Allowed("1", "view", cerbos.MatchResourceKind("leave_request")))
+	require.False(t, m.Allowed("1", "approve", cerbos.MatchResourceKind("leave_request")))
+	require.False(t, m.Allowed("1", "view", cerbos.MatchResourceKind("expense")))
+	require.False(t, m.Allowed("1", "approve", cerbos.MatchResourceKind("expense")))
+
+	require.False(t, m.Allowed("2", "view"))
+	require.False(t, cerbos.Matrix{}.Allowed("1", "view"))
+}
+
+func TestCheckResourceActions(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			entry := req.Resources[0]
+			actions := make(map[string]effectv1.Effect, len(entry.Actions))
+			for _, a := range entry.Actions {
+				actions[a] = effectv1.Effect_EFFECT_DENY
+				if a == "view" {
+					actions[a] = effectv1.Effect_EFFECT_ALLOW
+				}
+			}
+
+			return &responsev1.CheckResourcesResponse{
+				RequestId: req.RequestId,
+				Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+					{
+						Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+						Actions:  actions,
+					},
+				},
+			}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	have, err := c.CheckResourceActions(context.Background(), principal, resource, "view", "approve")
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"view": true, "approve": false}, have)
+
+	_, err = c.CheckResourceActions(context.Background(), principal, resource)
+	require.Error(t, err)
+}
+
+func TestResponseValidation(t *testing.T) {
+	var omitResults atomic.Bool
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			if omitResults.Load() {
+				return &responsev1.CheckResourcesResponse{RequestId: req.RequestId}, nil
+			}
+
+			entry := req.Resources[0]
+			return &responsev1.CheckResourcesResponse{
+				RequestId: req.RequestId,
+				Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+					{
+						Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+						Actions:  map[string]effectv1.Effect{"view": effectv1.Effect_EFFECT_ALLOW},
+					},
+				},
+			}, nil
+		},
+		planResources: func(_ context.Context, req *requestv1.PlanResourcesRequest) (*responsev1.PlanResourcesResponse, error) {
+			return &responsev1.PlanResourcesResponse{
+				RequestId: req.RequestId,
+				Filter:    &enginev1.PlanResourcesFilter{Kind: enginev1.PlanResourcesFilter_KIND_CONDITIONAL},
+			}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithResponseValidation())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	allowed, err := c.IsAllowed(context.Background(), principal, resource, "view")
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	omitResults.Store(true)
+	_, err = c.IsAllowed(context.Background(), principal, resource, "view")
+	var rverr *cerbos.ResponseValidationError
+	require.ErrorAs(t, err, &rverr)
+	require.ErrorContains(t, rverr, "expected 1 results, got 0")
+
+	_, err = c.PlanResources(context.Background(), principal, cerbos.NewResource("leave_request", ""), "view")
+	require.ErrorAs(t, err, &rverr)
+	require.ErrorContains(t, rverr, "conditional filter has no condition")
+}
+
+func TestLogger(t *testing.T) {
+	var fail atomic.Bool
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			if fail.Load() {
+				return nil, status.Error(codes.Internal, "boom")
+			}
+
+			entry := req.Resources[0]
+			return &responsev1.CheckResourcesResponse{
+				RequestId: req.RequestId,
+				Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+					{
+						Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+						Actions:  map[string]effectv1.Effect{"view": effectv1.Effect_EFFECT_ALLOW},
+					},
+				},
+			}, nil
+		},
+	})
+
+	principal := cerbos.NewPrincipal("john", "employee").WithAttr("department", "marketing")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	t.Run("redacted", func(t *testing.T) {
+		logger := &kvLogger{}
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithLogger(logger), cerbos.WithMaxRetries(0))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.IsAllowed(context.Background(), principal, resource, "view")
+		require.NoError(t, err)
+
+		entries := logger.Entries()
+		require.Len(t, entries, 1)
+		require.Equal(t, "debug", entries[0].level)
+		require.Equal(t, "john", entries[0].kv["principal_id"])
+		require.Equal(t, "XX125", entries[0].kv["resource_id"])
+		require.Equal(t, map[string]string{"view": "EFFECT_ALLOW"}, entries[0].kv["effects"])
+		require.NotContains(t, entries[0].kv, "principal_attributes")
+
+		fail.Store(true)
+		t.Cleanup(func() { fail.Store(false) })
+
+		_, err = c.IsAllowed(context.Background(), principal, resource, "view")
+		require.Error(t, err)
+
+		entries = logger.Entries()
+		require.Len(t, entries, 2)
+		require.Equal(t, "error", entries[1].level)
+	})
+
+	t.Run("principal_attributes", func(t *testing.T) {
+		logger := &kvLogger{}
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithLogger(logger), cerbos.WithLoggedPrincipalAttributes())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.IsAllowed(context.Background(), principal, resource, "view")
+		require.NoError(t, err)
+
+		entries := logger.Entries()
+		require.Len(t, entries, 1)
+		require.Equal(t, map[string]any{"department": "marketing"}, entries[0].kv["principal_attributes"])
+	})
+}
+
+type kvLogEntry struct {
+	kv    map[string]any
+	level string
+	msg   string
+}
+
+type kvLogger struct {
+	entries []kvLogEntry
+	mu      sync.Mutex
+}
+
+func (l *kvLogger) Debug(msg string, keysAndValues ...any) { l.log("debug", msg, keysAndValues) }
+
+func (l *kvLogger) Warn(msg string, keysAndValues ...any) { l.log("warn", msg, keysAndValues) }
+
+func (l *kvLogger) Error(msg string, keysAndValues ...any) { l.log("error", msg, keysAndValues) }
+
+func (l *kvLogger) log(level, msg string, keysAndValues []any) {
+	kv := make(map[string]any, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		kv[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, kvLogEntry{level: level, msg: msg, kv: kv})
+}
+
+func (l *kvLogger) Entries() []kvLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]kvLogEntry(nil), l.entries...)
+}
+
+func TestCompatibilityCheck(t *testing.T) {
+	var infoCalls atomic.Int32
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			infoCalls.Add(1)
+			return &responsev1.ServerInfoResponse{Version: "0.28.0"}, nil
+		},
+	})
+
+	t.Run("declared_features", func(t *testing.T) {
+		logger := &kvLogger{}
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithCompatibilityCheck(logger, cerbos.ScopedPolicies, cerbos.ExportedVariables))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		// Policy outputs aren't supported by the server either, but they aren't reported because they aren't declared.
+		require.Eventually(t, func() bool { return len(logger.Entries()) > 0 }, 5*time.Second, 10*time.Millisecond)
+		entries := logger.Entries()
+		require.Len(t, entries, 1)
+		require.Equal(t, "warn", entries[0].level)
+		require.Equal(t, "exported variables", entries[0].kv["feature"])
+	})
+
+	t.Run("no_features", func(t *testing.T) {
+		infoCalls.Store(0)
+		logger := &kvLogger{}
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithCompatibilityCheck(logger))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		require.Never(t, func() bool { return infoCalls.Load() > 0 || len(logger.Entries()) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	})
+}
+
+func TestTracing(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			results := make([]*responsev1.CheckResourcesResponse_ResultEntry, len(req.Resources))
+			for i, entry := range req.Resources {
+				results[i] = &responsev1.CheckResourcesResponse_ResultEntry{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+					Actions:  map[string]effectv1.Effect{"view": effectv1.Effect_EFFECT_ALLOW, "approve": effectv1.Effect_EFFECT_DENY},
+				}
+			}
+
+			return &responsev1.CheckResourcesResponse{RequestId: req.RequestId, Results: results}, nil
+		},
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithTracing(tp))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	batch := cerbos.NewResourceBatch().
+		Add(cerbos.NewResource("leave_request", "XX125"), "view", "approve").
+		Add(cerbos.NewResource("leave_request", "XX225"), "view", "approve")
+	_, err = c.With(cerbos.RequestIDGenerator(func(context.Context) string { return "req-1" })).
+		CheckResources(ctx, cerbos.NewPrincipal("john", "employee"), batch)
+	require.NoError(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	span := spans[0]
+	require.Equal(t, "cerbos.CheckResources", span.Name())
+	require.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	require.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("cerbos.request_id", "req-1"),
+		attribute.Int("cerbos.resource_count", 2),
+		attribute.Int("cerbos.allow_count", 2),
+		attribute.Int("cerbos.deny_count", 2),
+	}, span.Attributes())
+}
+
+func TestCompareVersions(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			results := make([]*responsev1.CheckResourcesResponse_ResultEntry, len(req.Resources))
+			for i, entry := range req.Resources {
+				effect := effectv1.Effect_EFFECT_DENY
+				if entry.Resource.PolicyVersion == "v2" {
+					effect = effectv1.Effect_EFFECT_ALLOW
+				}
+
+				results[i] = &responsev1.CheckResourcesResponse_ResultEntry{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{
+						Id:            entry.Resource.Id,
+						Kind:          entry.Resource.Kind,
+						PolicyVersion: entry.Resource.PolicyVersion,
+					},
+					Actions: map[string]effectv1.Effect{entry.Actions[0]: effect},
+				}
+			}
+
+			return &responsev1.CheckResourcesResponse{RequestId: req.RequestId, Results: results}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125").WithPolicyVersion("v0")
+
+	have, err := c.CompareVersions(context.Background(), principal, resource, "approve", "default", "v2")
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"default": false, "v2": true}, have)
+	require.Equal(t, "v0", resource.Obj.PolicyVersion, "resource should not be modified")
+
+	_, err = c.CompareVersions(context.Background(), principal, resource, "approve")
+	require.Error(t, err)
+
+	_, err = c.CompareVersions(context.Background(), principal, resource, "approve", "")
+	require.Error(t, err)
+}
+
+func TestPlanResourcesDebug(t *testing.T) {
+	includeMeta := make(chan bool, 1)
+	addr := startFakeServer(t, &fakeServer{
+		planResources: func(_ context.Context, req *requestv1.PlanResourcesRequest) (*responsev1.PlanResourcesResponse, error) {
+			includeMeta <- req.IncludeMeta
+			return &responsev1.PlanResourcesResponse{
+				RequestId: req.RequestId,
+				Filter: &enginev1.PlanResourcesFilter{
+					Kind: enginev1.PlanResourcesFilter_KIND_CONDITIONAL,
+					Condition: &enginev1.PlanResourcesFilter_Expression_Operand{
+						Node: &enginev1.PlanResourcesFilter_Expression_Operand_Expression{
+							Expression: &enginev1.PlanResourcesFilter_Expression{
+								Operator: "and",
+								Operands: []*enginev1.PlanResourcesFilter_Expression_Operand{
+									{Node: &enginev1.PlanResourcesFilter_Expression_Operand_Variable{Variable: "request.resource.attr.owner"}},
+									{Node: &enginev1.PlanResourcesFilter_Expression_Operand_Variable{Variable: "request.resource.attr.department"}},
+									{Node: &enginev1.PlanResourcesFilter_Expression_Operand_Variable{Variable: "request.resource.attr.owner"}},
+								},
+							},
+						},
+					},
+				},
+				Meta: &responsev1.PlanResourcesResponse_Meta{
+					FilterDebug:  "(request.resource.attr.owner AND request.resource.attr.department)",
+					MatchedScope: "acme",
+				},
+			}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	resp, info, err := c.PlanResourcesDebug(context.Background(), cerbos.NewPrincipal("john", "employee"), cerbos.NewResource("leave_request", ""), "view")
+	require.NoError(t, err)
+	require.True(t, <-includeMeta)
+	require.True(t, resp.IsConditional())
+	require.Equal(t, &cerbos.PlanDebugInfo{
+		Filter:       "(request.resource.attr.owner AND request.resource.attr.department)",
+		MatchedScope: "acme",
+		Variables:    []string{"request.resource.attr.department", "request.resource.attr.owner"},
+		Kind:         enginev1.PlanResourcesFilter_KIND_CONDITIONAL,
+	}, info)
+	require.Nil(t, resp.Meta, "metadata should be stripped unless requested")
+
+	resp, info, err = c.With(cerbos.IncludeMeta(true)).PlanResourcesDebug(context.Background(), cerbos.NewPrincipal("john", "employee"), cerbos.NewResource("leave_request", ""), "view")
+	require.NoError(t, err)
+	require.True(t, <-includeMeta)
+	require.Equal(t, "acme", info.MatchedScope)
+	require.Equal(t, "acme", resp.Meta.GetMatchedScope())
+}
+
+func TestDetachedContext(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(ctx context.Context, _ *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			delay, err := time.ParseDuration(strings.Join(md.Get("x-delay"), ""))
+			if err != nil {
+				return nil, err
+			}
+
+			select {
+			case <-time.After(delay):
+				return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	t.Run("outlives_caller", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), "x-delay", "200ms"))
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		info, err := c.With(cerbos.DetachedContext(time.Second)).ServerInfo(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "0.36.0", info.Version)
+		require.Error(t, ctx.Err())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-delay", "1s")
+		_, err := c.With(cerbos.DetachedContext(50 * time.Millisecond)).ServerInfo(ctx)
+		require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+}
+
+func TestWaitForReady(t *testing.T) {
+	// Reserve an address for a server that is started after the first call is made.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	failFast, err := cerbos.New("passthrough:///"+addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = failFast.Close() })
+
+	_, err = failFast.ServerInfo(context.Background())
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	c, err := cerbos.New("passthrough:///"+addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0), cerbos.WithWaitForReady())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	s := grpc.NewServer()
+	svcv1.RegisterCerbosServiceServer(s, &fakeServer{
+		serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+		},
+	})
+	t.Cleanup(s.Stop)
+
+	time.AfterFunc(200*time.Millisecond, func() {
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		_ = s.Serve(lis)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	info, err := c.ServerInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0.36.0", info.Version)
+}
+
+func TestOnConnect(t *testing.T) {
+	t.Run("connected", func(t *testing.T) {
+		addr := startFakeServer(t, &fakeServer{
+			serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+				return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+			},
+		})
+
+		infos := make(chan *cerbos.ServerInfo, 1)
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithOnConnect(func(info *cerbos.ServerInfo) {
+			infos <- info
+		}))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		select {
+		case info := <-infos:
+			require.Equal(t, "0.36.0", info.Version)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "Callback was not called")
+		}
+	})
+
+	t.Run("close_before_connect", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := lis.Addr().String()
+		require.NoError(t, lis.Close())
+
+		c, err := cerbos.New("passthrough:///"+addr, cerbos.WithPlaintext(), cerbos.WithOnConnect(func(*cerbos.ServerInfo) {
+			require.Fail(t, "Callback should not be called")
+		}))
+		require.NoError(t, err)
+		require.NoError(t, c.Close())
+	})
+
+	t.Run("close_from_callback", func(t *testing.T) {
+		addr := startFakeServer(t, &fakeServer{
+			serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+				return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+			},
+		})
+
+		clients := make(chan *cerbos.GRPCClient, 1)
+		closed := make(chan error, 1)
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithOnConnect(func(*cerbos.ServerInfo) {
+			closed <- (<-clients).Close()
+		}))
+		require.NoError(t, err)
+		clients <- c
+
+		select {
+		case err := <-closed:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "Close called from the callback did not return")
+		}
+	})
+
+	t.Run("shutdown_while_fetching", func(t *testing.T) {
+		fetching := make(chan struct{}, 1)
+		addr := startFakeServer(t, &fakeServer{
+			serverInfo: func(ctx context.Context, _ *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+				fetching <- struct{}{}
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		})
+
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0), cerbos.WithOnConnect(func(*cerbos.ServerInfo) {}))
+		require.NoError(t, err)
+
+		select {
+		case <-fetching:
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "Server information was not requested")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		require.NoError(t, c.Shutdown(ctx))
+		require.Less(t, time.Since(start), time.Second, "Shutdown should not wait for the server information request")
+	})
+}
+
+func TestDefaultCallTimeout(t *testing.T) {
+	const delay = 200 * time.Millisecond
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(ctx context.Context, _ *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			select {
+			case <-time.After(delay):
+				return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0), cerbos.WithDefaultCallTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	t.Run("no_deadline", func(t *testing.T) {
+		_, err := c.ServerInfo(context.Background())
+		require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+
+	t.Run("explicit_deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		info, err := c.ServerInfo(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "0.36.0", info.Version)
+	})
+}
+
+func TestAdaptiveTimeout(t *testing.T) {
+	const slowDelay = 300 * time.Millisecond
+
+	var slow atomic.Bool
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(ctx context.Context, _ *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			if slow.Load() {
+				select {
+				case <-time.After(slowDelay):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+			return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0), cerbos.WithAdaptiveTimeout(0.9, 2, 50*time.Millisecond, 5*time.Second))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	// The ceiling applies until enough calls have been observed.
+	slow.Store(true)
+	_, err = c.ServerInfo(context.Background())
+	require.NoError(t, err)
+
+	slow.Store(false)
+	for i := 0; i < 64; i++ {
+		_, err := c.ServerInfo(context.Background())
+		require.NoError(t, err)
+	}
+
+	// Fast responses bring the timeout down to the floor, so a slow call is cut short.
+	slow.Store(true)
+	start := time.Now()
+	_, err = c.ServerInfo(context.Background())
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.Less(t, time.Since(start), slowDelay)
+
+	_, err = cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithAdaptiveTimeout(0.99, 2, time.Second, 50*time.Millisecond))
+	require.Error(t, err)
+}
+
+func TestMaxConnectionIdleTime(t *testing.T) {
+	conns := make(chan struct{}, 2)
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+		},
+	}, grpc.StatsHandler(connCounter(conns)))
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxConnectionIdleTime(100*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	_, err = c.ServerInfo(context.Background())
+	require.NoError(t, err)
+	<-conns
+
+	time.Sleep(300 * time.Millisecond)
+
+	_, err = c.ServerInfo(context.Background())
+	require.NoError(t, err)
+	require.Len(t, conns, 1, "Client should have reconnected after being idle")
+}
+
+func TestTokenSource(t *testing.T) {
+	certsDir := tests.PathToTestDataDir(t, "certs")
+	creds, err := credentials.NewServerTLSFromFile(filepath.Join(certsDir, "tls.crt"), filepath.Join(certsDir, "tls.key"))
+	require.NoError(t, err)
+
+	authHeaders := make(chan []string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(ctx context.Context, _ *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			authHeaders <- md.Get("authorization")
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	}, grpc.Creds(creds))
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	batch := cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view")
+	// The test certificate has expired, so verification is skipped. The token is still only sent over TLS.
+	tlsOpts := []cerbos.Opt{cerbos.WithTLSInsecure()}
+
+	t.Run("token_source", func(t *testing.T) {
+		var calls atomic.Int32
+		ts := tokenSourceFunc(func() (*oauth2.Token, error) {
+			calls.Add(1)
+			return &oauth2.Token{AccessToken: "token", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}, nil
+		})
+
+		c, err := cerbos.New(addr, append(tlsOpts, cerbos.WithTokenSource(ts))...)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		for i := 0; i < 2; i++ {
+			_, err = c.CheckResources(context.Background(), principal, batch)
+			require.NoError(t, err)
+			require.Equal(t, []string{"Bearer token"}, <-authHeaders)
+		}
+		require.Equal(t, int32(1), calls.Load(), "Token should be reused until it expires")
+	})
+
+	t.Run("bearer_token", func(t *testing.T) {
+		c, err := cerbos.New(addr, append(tlsOpts, cerbos.WithBearerToken("static"))...)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.CheckResources(context.Background(), principal, batch)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Bearer static"}, <-authHeaders)
+	})
+
+	t.Run("plaintext", func(t *testing.T) {
+		_, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithBearerToken("static"))
+		require.ErrorContains(t, err, "can't be used with WithPlaintext")
+
+		_, err = cerbos.NewGRPCWeb("http://127.0.0.1:1", cerbos.WithBearerToken("static"))
+		require.ErrorContains(t, err, "https URL")
+	})
+}
+
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+func TestBasicAuth(t *testing.T) {
+	authHeaders := make(chan []string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(ctx context.Context, _ *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			authHeaders <- md.Get("authorization")
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	batch := cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view")
+
+	netrcFile := filepath.Join(t.TempDir(), ".netrc")
+	require.NoError(t, os.WriteFile(netrcFile, []byte("machine 127.0.0.1 login netrcuser password netrcpass\nmachine sidecar login user password pass\n"), 0o600))
+	t.Setenv("NETRC", netrcFile)
+	t.Setenv("CERBOS_USERNAME", "")
+	t.Setenv("CERBOS_PASSWORD", "")
+
+	testCases := []struct {
+		name string
+		opts []cerbos.Opt
+		want []string
+	}{
+		{
+			name: "explicit",
+			opts: []cerbos.Opt{cerbos.WithBasicAuth("user", "pass")},
+			want: []string{"Basic dXNlcjpwYXNz"},
+		},
+		{
+			name: "netrc",
+			opts: []cerbos.Opt{cerbos.WithBasicAuthFromNetrc()},
+			want: []string{"Basic bmV0cmN1c2VyOm5ldHJjcGFzcw=="},
+		},
+		{
+			name: "netrc_machine",
+			opts: []cerbos.Opt{cerbos.WithBasicAuthFromNetrc(), cerbos.WithNetrcMachine("sidecar")},
+			want: []string{"Basic dXNlcjpwYXNz"},
+		},
+		{
+			name: "explicit_overrides_netrc",
+			opts: []cerbos.Opt{cerbos.WithBasicAuthFromNetrc(), cerbos.WithBasicAuth("user", "pass")},
+			want: []string{"Basic dXNlcjpwYXNz"},
+		},
+		{
+			name: "none",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := cerbos.New(addr, append(tc.opts, cerbos.WithPlaintext())...)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = c.Close() })
+
+			_, err = c.CheckResources(context.Background(), principal, batch)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, <-authHeaders)
+		})
+	}
+
+	t.Run("netrc_missing_entry", func(t *testing.T) {
+		_, err := cerbos.New("passthrough:///localhost:3593", cerbos.WithPlaintext(), cerbos.WithBasicAuthFromNetrc())
+		require.ErrorContains(t, err, "failed to load basic auth credentials")
+	})
+}
+
+func TestNewFromConn(t *testing.T) {
+	versions := make(chan string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			versions <- req.Resources[0].Resource.PolicyVersion
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var intercepted atomic.Int32
+	interceptor := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		intercepted.Add(1)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	c, err := cerbos.NewFromConn(conn, cerbos.WithPolicyVersion("shared"), cerbos.WithUnaryInterceptors(interceptor))
+	require.NoError(t, err)
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	batch := cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view")
+
+	_, err = c.CheckResources(context.Background(), principal, batch)
+	require.NoError(t, err)
+	require.Equal(t, "shared", <-versions)
+	require.Equal(t, int32(1), intercepted.Load())
+
+	require.NoError(t, c.Close())
+	require.NotEqual(t, connectivity.Shutdown, conn.GetState(), "Shared connection should not be closed by the client")
+
+	_, err = cerbos.NewFromConn(conn, cerbos.WithPlaintext(), cerbos.WithTLSInsecure())
+	require.ErrorContains(t, err, "WithPlaintext, WithTLSInsecure")
+
+	_, err = cerbos.NewFromConn(conn, cerbos.WithDisableServiceConfig())
+	require.ErrorContains(t, err, "WithDisableServiceConfig")
+
+	_, err = cerbos.NewFromConn(nil)
+	require.Error(t, err)
+}
+
+func TestConnectionPool(t *testing.T) {
+	conns := make(chan struct{}, 4)
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+		},
+	}, grpc.StatsHandler(connCounter(conns)))
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithConnectionPool(3))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	for i := 0; i < 6; i++ {
+		_, err = c.ServerInfo(context.Background())
+		require.NoError(t, err)
+	}
+	require.Len(t, conns, 3, "Calls should be spread across all the connections in the pool")
+
+	_, err = cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithConnectionPool(0))
+	require.ErrorContains(t, err, "connection pool size must be at least 1")
+}
+
+// connCounter is a server stats handler that reports each new connection.
+type connCounter chan<- struct{}
+
+func (connCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (connCounter) HandleRPC(context.Context, stats.RPCStats) {}
+
+func (connCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (c connCounter) HandleConn(_ context.Context, s stats.ConnStats) {
+	if _, ok := s.(*stats.ConnBegin); ok {
+		c <- struct{}{}
+	}
+}
+
+func TestLoadBalancingPolicy(t *testing.T) {
+	var calls [2]atomic.Int32
+	addrs := make([]resolver.Address, len(calls))
+	for i := range calls {
+		counter := &calls[i]
+		addr := startFakeServer(t, &fakeServer{
+			serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+				counter.Add(1)
+				return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+			},
+		})
+		addrs[i] = resolver.Address{Addr: strings.TrimPrefix(addr, "passthrough:///")}
+	}
+
+	r := manual.NewBuilderWithScheme("lbtest")
+	r.InitialState(resolver.State{Addresses: addrs})
+	resolver.Register(r)
+
+	t.Run("round_robin", func(t *testing.T) {
+		c, err := cerbos.New("lbtest:///cerbos", cerbos.WithPlaintext(), cerbos.WithLoadBalancingPolicy("round_robin"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		// The addresses become ready one by one, so keep calling until both have been used.
+		require.Eventually(t, func() bool {
+			_, err := c.ServerInfo(context.Background())
+			return err == nil && calls[0].Load() > 0 && calls[1].Load() > 0
+		}, 5*time.Second, 10*time.Millisecond, "Calls should be spread across all the addresses")
+	})
+
+	t.Run("invalid_service_config", func(t *testing.T) {
+		_, err := cerbos.New("lbtest:///cerbos", cerbos.WithPlaintext(), cerbos.WithServiceConfig(`{"loadBalancingConfig": 42}`))
+		require.Error(t, err)
+	})
+
+	t.Run("both_options", func(t *testing.T) {
+		_, err := cerbos.New("lbtest:///cerbos", cerbos.WithPlaintext(), cerbos.WithServiceConfig(`{}`), cerbos.WithLoadBalancingPolicy("round_robin"))
+		require.ErrorContains(t, err, "can't be used together")
+	})
+}
+
+func TestDisableServiceConfig(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+		},
+	})
+
+	// The resolver supplies a service config with a timeout that is too short for any call to succeed.
+	r := manual.NewBuilderWithScheme("sctest")
+	r.BuildCallback = func(_ resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) {
+		r.InitialState(resolver.State{
+			Addresses:     []resolver.Address{{Addr: strings.TrimPrefix(addr, "passthrough:///")}},
+			ServiceConfig: cc.ParseServiceConfig(`{"methodConfig": [{"name": [{"service": "cerbos.svc.v1.CerbosService"}], "timeout": "0.000000001s"}]}`),
+		})
+	}
+	resolver.Register(r)
+
+	t.Run("enabled", func(t *testing.T) {
+		c, err := cerbos.New("sctest:///cerbos", cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.ServerInfo(context.Background())
+		require.Equal(t, codes.DeadlineExceeded, status.Code(err), "Service config from the resolver should be used")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		c, err := cerbos.New("sctest:///cerbos", cerbos.WithPlaintext(), cerbos.WithMaxRetries(0), cerbos.WithDisableServiceConfig())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.ServerInfo(context.Background())
+		require.NoError(t, err, "Service config from the resolver should be ignored")
+	})
+}
+
+func TestTLSHandshakeTimeout(t *testing.T) {
+	const handshakeTimeout = 100 * time.Millisecond
+
+	// The listener accepts connections but never responds, so TLS handshakes never complete.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var (
+		conns []net.Conn
+		mu    sync.Mutex
+	)
+	t.Cleanup(func() {
+		_ = lis.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+
+	c, err := cerbos.New("passthrough:///"+lis.Addr().String(), cerbos.WithTLSInsecure(), cerbos.WithMaxRetries(0), cerbos.WithTLSHandshakeTimeout(handshakeTimeout))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err = c.ServerInfo(ctx)
+	require.Error(t, err)
+	require.ErrorContains(t, err, "deadline exceeded")
+	require.Less(t, time.Since(start), time.Second, "Call should fail once the handshake times out")
+	require.NoError(t, ctx.Err(), "Call should fail before its own deadline")
+}
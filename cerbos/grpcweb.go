@@ -0,0 +1,366 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+const (
+	grpcWebContentType     = "application/grpc-web+proto"
+	grpcWebTextContentType = "application/grpc-web-text+proto"
+
+	grpcWebFrameHeaderLen = 5
+	grpcWebTrailerFlag    = 0x80
+	grpcWebMaxMessageSize = 1024 * 1024 * 4 // 4MiB
+)
+
+var errGRPCWebStreamingUnsupported = errors.New("streaming calls are not supported by the gRPC-Web transport")
+
+// WithGRPCWebText configures clients created with NewGRPCWeb to use the base64-encoded text mode of gRPC-Web.
+// Some proxies and gateways only support the text mode. It has no effect on clients created with New.
+func WithGRPCWebText() Opt {
+	return func(c *config) {
+		c.grpcWebText = true
+	}
+}
+
+// NewGRPCWeb creates a new Cerbos client that talks to a gRPC-Web gateway (such as Envoy) at the given URL over HTTP/1.1.
+// The URL scheme determines whether TLS is used. TLS, user agent, retry, interceptor and request options
+// behave the same as for clients created using New. Stream interceptors and options specific to HTTP/2 connections are ignored.
+func NewGRPCWeb(baseURL string, opts ...Opt) (*GRPCClient, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", baseURL, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q: must be http or https", u.Scheme)
+	}
+
+	conf := mkConfig(baseURL, opts...)
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         (&net.Dialer{Timeout: conf.connectTimeout}).DialContext,
+		TLSHandshakeTimeout: conf.handshakeTimeout,
+	}
+
+	if conf.tokenSource != nil && u.Scheme != "https" {
+		return nil, errors.New("bearer token credentials can only be used with an https URL because tokens are only sent over TLS")
+	}
+
+	basicAuth, err := mkBasicAuthCredentials(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if basicAuth != nil && basicAuth.RequireTransportSecurity() && u.Scheme != "https" {
+		return nil, errors.New("basic auth credentials can only be used with an https URL unless WithPlaintext is used")
+	}
+
+	if u.Scheme == "https" {
+		tlsConf, err := mkTLSConfig(conf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create TLS config: %w", err)
+		}
+
+		if authority := conf.effectiveAuthority(); authority != "" {
+			tlsConf.ServerName = authority
+		}
+
+		transport.TLSClientConfig = tlsConf
+	}
+
+	interceptors, err := mkUnaryInterceptors(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &grpcWebConn{
+		httpClient:   &http.Client{Transport: transport},
+		baseURL:      strings.TrimSuffix(u.String(), "/"),
+		conf:         conf,
+		interceptors: interceptors,
+		basicAuth:    basicAuth,
+	}
+
+	client := newClient(conn, conf)
+	client.closeFn = func() error {
+		transport.CloseIdleConnections()
+		return nil
+	}
+
+	return client, nil
+}
+
+// grpcWebConn implements grpc.ClientConnInterface for unary calls using the gRPC-Web protocol.
+type grpcWebConn struct {
+	httpClient   *http.Client
+	conf         *config
+	baseURL      string
+	basicAuth    credentials.PerRPCCredentials
+	interceptors []grpc.UnaryClientInterceptor
+}
+
+func (wc *grpcWebConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return chainUnaryInterceptors(wc.interceptors, wc.invoke)(ctx, method, args, reply, nil, opts...)
+}
+
+func (*grpcWebConn) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Error(codes.Unimplemented, errGRPCWebStreamingUnsupported.Error())
+}
+
+func (wc *grpcWebConn) invoke(ctx context.Context, method string, args, reply any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+	reqMsg, ok := args.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "unexpected request type %T", args)
+	}
+
+	replyMsg, ok := reply.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "unexpected reply type %T", reply)
+	}
+
+	req, err := wc.mkRequest(ctx, method, reqMsg)
+	if err != nil {
+		return err
+	}
+
+	resp, err := wc.httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return status.FromContextError(ctxErr).Err()
+		}
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer resp.Body.Close()
+
+	return readGRPCWebResponse(resp, replyMsg)
+}
+
+func (wc *grpcWebConn) mkRequest(ctx context.Context, method string, msg proto.Message) (*http.Request, error) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal request: %v", err)
+	}
+
+	frame := make([]byte, grpcWebFrameHeaderLen+len(payload))
+	binary.BigEndian.PutUint32(frame[1:grpcWebFrameHeaderLen], uint32(len(payload)))
+	copy(frame[grpcWebFrameHeaderLen:], payload)
+
+	contentType := grpcWebContentType
+	if wc.conf.grpcWebText {
+		contentType = grpcWebTextContentType
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wc.baseURL+method, bytes.NewReader(frame))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create request: %v", err)
+	}
+
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		for k, vs := range md {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+
+	if wc.conf.playgroundInstance != "" {
+		req.Header.Set(internal.PlaygroundInstanceHeader, wc.conf.playgroundInstance)
+	}
+
+	if wc.basicAuth != nil {
+		authMD, err := wc.basicAuth.GetRequestMetadata(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "failed to obtain credentials: %v", err)
+		}
+
+		for k, v := range authMD {
+			req.Header.Set(k, v)
+		}
+	}
+
+	if wc.conf.tokenSource != nil {
+		token, err := wc.conf.tokenSource.Token()
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "failed to obtain token: %v", err)
+		}
+		token.SetAuthHeader(req)
+	}
+
+	if authority := wc.conf.effectiveAuthority(); authority != "" {
+		req.Host = authority
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return nil, status.Error(codes.DeadlineExceeded, context.DeadlineExceeded.Error())
+		}
+		req.Header.Set("grpc-timeout", strconv.FormatInt(timeout.Milliseconds()+1, 10)+"m")
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+	req.Header.Set("X-Grpc-Web", "1")
+	req.Header.Set("X-User-Agent", wc.conf.userAgent)
+
+	return req, nil
+}
+
+func readGRPCWebResponse(resp *http.Response, reply proto.Message) error {
+	// Trailers-only responses carry the status in the headers.
+	trailers := textproto.MIMEHeader(resp.Header)
+
+	if resp.StatusCode != http.StatusOK && trailers.Get("grpc-status") == "" {
+		return status.Errorf(httpStatusToCode(resp.StatusCode), "unexpected HTTP status: %s", resp.Status)
+	}
+
+	var body io.Reader = io.LimitReader(resp.Body, grpcWebMaxMessageSize)
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), grpcWebTextContentType) {
+		body = newGRPCWebTextDecoder(body)
+	}
+
+	var gotMessage bool
+	br := bufio.NewReader(body)
+	header := make([]byte, grpcWebFrameHeaderLen)
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return status.Errorf(codes.Internal, "failed to read response frame: %v", err)
+		}
+
+		frameLen := binary.BigEndian.Uint32(header[1:])
+		if frameLen > grpcWebMaxMessageSize {
+			return status.Errorf(codes.ResourceExhausted, "response frame of %d bytes exceeds the maximum size", frameLen)
+		}
+
+		frame := make([]byte, frameLen)
+		if _, err := io.ReadFull(br, frame); err != nil {
+			return status.Errorf(codes.Internal, "failed to read response frame: %v", err)
+		}
+
+		if header[0]&grpcWebTrailerFlag != 0 {
+			t, err := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(frame), strings.NewReader("\r\n")))).ReadMIMEHeader()
+			if err != nil && !errors.Is(err, io.EOF) {
+				return status.Errorf(codes.Internal, "failed to parse trailers: %v", err)
+			}
+			trailers = t
+			break
+		}
+
+		if err := proto.Unmarshal(frame, reply); err != nil {
+			return status.Errorf(codes.Internal, "failed to unmarshal response: %v", err)
+		}
+		gotMessage = true
+	}
+
+	code := codes.Unknown
+	if s := trailers.Get("grpc-status"); s != "" {
+		c, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return status.Errorf(codes.Internal, "invalid grpc-status %q", s)
+		}
+		code = codes.Code(c)
+	} else if gotMessage {
+		return status.Error(codes.Internal, "response is missing grpc-status trailer")
+	}
+
+	if code != codes.OK {
+		msg, err := url.PathUnescape(trailers.Get("grpc-message"))
+		if err != nil {
+			msg = trailers.Get("grpc-message")
+		}
+		return status.Error(code, msg)
+	}
+
+	return nil
+}
+
+// grpcWebTextDecoder decodes a gRPC-Web text body, which may consist of several concatenated base64 segments each with its own padding.
+type grpcWebTextDecoder struct {
+	src     *bufio.Reader
+	pending []byte
+}
+
+func newGRPCWebTextDecoder(src io.Reader) *grpcWebTextDecoder {
+	return &grpcWebTextDecoder{src: bufio.NewReader(src)}
+}
+
+func (d *grpcWebTextDecoder) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		// Every quantum of four base64 characters can be decoded independently, regardless of padding.
+		quantum := make([]byte, 0, 4) //nolint:mnd
+		for len(quantum) < 4 {
+			b, err := d.src.ReadByte()
+			if err != nil {
+				if errors.Is(err, io.EOF) && len(quantum) > 0 {
+					return 0, io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+
+			if b == '\r' || b == '\n' || b == ' ' {
+				continue
+			}
+			quantum = append(quantum, b)
+		}
+
+		decoded := make([]byte, 3) //nolint:mnd
+		n, err := base64.StdEncoding.Decode(decoded, quantum)
+		if err != nil {
+			return 0, err
+		}
+		d.pending = decoded[:n]
+	}
+
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// httpStatusToCode maps HTTP status codes to gRPC codes as described in https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md.
+func httpStatusToCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.Internal
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
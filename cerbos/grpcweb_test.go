@@ -0,0 +1,143 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+// grpcWebGateway is a minimal gRPC-Web endpoint for the CheckResources method.
+func grpcWebGateway(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cerbos.svc.v1.CerbosService/CheckResources" {
+			http.NotFound(w, r)
+			return
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		textMode := strings.HasPrefix(contentType, "application/grpc-web-text")
+
+		var body io.Reader = r.Body
+		if textMode {
+			body = base64.NewDecoder(base64.StdEncoding, r.Body)
+		}
+
+		frame, err := io.ReadAll(body)
+		if err != nil || len(frame) < 5 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		req := &requestv1.CheckResourcesRequest{}
+		if err := proto.Unmarshal(frame[5:], req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+
+		if req.Principal.Id == "unauthenticated" {
+			// Trailers-only response.
+			w.Header().Set("grpc-status", fmt.Sprint(int(codes.Unauthenticated)))
+			w.Header().Set("grpc-message", "invalid%20token")
+			return
+		}
+
+		resp := &responsev1.CheckResourcesResponse{RequestId: req.RequestId}
+		for _, r := range req.Resources {
+			actions := make(map[string]effectv1.Effect, len(r.Actions))
+			for _, a := range r.Actions {
+				actions[a] = effectv1.Effect_EFFECT_ALLOW
+			}
+			resp.Results = append(resp.Results, &responsev1.CheckResourcesResponse_ResultEntry{
+				Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: r.Resource.Id, Kind: r.Resource.Kind},
+				Actions:  actions,
+			})
+		}
+
+		payload, err := proto.Marshal(resp)
+		require.NoError(t, err)
+
+		writeFrame := func(flag byte, data []byte) {
+			buf := make([]byte, 5+len(data))
+			buf[0] = flag
+			binary.BigEndian.PutUint32(buf[1:5], uint32(len(data)))
+			copy(buf[5:], data)
+			if textMode {
+				// Each frame is encoded separately, which results in a body with multiple padded segments.
+				buf = []byte(base64.StdEncoding.EncodeToString(buf))
+			}
+			_, _ = w.Write(buf)
+		}
+
+		writeFrame(0, payload)
+		writeFrame(0x80, []byte("grpc-status: 0\r\ngrpc-message: \r\n"))
+	}))
+}
+
+func TestGRPCWeb(t *testing.T) {
+	srv := grpcWebGateway(t)
+	t.Cleanup(srv.Close)
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	testCases := []struct {
+		name string
+		opts []cerbos.Opt
+	}{
+		{name: "binary"},
+		{name: "text", opts: []cerbos.Opt{cerbos.WithGRPCWebText()}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := cerbos.NewGRPCWeb(srv.URL, tc.opts...)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = c.Close() })
+
+			allowed, err := c.IsAllowed(context.Background(), principal, resource, "view")
+			require.NoError(t, err)
+			require.True(t, allowed)
+
+			resp, err := c.CheckResources(context.Background(), principal, cerbos.NewResourceBatch().Add(resource, "view", "approve"))
+			require.NoError(t, err)
+			require.True(t, resp.GetResource("XX125").IsAllowed("approve"))
+
+			_, err = c.IsAllowed(context.Background(), cerbos.NewPrincipal("unauthenticated", "employee"), resource, "view")
+			require.True(t, cerbos.IsUnauthenticated(err))
+			require.ErrorContains(t, err, "invalid token")
+
+			_, err = c.PlanResources(context.Background(), principal, resource, "view")
+			require.Equal(t, codes.Unimplemented, status.Code(err))
+		})
+	}
+
+	t.Run("invalid_url", func(t *testing.T) {
+		_, err := cerbos.NewGRPCWeb("grpc://localhost:3593")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	svcv1 "github.com/cerbos/cerbos/api/genpb/cerbos/svc/v1"
+)
+
+const healthPollInterval = 250 * time.Millisecond
+
+// HealthStatus is the serving status reported by the gRPC health service of the server.
+type HealthStatus int
+
+const (
+	// HealthStatusUnknown means that the server reported an unknown status.
+	HealthStatusUnknown HealthStatus = iota
+	// HealthStatusServing means that the server is ready to handle requests.
+	HealthStatusServing
+	// HealthStatusNotServing means that the server is up but not ready to handle requests, for example while it's shutting down.
+	HealthStatusNotServing
+	// HealthStatusServiceUnknown means that the server doesn't know about the requested service.
+	HealthStatusServiceUnknown
+)
+
+func (hs HealthStatus) String() string {
+	switch hs {
+	case HealthStatusServing:
+		return "SERVING"
+	case HealthStatusNotServing:
+		return "NOT_SERVING"
+	case HealthStatusServiceUnknown:
+		return "SERVICE_UNKNOWN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// HealthCheck checks the status of the given service using the standard gRPC health checking protocol.
+// Use an empty service name to check the overall health of the server, or svcv1.CerbosService_ServiceDesc.ServiceName
+// to check the Cerbos decision service. A server that doesn't know about the service reports HealthStatusServiceUnknown.
+func (c *GRPCClient) HealthCheck(ctx context.Context, service string) (HealthStatus, error) {
+	done, err := c.lifecycle.begin()
+	if err != nil {
+		return HealthStatusUnknown, err
+	}
+	defer done()
+
+	callCtx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	resp, err := c.health.Check(callCtx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return HealthStatusServiceUnknown, nil
+		}
+		return HealthStatusUnknown, c.classifyErr(err)
+	}
+
+	switch resp.GetStatus() {
+	case healthpb.HealthCheckResponse_SERVING:
+		return HealthStatusServing, nil
+	case healthpb.HealthCheckResponse_NOT_SERVING:
+		return HealthStatusNotServing, nil
+	case healthpb.HealthCheckResponse_SERVICE_UNKNOWN:
+		return HealthStatusServiceUnknown, nil
+	default:
+		return HealthStatusUnknown, nil
+	}
+}
+
+// WaitForReady blocks until the server reports that the Cerbos decision service is serving, checking its health periodically.
+// It's intended for startup probes that must wait for the server before sending real traffic. Errors such as the server being
+// unreachable are treated as the server not being ready yet. It returns an error if the context is cancelled first or if the
+// server doesn't implement the gRPC health service.
+func (c *GRPCClient) WaitForReady(ctx context.Context) error {
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+
+	for {
+		hs, err := c.HealthCheck(ctx, svcv1.CerbosService_ServiceDesc.ServiceName)
+		if errors.Is(err, ErrClientShutdown) {
+			return err
+		}
+
+		if err != nil && status.Code(err) == codes.Unimplemented {
+			return fmt.Errorf("server does not support health checks: %w", err)
+		}
+
+		if err == nil && hs == HealthStatusServing {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("server not ready (last health check failed: %v): %w", err, ctx.Err())
+			}
+			return fmt.Errorf("server not ready (status %s): %w", hs, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	svcv1 "github.com/cerbos/cerbos/api/genpb/cerbos/svc/v1"
+)
+
+func TestHealthCheck(t *testing.T) {
+	serviceName := svcv1.CerbosService_ServiceDesc.ServiceName
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	healthSrv := health.NewServer()
+	healthSrv.SetServingStatus(serviceName, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, healthSrv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	c, err := cerbos.New("passthrough:///"+lis.Addr().String(), cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	hs, err := c.HealthCheck(context.Background(), serviceName)
+	require.NoError(t, err)
+	require.Equal(t, cerbos.HealthStatusNotServing, hs)
+
+	hs, err = c.HealthCheck(context.Background(), "unknown.Service")
+	require.NoError(t, err)
+	require.Equal(t, cerbos.HealthStatusServiceUnknown, hs)
+
+	t.Run("wait_for_ready", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, c.WaitForReady(ctx), context.DeadlineExceeded)
+
+		time.AfterFunc(300*time.Millisecond, func() {
+			healthSrv.SetServingStatus(serviceName, healthpb.HealthCheckResponse_SERVING)
+		})
+
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, c.WaitForReady(ctx))
+
+		hs, err := c.HealthCheck(context.Background(), serviceName)
+		require.NoError(t, err)
+		require.Equal(t, cerbos.HealthStatusServing, hs)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := lis.Addr().String()
+		require.NoError(t, lis.Close())
+
+		c, err := cerbos.New("passthrough:///"+addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+
+		err = c.WaitForReady(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.ErrorContains(t, err, "last health check failed")
+	})
+
+	t.Run("unimplemented", func(t *testing.T) {
+		c, err := cerbos.New(startFakeServer(t, &fakeServer{}), cerbos.WithPlaintext())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.ErrorContains(t, c.WaitForReady(ctx), "server does not support health checks")
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MethodFilterInterceptor wraps the given interceptor so that it is only invoked for the listed gRPC methods.
+// Methods can be given either as full method names (e.g. "/cerbos.svc.v1.CerbosService/PlanResources") or
+// as bare method names (e.g. "PlanResources"). Calls to other methods bypass the wrapped interceptor.
+func MethodFilterInterceptor(methods []string, inner grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	filter := make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		filter[m] = struct{}{}
+	}
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		_, full := filter[method]
+		_, short := filter[path.Base(method)]
+		if !full && !short {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		return inner(ctx, method, req, reply, cc, invoker, opts...)
+	}
+}
+
+func credentialRefreshInterceptor(refresh func(context.Context) error) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if status.Code(err) != codes.Unauthenticated {
+			return err
+		}
+
+		if rerr := refresh(ctx); rerr != nil {
+			return fmt.Errorf("%w (failed to refresh credentials: %w)", err, rerr)
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func panicRecoveryInterceptor(handler func(any)) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				handler(r)
+				err = status.Errorf(codes.Internal, "recovered from panic in call to %s: %v", method, r)
+			}
+		}()
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func panicRecoveryStreamInterceptor(handler func(any)) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (stream grpc.ClientStream, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				handler(r)
+				stream = nil
+				err = status.Errorf(codes.Internal, "recovered from panic in call to %s: %v", method, r)
+			}
+		}()
+
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
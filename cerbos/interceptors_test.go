@@ -0,0 +1,133 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+func TestMethodFilterInterceptor(t *testing.T) {
+	var intercepted []string
+	inner := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		intercepted = append(intercepted, method)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	invoked := 0
+	invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		invoked++
+		return nil
+	}
+
+	interceptor := cerbos.MethodFilterInterceptor([]string{"PlanResources", "/cerbos.svc.v1.CerbosService/ServerInfo"}, inner)
+	for _, method := range []string{
+		"/cerbos.svc.v1.CerbosService/PlanResources",
+		"/cerbos.svc.v1.CerbosService/CheckResources",
+		"/cerbos.svc.v1.CerbosService/ServerInfo",
+	} {
+		require.NoError(t, interceptor(context.Background(), method, nil, nil, nil, invoker))
+	}
+
+	require.Equal(t, 3, invoked)
+	require.Equal(t, []string{"/cerbos.svc.v1.CerbosService/PlanResources", "/cerbos.svc.v1.CerbosService/ServerInfo"}, intercepted)
+}
+
+func TestCredentialRefresh(t *testing.T) {
+	var token atomic.Value
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(ctx context.Context, _ *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			if got := md.Get("authorization"); len(got) != 1 || got[0] != "fresh" {
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	attachToken := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, "authorization", token.Load().(string)), method, req, reply, cc, opts...) //nolint:forcetypeassert
+	}
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	t.Run("refresh_succeeds", func(t *testing.T) {
+		token.Store("stale")
+		refreshes := 0
+		c, err := cerbos.New(addr,
+			cerbos.WithPlaintext(),
+			cerbos.WithUnaryInterceptors(attachToken),
+			cerbos.WithCredentialRefresh(func(context.Context) error {
+				refreshes++
+				token.Store("fresh")
+				return nil
+			}),
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.CheckResources(context.Background(), principal, cerbos.NewResourceBatch().Add(resource, "view"))
+		require.NoError(t, err)
+		require.Equal(t, 1, refreshes)
+	})
+
+	t.Run("refresh_does_not_help", func(t *testing.T) {
+		token.Store("stale")
+		refreshes := 0
+		c, err := cerbos.New(addr,
+			cerbos.WithPlaintext(),
+			cerbos.WithUnaryInterceptors(attachToken),
+			cerbos.WithCredentialRefresh(func(context.Context) error {
+				refreshes++
+				return nil
+			}),
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.IsAllowed(context.Background(), principal, resource, "view")
+		require.True(t, cerbos.IsUnauthenticated(err))
+		require.Equal(t, 1, refreshes, "Refresh should only be attempted once per call")
+	})
+}
+
+func TestPanicRecovery(t *testing.T) {
+	addr := startFakeServer(t, &fakeServer{})
+
+	var recovered any
+	panicky := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get("x-panic")) > 0 {
+			panic("boom")
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithUnaryInterceptors(panicky), cerbos.WithPanicRecovery(func(r any) {
+		recovered = r
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	_, err = c.With(cerbos.Headers("x-panic", "true")).ServerInfo(context.Background())
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.ErrorContains(t, err, "recovered from panic in call to /cerbos.svc.v1.CerbosService/ServerInfo: boom")
+	require.Equal(t, "boom", recovered)
+
+	_, err = c.ServerInfo(context.Background())
+	require.NoError(t, err)
+}
@@ -0,0 +1,74 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+// ErrClientShutdown is returned by calls made after the client has started shutting down.
+var ErrClientShutdown = errors.New("client is shut down")
+
+// lifecycle tracks in-flight calls so that they can be drained before the connection is closed.
+type lifecycle struct {
+	inFlight sync.WaitGroup
+	mu       sync.RWMutex
+	closed   bool
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{}
+}
+
+// begin registers the start of a call. The returned function must be called when the call completes.
+func (l *lifecycle) begin() (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		return nil, ErrClientShutdown
+	}
+
+	l.inFlight.Add(1)
+	return l.inFlight.Done, nil
+}
+
+// drain stops new calls from starting and waits for in-flight calls to complete or the context to be done.
+func (l *lifecycle) drain(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown gracefully shuts down the client. New calls are rejected with ErrClientShutdown while calls that are already in progress
+// are allowed to complete. The connection is closed once all in-flight calls have completed or the context is done, whichever happens first.
+// Clients derived from this client using With are shut down as well.
+func (c *GRPCClient) Shutdown(ctx context.Context) error {
+	return multierr.Append(c.lifecycle.drain(ctx), c.Close())
+}
@@ -0,0 +1,80 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+func TestShutdown(t *testing.T) {
+	received := make(chan struct{})
+	release := make(chan struct{})
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(ctx context.Context, _ *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			received <- struct{}{}
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	batch := cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view")
+
+	t.Run("drains_in_flight_calls", func(t *testing.T) {
+		c, err := cerbos.New(addr, cerbos.WithPlaintext())
+		require.NoError(t, err)
+
+		callErr := make(chan error, 1)
+		go func() {
+			_, err := c.CheckResources(context.Background(), principal, batch)
+			callErr <- err
+		}()
+		<-received
+
+		shutdownErr := make(chan error, 1)
+		go func() { shutdownErr <- c.Shutdown(context.Background()) }()
+
+		require.Eventually(t, func() bool {
+			_, err := c.With(cerbos.IncludeMeta(true)).ServerInfo(context.Background())
+			return errors.Is(err, cerbos.ErrClientShutdown)
+		}, 5*time.Second, 10*time.Millisecond, "New calls should be rejected during shutdown")
+
+		select {
+		case <-shutdownErr:
+			require.FailNow(t, "Shutdown returned before in-flight call completed")
+		default:
+		}
+
+		close(release)
+		require.NoError(t, <-callErr)
+		require.NoError(t, <-shutdownErr)
+	})
+
+	t.Run("context_expires", func(t *testing.T) {
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+		require.NoError(t, err)
+
+		release = make(chan struct{})
+		go func() { _, _ = c.CheckResources(context.Background(), principal, batch) }()
+		<-received
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, c.Shutdown(ctx), context.DeadlineExceeded)
+	})
+}
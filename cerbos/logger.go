@@ -0,0 +1,13 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+// Logger is the interface used by the client to emit diagnostic messages.
+// Arguments following the message are alternating key-value pairs.
+// The method set is compatible with *slog.Logger from the standard library.
+type Logger interface {
+	Debug(msg string, keysAndValues ...any)
+	Warn(msg string, keysAndValues ...any)
+	Error(msg string, keysAndValues ...any)
+}
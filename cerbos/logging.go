@@ -0,0 +1,90 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+// WithLogger configures the client to log a summary of every CheckResources and PlanResources call at debug level and
+// every failed call at error level. Summaries include the principal, the resources, the actions, the outcomes and the
+// latency of the call. Principal attributes are left out unless WithLoggedPrincipalAttributes is also used.
+func WithLogger(logger Logger) Opt {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithLoggedPrincipalAttributes includes the principal attributes in the summaries logged by clients configured with WithLogger.
+// Attributes often contain personal data, so make sure that the logs are handled accordingly.
+func WithLoggedPrincipalAttributes() Opt {
+	return func(c *config) {
+		c.logPrincipalAttrs = true
+	}
+}
+
+func loggingInterceptor(logger Logger, includePrincipalAttrs bool) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		latency := time.Since(start)
+
+		if err != nil {
+			logger.Error("Cerbos call failed", "method", method, "latency", latency, "error", err)
+			return err
+		}
+
+		switch r := req.(type) {
+		case *requestv1.CheckResourcesRequest:
+			resp, _ := reply.(*responsev1.CheckResourcesResponse)
+			for _, result := range resp.GetResults() {
+				effects := make(map[string]string, len(result.Actions))
+				for action, effect := range result.Actions {
+					effects[action] = effect.String()
+				}
+
+				logger.Debug("Cerbos decision", append(principalKeysAndValues(r.Principal, includePrincipalAttrs),
+					"request_id", r.RequestId,
+					"resource_kind", result.GetResource().GetKind(),
+					"resource_id", result.GetResource().GetId(),
+					"policy_version", result.GetResource().GetPolicyVersion(),
+					"effects", effects,
+					"latency", latency,
+				)...)
+			}
+		case *requestv1.PlanResourcesRequest:
+			resp, _ := reply.(*responsev1.PlanResourcesResponse)
+			logger.Debug("Cerbos query plan", append(principalKeysAndValues(r.Principal, includePrincipalAttrs),
+				"request_id", r.RequestId,
+				"resource_kind", r.GetResource().GetKind(),
+				"action", resp.GetAction(),
+				"filter_kind", resp.GetFilter().GetKind().String(),
+				"latency", latency,
+			)...)
+		}
+
+		return nil
+	}
+}
+
+func principalKeysAndValues(principal *enginev1.Principal, includeAttrs bool) []any {
+	kv := []any{"principal_id", principal.GetId(), "principal_roles", principal.GetRoles()}
+	if !includeAttrs {
+		return kv
+	}
+
+	attrs := make(map[string]any, len(principal.GetAttr()))
+	for k, v := range principal.GetAttr() {
+		attrs[k] = v.AsInterface()
+	}
+
+	return append(kv, "principal_attributes", attrs)
+}
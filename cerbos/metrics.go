@@ -0,0 +1,123 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+// WithMetrics registers Prometheus metrics for the calls made by the client with the given registerer:
+//   - cerbos_check_total: counter of the actions checked, labelled by effect.
+//   - cerbos_request_duration_seconds: histogram of the duration of calls including retries, labelled by method and status code.
+//   - cerbos_retries_total: counter of the retries made, labelled by method.
+//
+// Clients configured with the same registerer share the metrics.
+func WithMetrics(reg prometheus.Registerer) Opt {
+	return func(c *config) {
+		c.metricsRegisterer = reg
+	}
+}
+
+type clientMetrics struct {
+	checks   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	retries  *prometheus.CounterVec
+}
+
+func newClientMetrics(reg prometheus.Registerer) (*clientMetrics, error) {
+	m := &clientMetrics{
+		checks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cerbos_check_total",
+			Help: "Number of actions checked, by effect.",
+		}, []string{"effect"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cerbos_request_duration_seconds",
+			Help:    "Duration of calls to the Cerbos server, including retries.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cerbos_retries_total",
+			Help: "Number of retries of calls to the Cerbos server.",
+		}, []string{"method"}),
+	}
+
+	var err error
+	if m.checks, err = register(reg, m.checks); err != nil {
+		return nil, err
+	}
+
+	if m.duration, err = register(reg, m.duration); err != nil {
+		return nil, err
+	}
+
+	if m.retries, err = register(reg, m.retries); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// register registers the collector, or returns the collector registered previously if there's one.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing, nil
+			}
+		}
+
+		return c, fmt.Errorf("failed to register metrics: %w", err)
+	}
+
+	return c, nil
+}
+
+type attemptsKey struct{}
+
+// interceptors returns the interceptors that record the metrics. The first interceptor must be placed outside
+// and the second inside the retry interceptor.
+func (m *clientMetrics) interceptors() []grpc.UnaryClientInterceptor {
+	return []grpc.UnaryClientInterceptor{
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			attempts := new(atomic.Int32)
+			start := time.Now()
+			err := invoker(context.WithValue(ctx, attemptsKey{}, attempts), method, req, reply, cc, opts...)
+
+			name := path.Base(method)
+			m.duration.WithLabelValues(name, status.Code(err).String()).Observe(time.Since(start).Seconds())
+			if n := attempts.Load(); n > 1 {
+				m.retries.WithLabelValues(name).Add(float64(n - 1))
+			}
+
+			if resp, ok := reply.(*responsev1.CheckResourcesResponse); ok && err == nil {
+				for _, result := range resp.Results {
+					for _, effect := range result.Actions {
+						m.checks.WithLabelValues(effect.String()).Inc()
+					}
+				}
+			}
+
+			return err
+		},
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if attempts, ok := ctx.Value(attemptsKey{}).(*atomic.Int32); ok {
+				attempts.Add(1)
+			}
+
+			return invoker(ctx, method, req, reply, cc, opts...)
+		},
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+func TestMetrics(t *testing.T) {
+	var calls atomic.Int32
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			if calls.Add(1) == 1 {
+				return nil, status.Error(codes.Unavailable, "boom")
+			}
+
+			entry := req.Resources[0]
+			return &responsev1.CheckResourcesResponse{
+				RequestId: req.RequestId,
+				Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+					{
+						Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+						Actions:  map[string]effectv1.Effect{"view": effectv1.Effect_EFFECT_ALLOW, "approve": effectv1.Effect_EFFECT_DENY},
+					},
+				},
+			}, nil
+		},
+	})
+
+	reg := prometheus.NewRegistry()
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMetrics(reg))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	// A second client with the same registry shares the metrics.
+	other, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMetrics(reg))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = other.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	batch := cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view", "approve")
+
+	_, err = c.CheckResources(context.Background(), principal, batch)
+	require.NoError(t, err)
+	_, err = other.CheckResources(context.Background(), principal, batch)
+	require.NoError(t, err)
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	counts := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			switch f.GetName() {
+			case "cerbos_check_total":
+				counts[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+			case "cerbos_retries_total":
+				counts["retries"] = m.GetCounter().GetValue()
+			case "cerbos_request_duration_seconds":
+				counts["requests"] += float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	require.Equal(t, map[string]float64{"EFFECT_ALLOW": 2, "EFFECT_DENY": 2, "retries": 1, "requests": 2}, counts)
+	require.Equal(t, 1, promtestutil.CollectAndCount(reg, "cerbos_request_duration_seconds"))
+}
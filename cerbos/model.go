@@ -4,11 +4,14 @@
 package cerbos
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -21,6 +24,7 @@ import (
 	schemav1 "github.com/cerbos/cerbos/api/genpb/cerbos/schema/v1"
 	"go.uber.org/multierr"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/cerbos/cerbos-sdk-go/internal"
@@ -28,10 +32,34 @@ import (
 
 const apiVersion = "api.cerbos.dev/v1"
 
+// TimeEncoding determines how time.Time values are represented when they are used as attribute values.
+type TimeEncoding int
+
+const (
+	// TimeEncodingRFC3339 encodes times as RFC3339 formatted strings. This is the default.
+	TimeEncodingRFC3339 TimeEncoding = iota
+	// TimeEncodingUnixSeconds encodes times as the number of seconds elapsed since the Unix epoch.
+	TimeEncodingUnixSeconds
+	// TimeEncodingUnixMillis encodes times as the number of milliseconds elapsed since the Unix epoch.
+	TimeEncodingUnixMillis
+)
+
+func (te TimeEncoding) encoder() func(time.Time) any {
+	switch te {
+	case TimeEncodingUnixSeconds:
+		return func(t time.Time) any { return t.Unix() }
+	case TimeEncodingUnixMillis:
+		return func(t time.Time) any { return t.UnixMilli() }
+	default:
+		return nil
+	}
+}
+
 // Principal is a container for principal data.
 type Principal struct {
-	Obj *enginev1.Principal
-	err error
+	Obj          *enginev1.Principal
+	err          error
+	timeEncoding TimeEncoding
 }
 
 // NewPrincipal creates a new principal object with the given ID and roles.
@@ -62,6 +90,12 @@ func (p *Principal) WithScope(scope string) *Principal {
 	return p
 }
 
+// WithTimeAttrEncoding sets how time.Time values in attributes added after this call are encoded.
+func (p *Principal) WithTimeAttrEncoding(encoding TimeEncoding) *Principal {
+	p.timeEncoding = encoding
+	return p
+}
+
 // WithAttributes merges the given attributes to principal's existing attributes.
 func (p *Principal) WithAttributes(attr map[string]any) *Principal {
 	if p.Obj.Attr == nil {
@@ -69,7 +103,7 @@ func (p *Principal) WithAttributes(attr map[string]any) *Principal {
 	}
 
 	for k, v := range attr {
-		pbVal, err := internal.ToStructPB(v)
+		pbVal, err := internal.ToStructPBWithTimeEncoder(v, p.timeEncoding.encoder())
 		if err != nil {
 			p.err = multierr.Append(p.err, fmt.Errorf("invalid attribute value for '%s': %w", k, err))
 			continue
@@ -87,7 +121,7 @@ func (p *Principal) WithAttr(key string, value any) *Principal {
 		p.Obj.Attr = make(map[string]*structpb.Value)
 	}
 
-	pbVal, err := internal.ToStructPB(value)
+	pbVal, err := internal.ToStructPBWithTimeEncoder(value, p.timeEncoding.encoder())
 	if err != nil {
 		p.err = multierr.Append(p.err, fmt.Errorf("invalid attribute value for '%s': %w", key, err))
 		return p
@@ -107,6 +141,26 @@ func (p *Principal) Roles() []string {
 	return p.Obj.GetRoles()
 }
 
+// AttrString returns the value of the principal attribute with the given key if it exists and is a string.
+func (p *Principal) AttrString(key string) (string, bool) {
+	return internal.AttrString(p.Obj.GetAttr(), key)
+}
+
+// AttrInt returns the value of the principal attribute with the given key if it exists and is a whole number.
+func (p *Principal) AttrInt(key string) (int64, bool) {
+	return internal.AttrInt(p.Obj.GetAttr(), key)
+}
+
+// AttrBool returns the value of the principal attribute with the given key if it exists and is a boolean.
+func (p *Principal) AttrBool(key string) (bool, bool) {
+	return internal.AttrBool(p.Obj.GetAttr(), key)
+}
+
+// AttrStringSlice returns the value of the principal attribute with the given key if it exists and is a list of strings.
+func (p *Principal) AttrStringSlice(key string) ([]string, bool) {
+	return internal.AttrStringSlice(p.Obj.GetAttr(), key)
+}
+
 // Proto returns the underlying protobuf object representing the principal.
 func (p *Principal) Proto() *enginev1.Principal {
 	return p.Obj
@@ -118,6 +172,7 @@ func (p *Principal) Err() error {
 }
 
 // Validate checks whether the principal object is valid.
+// It runs the same checks that the client performs before sending a request, so it can be used to report errors early.
 func (p *Principal) Validate() error {
 	if p.err != nil {
 		return p.err
@@ -128,8 +183,9 @@ func (p *Principal) Validate() error {
 
 // Resource is a single resource instance.
 type Resource struct {
-	Obj *enginev1.Resource
-	err error
+	Obj          *enginev1.Resource
+	err          error
+	timeEncoding TimeEncoding
 }
 
 // NewResource creates a new instance of a resource.
@@ -145,6 +201,13 @@ func (r *Resource) WithPolicyVersion(policyVersion string) *Resource {
 	return r
 }
 
+// WithTimeAttrEncoding sets how time.Time values in attributes added after this call are encoded.
+// To control the encoding of times in structs, call this before WithAttributesFromStruct.
+func (r *Resource) WithTimeAttrEncoding(encoding TimeEncoding) *Resource {
+	r.timeEncoding = encoding
+	return r
+}
+
 // WithAttributes merges the given attributes to the resource's existing attributes.
 func (r *Resource) WithAttributes(attr map[string]any) *Resource {
 	if r.Obj.Attr == nil {
@@ -152,7 +215,7 @@ func (r *Resource) WithAttributes(attr map[string]any) *Resource {
 	}
 
 	for k, v := range attr {
-		pbVal, err := internal.ToStructPB(v)
+		pbVal, err := internal.ToStructPBWithTimeEncoder(v, r.timeEncoding.encoder())
 		if err != nil {
 			r.err = multierr.Append(r.err, fmt.Errorf("invalid attribute value for '%s': %w", k, err))
 			continue
@@ -170,7 +233,7 @@ func (r *Resource) WithAttr(key string, value any) *Resource {
 		r.Obj.Attr = make(map[string]*structpb.Value)
 	}
 
-	pbVal, err := internal.ToStructPB(value)
+	pbVal, err := internal.ToStructPBWithTimeEncoder(value, r.timeEncoding.encoder())
 	if err != nil {
 		r.err = multierr.Append(r.err, fmt.Errorf("invalid attribute value for '%s': %w", key, err))
 		return r
@@ -180,6 +243,19 @@ func (r *Resource) WithAttr(key string, value any) *Resource {
 	return r
 }
 
+// WithAttributesFromStruct merges the exported fields of the given struct to the resource's existing attributes.
+// Attribute names default to the field names and can be changed using the `cerbos` struct tag (e.g. `cerbos:"owner,omitempty"`).
+// Fields tagged with `cerbos:"-"` are ignored.
+func (r *Resource) WithAttributesFromStruct(v any) *Resource {
+	attr, err := internal.StructToAttributes(v)
+	if err != nil {
+		r.err = multierr.Append(r.err, fmt.Errorf("failed to convert struct to attributes: %w", err))
+		return r
+	}
+
+	return r.WithAttributes(attr)
+}
+
 // WithScope sets the scope this resource belongs to.
 func (r *Resource) WithScope(scope string) *Resource {
 	r.Obj.Scope = scope
@@ -196,6 +272,26 @@ func (r *Resource) Kind() string {
 	return r.Obj.GetKind()
 }
 
+// AttrString returns the value of the resource attribute with the given key if it exists and is a string.
+func (r *Resource) AttrString(key string) (string, bool) {
+	return internal.AttrString(r.Obj.GetAttr(), key)
+}
+
+// AttrInt returns the value of the resource attribute with the given key if it exists and is a whole number.
+func (r *Resource) AttrInt(key string) (int64, bool) {
+	return internal.AttrInt(r.Obj.GetAttr(), key)
+}
+
+// AttrBool returns the value of the resource attribute with the given key if it exists and is a boolean.
+func (r *Resource) AttrBool(key string) (bool, bool) {
+	return internal.AttrBool(r.Obj.GetAttr(), key)
+}
+
+// AttrStringSlice returns the value of the resource attribute with the given key if it exists and is a list of strings.
+func (r *Resource) AttrStringSlice(key string) ([]string, bool) {
+	return internal.AttrStringSlice(r.Obj.GetAttr(), key)
+}
+
 // Proto returns the underlying protobuf object representing the resource.
 func (r *Resource) Proto() *enginev1.Resource {
 	return r.Obj
@@ -207,6 +303,7 @@ func (r *Resource) Err() error {
 }
 
 // Validate checks whether the resource is valid.
+// It runs the same checks that the client performs before sending a request, so it can be used to report errors early.
 func (r *Resource) Validate() error {
 	if r.err != nil {
 		return r.err
@@ -215,6 +312,17 @@ func (r *Resource) Validate() error {
 	return internal.Validate(r.Obj)
 }
 
+// ResourceFromStruct creates a new resource with attributes derived from the exported fields of the given struct.
+// See Resource.WithAttributesFromStruct for details about how the fields are converted.
+func ResourceFromStruct(kind, id string, v any) (*Resource, error) {
+	r := NewResource(kind, id).WithAttributesFromStruct(v)
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
 // ResourceBatch is a container for a batch of heterogeneous resources.
 type ResourceBatch struct {
 	err   error
@@ -227,8 +335,20 @@ func NewResourceBatch() *ResourceBatch {
 }
 
 // Add a new resource to the batch.
+// Invalid resources are not added. Instead, the problems are accumulated and reported together by Err and Validate.
 func (rb *ResourceBatch) Add(resource *Resource, actions ...string) *ResourceBatch {
-	if resource == nil || len(actions) == 0 {
+	if resource == nil || resource.Obj == nil {
+		rb.err = multierr.Append(rb.err, errors.New("resource is nil"))
+		return rb
+	}
+
+	if len(actions) == 0 {
+		rb.err = multierr.Append(rb.err, fmt.Errorf("invalid resource '%s': no actions to check", resource.Obj.Id))
+		return rb
+	}
+
+	if resource.err != nil {
+		rb.err = multierr.Append(rb.err, fmt.Errorf("invalid resource '%s': %w", resource.Obj.Id, resource.err))
 		return rb
 	}
 
@@ -246,6 +366,43 @@ func (rb *ResourceBatch) Add(resource *Resource, actions ...string) *ResourceBat
 	return rb
 }
 
+// AddResources adds the resources to the batch, checking the same actions for each of them.
+func (rb *ResourceBatch) AddResources(resources []*Resource, actions ...string) *ResourceBatch {
+	for _, r := range resources {
+		rb.Add(r, actions...)
+	}
+
+	return rb
+}
+
+// ResourceBatchFromStructs creates a batch containing a resource of the given kind for each of the items,
+// with the given actions to check. The ID of each resource is obtained by calling idFn and the attributes are derived
+// from the fields of the item as described in Resource.WithAttributesFromStruct.
+// Errors are reported with the index of the item that caused them.
+func ResourceBatchFromStructs(kind string, actions []string, items []any, idFn func(any) string) (*ResourceBatch, error) {
+	batch := NewResourceBatch()
+	var errs error
+	for i, item := range items {
+		r, err := ResourceFromStruct(kind, idFn(item), item)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("item %d: %w", i, err))
+			continue
+		}
+
+		batch.Add(r, actions...)
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	if err := batch.Err(); err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
 // Err returns any errors accumulated during the construction of the resource batch.
 func (rb *ResourceBatch) Err() error {
 	return rb.err
@@ -292,6 +449,50 @@ func (rr *ResourceResult) IsAllowed(action string) bool {
 	return false
 }
 
+const (
+	// noMatchPolicy is reported by the server as the matched policy of actions that were denied because no policy matched them.
+	noMatchPolicy = "NO_MATCH"
+	// noMatchForScopePermissionsPolicy is reported by the server as the matched policy of actions that were denied because
+	// no rule in a scope with scope permissions allowed them.
+	noMatchForScopePermissionsPolicy = "NO_MATCH_FOR_SCOPE_PERMISSIONS"
+)
+
+// Effect returns the effect of the given action, or EFFECT_UNSPECIFIED if the action is not in the response or if there
+// was an error getting this result. If the request was made with the IncludeMeta request option, actions that were denied
+// because no policy matched them, including those that no rule matched in a scope with scope permissions, are reported as
+// EFFECT_NO_MATCH instead of EFFECT_DENY.
+func (rr *ResourceResult) Effect(action string) effectv1.Effect {
+	if rr == nil || rr.err != nil {
+		return effectv1.Effect_EFFECT_UNSPECIFIED
+	}
+
+	effect, ok := rr.Actions[action]
+	if !ok {
+		return effectv1.Effect_EFFECT_UNSPECIFIED
+	}
+
+	if effect == effectv1.Effect_EFFECT_DENY {
+		if em, ok := rr.GetMeta().GetActions()[action]; ok {
+			switch em.GetMatchedPolicy() {
+			case "", noMatchPolicy, noMatchForScopePermissionsPolicy:
+				return effectv1.Effect_EFFECT_NO_MATCH
+			}
+		}
+	}
+
+	return effect
+}
+
+// EffectiveDerivedRoles returns the derived roles that were activated for the principal when evaluating the resource.
+// It's only populated if the request was made with the IncludeMeta request option.
+func (rr *ResourceResult) EffectiveDerivedRoles() []string {
+	if rr == nil || rr.err != nil {
+		return nil
+	}
+
+	return rr.GetMeta().GetEffectiveDerivedRoles()
+}
+
 func (rr *ResourceResult) buildOutputMap() {
 	rr.outputOnce.Do(func() {
 		if len(rr.GetOutputs()) == 0 {
@@ -366,6 +567,8 @@ func (crr *CheckResourcesResponse) buildIdx() {
 
 // GetResource finds the resource with the given ID and optional properties from the result list.
 // Returns a ResourceResult object with the Err field set if the resource is not found.
+// Results are matched using the resource details echoed back by the server, so the lookup doesn't depend on
+// the order of the results. This is the recommended way to consume the response.
 func (crr *CheckResourcesResponse) GetResource(resourceID string, match ...MatchResource) *ResourceResult {
 	crr.buildIdx()
 
@@ -393,6 +596,77 @@ func (crr *CheckResourcesResponse) GetResource(resourceID string, match ...Match
 	return &ResourceResult{err: fmt.Errorf("resource with ID %q does not exist in the response", resourceID)}
 }
 
+// ResultByIndex returns the result at the given position in the response.
+// Returns a ResourceResult object with the Err field set if the index is out of range.
+// The Cerbos server currently returns results in the same order as the resources in the request,
+// but this is not guaranteed by the API. Use GetResource for order-independent matching.
+func (crr *CheckResourcesResponse) ResultByIndex(i int) *ResourceResult {
+	if crr == nil || i < 0 || i >= len(crr.Results) || crr.Results[i] == nil {
+		return &ResourceResult{err: fmt.Errorf("no result at index %d in the response", i)}
+	}
+
+	return &ResourceResult{CheckResourcesResponse_ResultEntry: crr.Results[i]}
+}
+
+// IsAllowed returns true if the given action is allowed for the resource with the given ID.
+// Unlike ResourceResult.IsAllowed, it returns an error if the resource or the action is not in the response,
+// so that a denied action can be distinguished from one that was never evaluated.
+// The optional matchers can be used to pick a resource when more than one resource shares the same ID.
+func (crr *CheckResourcesResponse) IsAllowed(resourceID, action string, match ...MatchResource) (bool, error) {
+	rr := crr.GetResource(resourceID, match...)
+	if err := rr.Err(); err != nil {
+		return false, err
+	}
+
+	effect, ok := rr.Actions[action]
+	if !ok {
+		return false, fmt.Errorf("action %q does not exist in the response for resource with ID %q", action, resourceID)
+	}
+
+	return effect == effectv1.Effect_EFFECT_ALLOW, nil
+}
+
+// ResultsByResourceID returns the results indexed by resource ID. The returned map is never nil.
+// If the response contains more than one result for the same resource ID (for example, resources of different kinds
+// sharing an ID), the last one wins. Use HasDuplicates to detect this case and GetResource to match by kind.
+func (crr *CheckResourcesResponse) ResultsByResourceID() map[string]*ResourceResult {
+	if crr == nil {
+		return map[string]*ResourceResult{}
+	}
+
+	out := make(map[string]*ResourceResult, len(crr.Results))
+	for _, r := range crr.Results {
+		if r == nil {
+			continue
+		}
+		out[r.GetResource().GetId()] = &ResourceResult{CheckResourcesResponse_ResultEntry: r}
+	}
+
+	return out
+}
+
+// HasDuplicates returns true if the response contains more than one result for the same resource ID.
+func (crr *CheckResourcesResponse) HasDuplicates() bool {
+	if crr == nil {
+		return false
+	}
+
+	seen := make(map[string]struct{}, len(crr.Results))
+	for _, r := range crr.Results {
+		if r == nil {
+			continue
+		}
+
+		id := r.GetResource().GetId()
+		if _, ok := seen[id]; ok {
+			return true
+		}
+		seen[id] = struct{}{}
+	}
+
+	return false
+}
+
 // Errors returns any validation errors returned by the server.
 func (crr *CheckResourcesResponse) Errors() error {
 	var err error
@@ -407,15 +681,135 @@ func (crr *CheckResourcesResponse) Errors() error {
 	return err
 }
 
+// ValidationErrors returns the schema validation errors reported by the server for the resources with the given ID.
+func (crr *CheckResourcesResponse) ValidationErrors(resourceID string) []*schemav1.ValidationError {
+	var verrs []*schemav1.ValidationError
+	for _, result := range crr.GetResults() {
+		if result.GetResource().GetId() == resourceID {
+			verrs = append(verrs, result.ValidationErrors...)
+		}
+	}
+
+	return verrs
+}
+
+// AllValidationErrors returns the schema validation errors reported by the server for all the resources in the response.
+// Use ValidationErrors or Errors to find out which resource each error relates to.
+func (crr *CheckResourcesResponse) AllValidationErrors() []*schemav1.ValidationError {
+	var verrs []*schemav1.ValidationError
+	for _, result := range crr.GetResults() {
+		verrs = append(verrs, result.ValidationErrors...)
+	}
+
+	return verrs
+}
+
+// ServerTraceID returns the call ID assigned to the request by the Cerbos server.
+// It's the ID used by the server to identify the request in its audit logs, and can be used to correlate
+// the response with the corresponding decision log entry.
+func (crr *CheckResourcesResponse) ServerTraceID() string {
+	if crr == nil {
+		return ""
+	}
+
+	return crr.GetCerbosCallId()
+}
+
 func (crr *CheckResourcesResponse) String() string {
-	return protojson.Format(crr.CheckResourcesResponse)
+	return formatJSON(crr.CheckResourcesResponse)
 }
 
 func (crr *CheckResourcesResponse) MarshalJSON() ([]byte, error) {
-	return protojson.Marshal(crr.CheckResourcesResponse)
+	return marshalJSON(crr.CheckResourcesResponse)
+}
+
+// Matrix holds the outcome of checking a set of actions against a set of resources.
+type Matrix struct {
+	resp *CheckResourcesResponse
+}
+
+// Allowed returns true if the action is allowed on the resource with the given ID.
+// If resources of different kinds share the same ID, use the optional matchers to pick the right one.
+func (m Matrix) Allowed(resourceID, action string, match ...MatchResource) bool {
+	if m.resp == nil {
+		return false
+	}
+
+	return m.resp.GetResource(resourceID, match...).IsAllowed(action)
+}
+
+// Response returns the underlying CheckResources response.
+func (m Matrix) Response() *CheckResourcesResponse {
+	return m.resp
+}
+
+// KindAction is a resource kind and an action to check for AccessibleKinds.
+type KindAction struct {
+	Kind   string
+	Action string
+}
+
+// Policy kinds returned by PolicyKind.
+const (
+	PolicyKindResource        = "RESOURCE"
+	PolicyKindPrincipal       = "PRINCIPAL"
+	PolicyKindDerivedRoles    = "DERIVED_ROLES"
+	PolicyKindExportVariables = "EXPORT_VARIABLES"
+	PolicyKindUnknown         = "UNKNOWN"
+)
+
+// PolicyKind returns the kind of the given policy.
+// PolicyKindUnknown is returned for policy types not supported by this version of the SDK.
+func PolicyKind(p *policyv1.Policy) string {
+	switch p.GetPolicyType().(type) {
+	case *policyv1.Policy_ResourcePolicy:
+		return PolicyKindResource
+	case *policyv1.Policy_PrincipalPolicy:
+		return PolicyKindPrincipal
+	case *policyv1.Policy_DerivedRoles:
+		return PolicyKindDerivedRoles
+	case *policyv1.Policy_ExportVariables:
+		return PolicyKindExportVariables
+	default:
+		return PolicyKindUnknown
+	}
+}
+
+// PolicyMetadata returns the metadata of the given policy, creating it if it doesn't exist.
+// Policies loaded from files have the source file and the hash of the policy populated in their metadata.
+func PolicyMetadata(p *policyv1.Policy) *policyv1.Metadata {
+	if p == nil {
+		return nil
+	}
+
+	if p.Metadata == nil {
+		p.Metadata = &policyv1.Metadata{}
+	}
+
+	return p.Metadata
+}
+
+// WithPolicyAnnotation sets the annotation with the given key on the policy metadata and returns the policy.
+// Annotations can be used to record provenance information, such as the commit that the policy was loaded from.
+func WithPolicyAnnotation(p *policyv1.Policy, key, value string) *policyv1.Policy {
+	md := PolicyMetadata(p)
+	if md == nil {
+		return p
+	}
+
+	if md.Annotations == nil {
+		md.Annotations = make(map[string]string)
+	}
+
+	md.Annotations[key] = value
+	return p
 }
 
 // PolicySet is a container for a set of policies.
+//
+// Policies read from YAML may use the standard tags !!str, !!int, !!float, !!bool, !!null, !!timestamp, !!map, !!seq and !!merge.
+// Values tagged with !!binary are converted to strings containing the base64-encoded data. Other tags, including custom tags,
+// are rejected with an error.
 type PolicySet struct {
 	err      error
 	policies []*policyv1.Policy
@@ -427,6 +821,8 @@ func NewPolicySet() *PolicySet {
 }
 
 // AddPolicyFromFile adds a policy from the given file to the set.
+// The path of the file is recorded as the source file in the policy metadata.
+// Files larger than 4MiB are rejected with an error wrapping ErrPolicyFileTooLarge.
 func (ps *PolicySet) AddPolicyFromFile(file string) *PolicySet {
 	f, err := os.Open(file)
 	if err != nil {
@@ -435,10 +831,21 @@ func (ps *PolicySet) AddPolicyFromFile(file string) *PolicySet {
 	}
 
 	defer f.Close()
-	return ps.AddPolicyFromReader(f)
+
+	p, err := internal.ReadPolicy(f)
+	if err != nil {
+		ps.err = multierr.Append(ps.err, fmt.Errorf("failed to add policy from file '%s': %w", file, err))
+		return ps
+	}
+
+	internal.SetPolicyProvenance(p, file)
+	ps.policies = append(ps.policies, p)
+	return ps
 }
 
 // AddPolicyFromFileWithErr adds a policy from the given file to the set and returns the error.
+// The path of the file is recorded as the source file in the policy metadata.
+// Files larger than 4MiB are rejected with an error wrapping ErrPolicyFileTooLarge.
 func (ps *PolicySet) AddPolicyFromFileWithErr(file string) (*PolicySet, error) {
 	f, err := os.Open(file)
 	if err != nil {
@@ -451,10 +858,24 @@ func (ps *PolicySet) AddPolicyFromFileWithErr(file string) (*PolicySet, error) {
 		return nil, fmt.Errorf("failed to read policy: %w", err)
 	}
 
+	internal.SetPolicyProvenance(p, file)
 	return ps.AddPolicies(p), nil
 }
 
+// ReadPolicyLenient reads a policy from the given reader, discarding any fields unknown to this version of the SDK.
+// It's intended for loading policies authored for newer versions of Cerbos, but it also hides typos in field names.
+// Add the policy to a set using AddPolicies. Policies larger than 4MiB are rejected with an error wrapping ErrPolicyFileTooLarge.
+func ReadPolicyLenient(r io.Reader) (*policyv1.Policy, error) {
+	p, err := internal.ReadPolicyLenient(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	return p, nil
+}
+
 // AddPolicyFromReader adds a policy from the given reader to the set.
+// Policies larger than 4MiB are rejected with an error wrapping ErrPolicyFileTooLarge.
 func (ps *PolicySet) AddPolicyFromReader(r io.Reader) *PolicySet {
 	p, err := internal.ReadPolicy(r)
 	if err != nil {
@@ -466,6 +887,25 @@ func (ps *PolicySet) AddPolicyFromReader(r io.Reader) *PolicySet {
 	return ps
 }
 
+// AddPoliciesFromReader adds all the policies from the given reader to the set.
+// The reader can contain multiple YAML documents separated by "---" lines, each defining a policy.
+func (ps *PolicySet) AddPoliciesFromReader(r io.Reader) *PolicySet {
+	policies, err := ReadPolicies(r)
+	if err != nil {
+		ps.err = multierr.Append(ps.err, fmt.Errorf("failed to add policies from reader: %w", err))
+		return ps
+	}
+
+	ps.policies = append(ps.policies, policies...)
+	return ps
+}
+
+// ReadPolicies reads all the policies from the given reader, which can contain multiple YAML documents
+// separated by "---" lines, each defining a policy. Empty documents are skipped. JSON input must contain a single policy.
+func ReadPolicies(r io.Reader) ([]*policyv1.Policy, error) {
+	return internal.ReadPolicies(r)
+}
+
 // AddPolicies adds the given policies to the set.
 func (ps *PolicySet) AddPolicies(policies ...*policyv1.Policy) *PolicySet {
 	ps.policies = append(ps.policies, policies...)
@@ -660,6 +1100,29 @@ func (ss *SchemaSet) Err() error {
 	return ss.err
 }
 
+// Validate checks that the schema set is not empty and that every schema has an ID and a definition.
+func (ss *SchemaSet) Validate() error {
+	if ss.err != nil {
+		return ss.err
+	}
+
+	if len(ss.schemas) == 0 {
+		return errors.New("empty schema set")
+	}
+
+	var errs error
+	for i, s := range ss.schemas {
+		switch {
+		case s.GetId() == "":
+			errs = multierr.Append(errs, fmt.Errorf("schema at index %d has no ID", i))
+		case len(s.GetDefinition()) == 0:
+			errs = multierr.Append(errs, fmt.Errorf("schema %q has no definition", s.GetId()))
+		}
+	}
+
+	return errs
+}
+
 // Schema is a builder for Schemas_Schema.
 type Schema struct {
 	Obj *policyv1.Schemas_Schema
@@ -1166,11 +1629,59 @@ type ServerInfo struct {
 }
 
 func (si *ServerInfo) String() string {
-	return protojson.Format(si.ServerInfoResponse)
+	return formatJSON(si.ServerInfoResponse)
 }
 
 func (si *ServerInfo) MarshalJSON() ([]byte, error) {
-	return protojson.Marshal(si.ServerInfoResponse)
+	return marshalJSON(si.ServerInfoResponse)
+}
+
+// marshalJSON renders the message as Cerbos JSON. Unlike protojson, the output is stable: fields are in the order
+// in which they are declared, map entries are sorted by key and there's no whitespace.
+func marshalJSON(m proto.Message) ([]byte, error) {
+	out, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// protojson randomly adds whitespace to discourage relying on its output being byte-for-byte stable.
+	buf := new(bytes.Buffer)
+	if err := json.Compact(buf, out); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// formatJSON renders the message as indented Cerbos JSON for display. It returns an empty string if the message can't be rendered.
+func formatJSON(m proto.Message) string {
+	out, err := marshalJSON(m)
+	if err != nil {
+		return ""
+	}
+
+	buf := new(bytes.Buffer)
+	if err := json.Indent(buf, out, "", "  "); err != nil {
+		return ""
+	}
+
+	return buf.String()
+}
+
+// AtLeast returns true if the server version is equal to or newer than the given version.
+// Pre-release suffixes are ignored when comparing versions.
+func (si *ServerInfo) AtLeast(version string) (bool, error) {
+	want, err := internal.ParseSemVer(version)
+	if err != nil {
+		return false, err
+	}
+
+	have, err := internal.ParseSemVer(si.GetVersion())
+	if err != nil {
+		return false, fmt.Errorf("failed to parse server version: %w", err)
+	}
+
+	return have.Compare(want) >= 0, nil
 }
 
 type AuditLogType uint8
@@ -1211,6 +1722,149 @@ type PlanResourcesResponse struct {
 	*responsev1.PlanResourcesResponse
 }
 
+func (r *PlanResourcesResponse) String() string {
+	return formatJSON(r.PlanResourcesResponse)
+}
+
+func (r *PlanResourcesResponse) MarshalJSON() ([]byte, error) {
+	return marshalJSON(r.PlanResourcesResponse)
+}
+
+// Kind returns the kind of the query plan filter.
+func (r *PlanResourcesResponse) Kind() enginev1.PlanResourcesFilter_Kind {
+	if r == nil {
+		return enginev1.PlanResourcesFilter_KIND_UNSPECIFIED
+	}
+
+	return r.GetFilter().GetKind()
+}
+
+// AllowsAll returns true if the principal is allowed to perform the action on all resources of the kind.
+func (r *PlanResourcesResponse) AllowsAll() bool {
+	return r.Kind() == enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED
+}
+
+// DeniesAll returns true if the principal is not allowed to perform the action on any resource of the kind.
+func (r *PlanResourcesResponse) DeniesAll() bool {
+	return r.Kind() == enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED
+}
+
+// IsConditional returns true if the principal is only allowed to perform the action on resources that satisfy the filter condition.
+func (r *PlanResourcesResponse) IsConditional() bool {
+	return r.Kind() == enginev1.PlanResourcesFilter_KIND_CONDITIONAL
+}
+
+// ServerTraceID returns the call ID assigned to the request by the Cerbos server.
+// It's the ID used by the server to identify the request in its audit logs, and can be used to correlate
+// the response with the corresponding decision log entry.
+func (r *PlanResourcesResponse) ServerTraceID() string {
+	if r == nil {
+		return ""
+	}
+
+	return r.GetCerbosCallId()
+}
+
+// Errors returns any validation errors returned by the server.
+// The server only validates the attributes against the schemas if schema enforcement is enabled, and still produces a plan
+// when it's configured to only warn about validation errors.
+func (r *PlanResourcesResponse) Errors() error {
+	if r == nil {
+		return nil
+	}
+
+	var err error
+	for _, verr := range r.GetValidationErrors() {
+		err = multierr.Append(err,
+			fmt.Errorf("query plan for %q failed validation: source=%s path=%s msg=%s", r.ResourceKind, verr.Source, verr.Path, verr.Message),
+		)
+	}
+
+	return err
+}
+
+// FilterJSON serializes the query plan to JSON so that it can be stored and reused later without calling the server again.
+// Use PlanFromJSON to load it. Along with the filter, the JSON includes the action, resource kind and policy version the plan
+// was produced for. Details that only make sense for a single call, such as the request ID and the metadata, are omitted.
+//
+// A stored plan reflects the policies that were in effect when it was produced and is not updated when the policies or the
+// attributes of the principal change. Store it alongside a version, such as the policy version or a deployment identifier,
+// and discard it when that changes.
+func (r *PlanResourcesResponse) FilterJSON() ([]byte, error) {
+	if r == nil || r.PlanResourcesResponse == nil {
+		return nil, errors.New("response is nil")
+	}
+
+	stored := &responsev1.PlanResourcesResponse{
+		Action:        r.Action,
+		ResourceKind:  r.ResourceKind,
+		PolicyVersion: r.PolicyVersion,
+		Filter:        r.Filter,
+	}
+
+	return marshalJSON(stored)
+}
+
+// PlanFromJSON loads a query plan serialized using FilterJSON.
+func PlanFromJSON(data []byte) (*PlanResourcesResponse, error) {
+	resp := &responsev1.PlanResourcesResponse{}
+	if err := protojson.Unmarshal(data, resp); err != nil {
+		return nil, fmt.Errorf("invalid plan JSON: %w", err)
+	}
+
+	if resp.GetFilter().GetKind() == enginev1.PlanResourcesFilter_KIND_UNSPECIFIED {
+		return nil, errors.New("invalid plan JSON: filter kind is missing")
+	}
+
+	return &PlanResourcesResponse{PlanResourcesResponse: resp}, nil
+}
+
+// PlanDebugInfo describes how the Cerbos server arrived at the filter of a query plan.
+type PlanDebugInfo struct {
+	// Filter is the simplified filter condition rendered in a human-readable form by the server.
+	Filter string
+	// MatchedScope is the scope of the policy that produced the plan. It's empty if the plan came from an unscoped policy.
+	MatchedScope string
+	// Variables are the attributes and other variables that the filter condition depends on, sorted and deduplicated.
+	Variables []string
+	// Kind is the kind of the filter.
+	Kind enginev1.PlanResourcesFilter_Kind
+}
+
+// DebugInfo extracts debugging information from the response.
+// The Filter and MatchedScope fields are only populated if the request was made with the IncludeMeta request option.
+func (r *PlanResourcesResponse) DebugInfo() *PlanDebugInfo {
+	if r == nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var variables []string
+	var walk func(*enginev1.PlanResourcesFilter_Expression_Operand)
+	walk = func(o *enginev1.PlanResourcesFilter_Expression_Operand) {
+		if v := o.GetVariable(); v != "" {
+			if _, ok := seen[v]; !ok {
+				seen[v] = struct{}{}
+				variables = append(variables, v)
+			}
+			return
+		}
+
+		for _, child := range o.GetExpression().GetOperands() {
+			walk(child)
+		}
+	}
+	walk(r.GetFilter().GetCondition())
+	sort.Strings(variables)
+
+	return &PlanDebugInfo{
+		Filter:       r.GetMeta().GetFilterDebug(),
+		MatchedScope: r.GetMeta().GetMatchedScope(),
+		Variables:    variables,
+		Kind:         r.Kind(),
+	}
+}
+
 type (
 	FilterOptions struct {
 		NameRegexp      string
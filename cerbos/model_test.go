@@ -7,13 +7,25 @@
 package cerbos_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
 
 	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	"github.com/cerbos/cerbos-sdk-go/internal/tests"
 	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
 	policyv1 "github.com/cerbos/cerbos/api/genpb/cerbos/policy/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+	schemav1 "github.com/cerbos/cerbos/api/genpb/cerbos/schema/v1"
 )
 
 const (
@@ -329,3 +341,432 @@ func newSchema(t *testing.T) *cerbos.Schema {
 	return cerbos.NewSchema(ref).
 		AddIgnoredActions(actionApprove)
 }
+
+func TestPolicyKind(t *testing.T) {
+	testCases := []struct {
+		file string
+		want string
+	}{
+		{file: "resource_policies/policy_01.yaml", want: cerbos.PolicyKindResource},
+		{file: "principal_policies/policy_01.yaml", want: cerbos.PolicyKindPrincipal},
+		{file: "derived_roles/derived_roles_01.yaml", want: cerbos.PolicyKindDerivedRoles},
+		{file: "export_variables/export_variables_01.yaml", want: cerbos.PolicyKindExportVariables},
+	}
+
+	policyDir := tests.PathToTestDataDir(t, "policies")
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			ps, err := cerbos.NewPolicySet().AddPolicyFromFileWithErr(filepath.Join(policyDir, tc.file))
+			require.NoError(t, err)
+			require.Equal(t, 1, ps.Size())
+			require.Equal(t, tc.want, cerbos.PolicyKind(ps.GetPolicies()[0]))
+		})
+	}
+
+	require.Equal(t, cerbos.PolicyKindUnknown, cerbos.PolicyKind(&policyv1.Policy{}))
+	require.Equal(t, cerbos.PolicyKindUnknown, cerbos.PolicyKind(nil))
+}
+
+func TestPolicyFileTooLarge(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(file, bytes.Repeat([]byte("#\n"), 3*1024*1024), 0o600))
+
+	_, err := cerbos.NewPolicySet().AddPolicyFromFileWithErr(file)
+	require.ErrorIs(t, err, cerbos.ErrPolicyFileTooLarge)
+
+	require.ErrorIs(t, cerbos.NewPolicySet().AddPolicyFromFile(file).Err(), cerbos.ErrPolicyFileTooLarge)
+}
+
+func TestReadPolicyLenient(t *testing.T) {
+	const policy = `apiVersion: api.cerbos.dev/v1
+resourcePolicy:
+  resource: leave_request
+  version: default
+  someFutureField: true
+  rules:
+    - actions: ["view"]
+      effect: EFFECT_ALLOW
+      roles: ["employee"]
+`
+
+	require.Error(t, cerbos.NewPolicySet().AddPolicyFromReader(strings.NewReader(policy)).Err())
+
+	p, err := cerbos.ReadPolicyLenient(strings.NewReader(policy))
+	require.NoError(t, err)
+	require.Equal(t, "leave_request", p.GetResourcePolicy().GetResource())
+
+	ps := cerbos.NewPolicySet().AddPolicies(p)
+	require.NoError(t, ps.Validate())
+	require.Equal(t, 1, ps.Size())
+}
+
+func TestPolicyMetadata(t *testing.T) {
+	file := filepath.Join(tests.PathToTestDataDir(t, "policies"), "resource_policies", "policy_01.yaml")
+
+	ps := cerbos.NewPolicySet().AddPolicyFromFile(file)
+	require.NoError(t, ps.Err())
+	p := ps.GetPolicies()[0]
+
+	md := cerbos.PolicyMetadata(p)
+	require.Equal(t, file, md.SourceFile)
+	require.NotNil(t, md.Hash)
+	hash := md.Hash.GetValue()
+
+	require.Same(t, p, cerbos.WithPolicyAnnotation(p, "commit", "abc123"))
+	require.Equal(t, map[string]string{"commit": "abc123"}, cerbos.PolicyMetadata(p).Annotations)
+
+	// The hash only covers the policy definition.
+	ps2, err := cerbos.NewPolicySet().AddPolicyFromFileWithErr(file)
+	require.NoError(t, err)
+	require.Equal(t, hash, cerbos.PolicyMetadata(ps2.GetPolicies()[0]).Hash.GetValue())
+
+	empty := &policyv1.Policy{}
+	cerbos.WithPolicyAnnotation(empty, "owner", "team-a")
+	require.Equal(t, "team-a", empty.Metadata.Annotations["owner"])
+	require.Nil(t, cerbos.PolicyMetadata(nil))
+}
+
+func TestResultByIndex(t *testing.T) {
+	entry := func(kind, id string, effect effectv1.Effect) *responsev1.CheckResourcesResponse_ResultEntry {
+		return &responsev1.CheckResourcesResponse_ResultEntry{
+			Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Kind: kind, Id: id},
+			Actions:  map[string]effectv1.Effect{actionApprove: effect},
+		}
+	}
+
+	// Results in a different order to the request.
+	resp := &cerbos.CheckResourcesResponse{
+		CheckResourcesResponse: &responsev1.CheckResourcesResponse{
+			Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+				entry("purchase_order", id, effectv1.Effect_EFFECT_DENY),
+				entry(kind, id, effectv1.Effect_EFFECT_ALLOW),
+			},
+		},
+	}
+
+	require.True(t, resp.GetResource(id, cerbos.MatchResourceKind(kind)).IsAllowed(actionApprove))
+	require.False(t, resp.GetResource(id, cerbos.MatchResourceKind("purchase_order")).IsAllowed(actionApprove))
+
+	require.Equal(t, "purchase_order", resp.ResultByIndex(0).Resource.Kind)
+	require.True(t, resp.ResultByIndex(1).IsAllowed(actionApprove))
+	require.Error(t, resp.ResultByIndex(2).Err())
+	require.Error(t, resp.ResultByIndex(-1).Err())
+	require.False(t, resp.ResultByIndex(2).IsAllowed(actionApprove))
+}
+
+func TestCheckResourcesResponseIsAllowed(t *testing.T) {
+	resp := &cerbos.CheckResourcesResponse{
+		CheckResourcesResponse: &responsev1.CheckResourcesResponse{
+			Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+				{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Kind: kind, Id: id},
+					Actions:  map[string]effectv1.Effect{actionApprove: effectv1.Effect_EFFECT_ALLOW, actionCreate: effectv1.Effect_EFFECT_DENY},
+				},
+				{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Kind: "purchase_order", Id: id},
+					Actions:  map[string]effectv1.Effect{actionApprove: effectv1.Effect_EFFECT_DENY},
+				},
+			},
+		},
+	}
+
+	allowed, err := resp.IsAllowed(id, actionApprove, cerbos.MatchResourceKind(kind))
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	allowed, err = resp.IsAllowed(id, actionCreate)
+	require.NoError(t, err)
+	require.False(t, allowed)
+
+	allowed, err = resp.IsAllowed(id, actionApprove, cerbos.MatchResourceKind("purchase_order"))
+	require.NoError(t, err)
+	require.False(t, allowed)
+
+	_, err = resp.IsAllowed(id, "delete")
+	require.ErrorContains(t, err, `action "delete" does not exist`)
+
+	_, err = resp.IsAllowed("XX999", actionApprove)
+	require.ErrorContains(t, err, `resource with ID "XX999" does not exist`)
+}
+
+func TestSchemaSetValidate(t *testing.T) {
+	valid := &schemav1.Schema{Id: "principal.json", Definition: []byte(`{"type": "object"}`)}
+
+	require.NoError(t, cerbos.NewSchemaSet().AddSchemas(valid).Validate())
+	require.ErrorContains(t, cerbos.NewSchemaSet().Validate(), "empty schema set")
+	require.ErrorContains(t, cerbos.NewSchemaSet().AddSchemas(valid, &schemav1.Schema{Definition: valid.Definition}).Validate(), "schema at index 1 has no ID")
+	require.ErrorContains(t, cerbos.NewSchemaSet().AddSchemas(&schemav1.Schema{Id: "resource.json"}).Validate(), `schema "resource.json" has no definition`)
+}
+
+func TestResultsByResourceID(t *testing.T) {
+	entry := func(kind, id string, effect effectv1.Effect) *responsev1.CheckResourcesResponse_ResultEntry {
+		return &responsev1.CheckResourcesResponse_ResultEntry{
+			Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Kind: kind, Id: id},
+			Actions:  map[string]effectv1.Effect{actionApprove: effect},
+		}
+	}
+
+	t.Run("unique", func(t *testing.T) {
+		resp := &cerbos.CheckResourcesResponse{
+			CheckResourcesResponse: &responsev1.CheckResourcesResponse{
+				Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+					entry(kind, "XX125", effectv1.Effect_EFFECT_ALLOW),
+					entry(kind, "XX225", effectv1.Effect_EFFECT_DENY),
+				},
+			},
+		}
+
+		results := resp.ResultsByResourceID()
+		require.Len(t, results, 2)
+		require.True(t, results["XX125"].IsAllowed(actionApprove))
+		require.False(t, results["XX225"].IsAllowed(actionApprove))
+		require.False(t, resp.HasDuplicates())
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		resp := &cerbos.CheckResourcesResponse{
+			CheckResourcesResponse: &responsev1.CheckResourcesResponse{
+				Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+					entry("purchase_order", id, effectv1.Effect_EFFECT_DENY),
+					entry(kind, id, effectv1.Effect_EFFECT_ALLOW),
+				},
+			},
+		}
+
+		results := resp.ResultsByResourceID()
+		require.Len(t, results, 1)
+		require.Equal(t, kind, results[id].Resource.Kind)
+		require.True(t, resp.HasDuplicates())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		resp := &cerbos.CheckResourcesResponse{CheckResourcesResponse: &responsev1.CheckResourcesResponse{}}
+		require.NotNil(t, resp.ResultsByResourceID())
+		require.Empty(t, resp.ResultsByResourceID())
+		require.False(t, resp.HasDuplicates())
+
+		var nilResp *cerbos.CheckResourcesResponse
+		require.NotNil(t, nilResp.ResultsByResourceID())
+	})
+}
+
+func TestResourceBatchAdd(t *testing.T) {
+	r1 := cerbos.NewResource("leave_request", "XX125")
+	r2 := cerbos.NewResource("leave_request", "XX150")
+
+	batch := cerbos.NewResourceBatch().AddResources([]*cerbos.Resource{r1, r2}, "view", "approve")
+	require.NoError(t, batch.Validate())
+	require.Len(t, batch.Batch, 2)
+	require.Equal(t, []string{"view", "approve"}, batch.Batch[1].Actions)
+
+	batch = cerbos.NewResourceBatch().
+		Add(r1, "view").
+		Add(nil, "view").
+		Add(r2).
+		Add(cerbos.NewResource("", "XX200"), "view").
+		Add(cerbos.NewResource("leave_request", "XX225").WithAttr("bad", make(chan int)), "view")
+
+	err := batch.Validate()
+	require.Error(t, err)
+	require.Len(t, multierr.Errors(err), 4)
+	require.ErrorContains(t, err, "resource is nil")
+	require.ErrorContains(t, err, "invalid resource 'XX150': no actions to check")
+	require.ErrorContains(t, err, "invalid resource 'XX200'")
+	require.ErrorContains(t, err, "invalid resource 'XX225'")
+	require.Len(t, batch.Batch, 1)
+}
+
+func TestResourceBatchFromStructs(t *testing.T) {
+	type document struct {
+		ID    string `cerbos:"-"`
+		Owner string `cerbos:"owner"`
+	}
+
+	idFn := func(v any) string {
+		switch d := v.(type) {
+		case document:
+			return d.ID
+		default:
+			return "invalid"
+		}
+	}
+
+	batch, err := cerbos.ResourceBatchFromStructs("document", []string{"view", "edit"}, []any{
+		document{ID: "doc1", Owner: "alice"},
+		document{ID: "doc2", Owner: "bob"},
+	}, idFn)
+	require.NoError(t, err)
+	require.Len(t, batch.Batch, 2)
+	require.Equal(t, "doc2", batch.Batch[1].Resource.Id)
+	require.Equal(t, "bob", batch.Batch[1].Resource.Attr["owner"].GetStringValue())
+	require.Equal(t, []string{"view", "edit"}, batch.Batch[1].Actions)
+
+	_, err = cerbos.ResourceBatchFromStructs("document", []string{"view"}, []any{document{ID: "doc1"}, 42}, idFn)
+	require.ErrorContains(t, err, "item 1")
+}
+
+func TestTimeAttrEncoding(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	r := cerbos.NewResource(kind, id).WithAttr("rfc3339", ts).
+		WithTimeAttrEncoding(cerbos.TimeEncodingUnixMillis).
+		WithAttr("millis", ts).
+		WithAttributesFromStruct(struct {
+			CreatedAt time.Time `cerbos:"createdAt"`
+		}{CreatedAt: ts})
+	require.NoError(t, r.Err())
+	require.Equal(t, "2024-01-02T03:04:05Z", r.Obj.Attr["rfc3339"].GetStringValue())
+	require.Equal(t, float64(ts.UnixMilli()), r.Obj.Attr["millis"].GetNumberValue())
+	require.Equal(t, float64(ts.UnixMilli()), r.Obj.Attr["createdAt"].GetNumberValue())
+
+	p := cerbos.NewPrincipal(id).WithTimeAttrEncoding(cerbos.TimeEncodingUnixSeconds).WithAttr("since", ts)
+	require.NoError(t, p.Err())
+	require.Equal(t, float64(ts.Unix()), p.Obj.Attr["since"].GetNumberValue())
+}
+
+func TestPlanResourcesResponseKind(t *testing.T) {
+	testCases := []struct {
+		kind          enginev1.PlanResourcesFilter_Kind
+		allowsAll     bool
+		deniesAll     bool
+		isConditional bool
+	}{
+		{kind: enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED, allowsAll: true},
+		{kind: enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED, deniesAll: true},
+		{kind: enginev1.PlanResourcesFilter_KIND_CONDITIONAL, isConditional: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.kind.String(), func(t *testing.T) {
+			resp := &cerbos.PlanResourcesResponse{
+				PlanResourcesResponse: &responsev1.PlanResourcesResponse{Filter: &enginev1.PlanResourcesFilter{Kind: tc.kind}},
+			}
+			require.Equal(t, tc.kind, resp.Kind())
+			require.Equal(t, tc.allowsAll, resp.AllowsAll())
+			require.Equal(t, tc.deniesAll, resp.DeniesAll())
+			require.Equal(t, tc.isConditional, resp.IsConditional())
+		})
+	}
+
+	var resp *cerbos.PlanResourcesResponse
+	require.False(t, resp.AllowsAll())
+	require.False(t, (&cerbos.PlanResourcesResponse{}).IsConditional())
+	require.NoError(t, resp.Errors())
+}
+
+func TestPlanResourcesResponseErrors(t *testing.T) {
+	resp := &cerbos.PlanResourcesResponse{
+		PlanResourcesResponse: &responsev1.PlanResourcesResponse{
+			ResourceKind: "leave_request",
+			Filter:       &enginev1.PlanResourcesFilter{Kind: enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED},
+		},
+	}
+	require.NoError(t, resp.Errors())
+
+	resp.ValidationErrors = []*schemav1.ValidationError{
+		{Path: "/department", Message: "value must be one of \"marketing\", \"engineering\"", Source: schemav1.ValidationError_SOURCE_PRINCIPAL},
+		{Path: "/owner", Message: "missing property", Source: schemav1.ValidationError_SOURCE_RESOURCE},
+	}
+
+	err := resp.Errors()
+	require.Len(t, multierr.Errors(err), 2)
+	require.ErrorContains(t, err, `query plan for "leave_request" failed validation: source=SOURCE_PRINCIPAL path=/department`)
+	require.ErrorContains(t, err, "source=SOURCE_RESOURCE path=/owner msg=missing property")
+}
+
+func TestPlanFilterJSON(t *testing.T) {
+	resp := &cerbos.PlanResourcesResponse{
+		PlanResourcesResponse: &responsev1.PlanResourcesResponse{
+			RequestId:     "req-1",
+			Action:        "view",
+			ResourceKind:  "leave_request",
+			PolicyVersion: "default",
+			Filter: &enginev1.PlanResourcesFilter{
+				Kind: enginev1.PlanResourcesFilter_KIND_CONDITIONAL,
+				Condition: &enginev1.PlanResourcesFilter_Expression_Operand{
+					Node: &enginev1.PlanResourcesFilter_Expression_Operand_Variable{Variable: "request.resource.attr.public"},
+				},
+			},
+			Meta: &responsev1.PlanResourcesResponse_Meta{FilterDebug: "request.resource.attr.public"},
+		},
+	}
+
+	data, err := resp.FilterJSON()
+	require.NoError(t, err)
+
+	have, err := cerbos.PlanFromJSON(data)
+	require.NoError(t, err)
+	require.True(t, have.IsConditional())
+	require.Equal(t, "view", have.Action)
+	require.Equal(t, "leave_request", have.ResourceKind)
+	require.Equal(t, "default", have.PolicyVersion)
+	require.Equal(t, "request.resource.attr.public", have.Filter.GetCondition().GetVariable())
+	require.Empty(t, have.RequestId)
+	require.Nil(t, have.Meta)
+
+	_, err = cerbos.PlanFromJSON([]byte(`{"action": "view"}`))
+	require.Error(t, err)
+
+	_, err = cerbos.PlanFromJSON([]byte(`not json`))
+	require.Error(t, err)
+
+	_, err = (*cerbos.PlanResourcesResponse)(nil).FilterJSON()
+	require.Error(t, err)
+}
+
+func TestResponseJSON(t *testing.T) {
+	check := &cerbos.CheckResourcesResponse{
+		CheckResourcesResponse: &responsev1.CheckResourcesResponse{
+			RequestId: "req-1",
+			Results: []*responsev1.CheckResourcesResponse_ResultEntry{
+				{
+					Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: id, Kind: kind},
+					Actions: map[string]effectv1.Effect{
+						actionApprove: effectv1.Effect_EFFECT_DENY,
+						actionCreate:  effectv1.Effect_EFFECT_ALLOW,
+					},
+				},
+			},
+		},
+	}
+
+	plan := &cerbos.PlanResourcesResponse{
+		PlanResourcesResponse: &responsev1.PlanResourcesResponse{
+			RequestId:    "req-2",
+			Action:       actionApprove,
+			ResourceKind: kind,
+			Filter:       &enginev1.PlanResourcesFilter{Kind: enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED},
+		},
+	}
+
+	info := &cerbos.ServerInfo{ServerInfoResponse: &responsev1.ServerInfoResponse{Version: "0.36.0"}}
+
+	testCases := []struct {
+		value json.Marshaler
+		want  string
+	}{
+		{
+			value: check,
+			want:  `{"requestId":"req-1","results":[{"resource":{"id":"XX125","kind":"leave_request"},"actions":{"approve":"EFFECT_DENY","create":"EFFECT_ALLOW"}}]}`,
+		},
+		{
+			value: plan,
+			want:  `{"requestId":"req-2","action":"approve","resourceKind":"leave_request","filter":{"kind":"KIND_ALWAYS_ALLOWED"}}`,
+		},
+		{
+			value: info,
+			want:  `{"version":"0.36.0"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		have, err := json.Marshal(tc.value)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, string(have))
+
+		str, ok := tc.value.(fmt.Stringer)
+		require.True(t, ok)
+		require.JSONEq(t, tc.want, str.String())
+		require.Contains(t, str.String(), "\n  ")
+	}
+}
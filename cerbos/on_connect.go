@@ -0,0 +1,68 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+)
+
+const defaultOnConnectTimeout = 30 * time.Second
+
+// WithOnConnect registers a function to call once the client has connected to the server, for example to log the server
+// version or to warm up caches. When the connection first becomes ready, the client fetches the server information and
+// passes it to the function from a separate goroutine. If the server information can't be fetched, the client tries again
+// the next time the connection becomes ready. The function is called at most once.
+//
+// The function runs in its own goroutine, which Close and Shutdown don't wait for, so it may call Close itself.
+// Close stops waiting for the connection, but a function that has already been started may still be running, or be about
+// to run, after Close returns. It has no effect on clients created with NewGRPCWeb.
+func WithOnConnect(fn func(*ServerInfo)) Opt {
+	return func(c *config) {
+		c.onConnect = fn
+	}
+}
+
+// startOnConnectWatcher runs the callback once the connection is ready and returns a function that stops the watcher.
+func startOnConnectWatcher(c *GRPCClient, fn func(*ServerInfo), timeout time.Duration) func() {
+	if timeout <= 0 {
+		timeout = defaultOnConnectTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		c.conn.Connect()
+		for {
+			state := c.conn.GetState()
+			if state == connectivity.Ready {
+				callCtx, callCancel := context.WithTimeout(ctx, timeout)
+				// The stub is called directly so that Shutdown doesn't wait for the call as if it had been made by the user.
+				resp, err := c.stub.ServerInfo(callCtx, &requestv1.ServerInfoRequest{})
+				callCancel()
+				if err == nil {
+					// The callback runs separately so that calling Close from it doesn't wait for itself.
+					go fn(&ServerInfo{ServerInfoResponse: resp})
+					return
+				}
+			}
+
+			if state == connectivity.Shutdown || !c.conn.WaitForStateChange(ctx, state) {
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
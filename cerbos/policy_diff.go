@@ -0,0 +1,241 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"fmt"
+	"sort"
+
+	policyv1 "github.com/cerbos/cerbos/api/genpb/cerbos/policy/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ChangeType describes how an element differs between two versions of a policy set.
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "ADDED"
+	ChangeRemoved  ChangeType = "REMOVED"
+	ChangeModified ChangeType = "MODIFIED"
+)
+
+// PolicyDiff describes the changes to a single policy.
+// A modified policy with no rule changes indicates that only policy-level settings such as
+// imports, variables or schemas have changed.
+type PolicyDiff struct {
+	PolicyID string
+	Kind     string
+	Change   ChangeType
+	Rules    []RuleDiff
+}
+
+// RuleDiff describes the changes to a single rule of a policy.
+// Rules are identified by their name or, if they are unnamed, by their position in the policy.
+// For derived roles and exported variables, the role or variable name is used as the rule name.
+type RuleDiff struct {
+	Rule           string
+	Change         ChangeType
+	AddedActions   []string
+	RemovedActions []string
+}
+
+// DiffPolicies compares two sets of policies and reports the semantic differences between them.
+// Policies are matched by their ID and compared structurally, so differences in formatting, map ordering,
+// metadata and descriptions are ignored.
+func DiffPolicies(oldPolicies, newPolicies []*policyv1.Policy) ([]PolicyDiff, error) {
+	oldIdx, err := indexPolicies(oldPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid old policies: %w", err)
+	}
+
+	newIdx, err := indexPolicies(newPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid new policies: %w", err)
+	}
+
+	ids := make([]string, 0, len(oldIdx)+len(newIdx))
+	for id := range oldIdx {
+		ids = append(ids, id)
+	}
+	for id := range newIdx {
+		if _, ok := oldIdx[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var diffs []PolicyDiff
+	for _, id := range ids {
+		oldP, newP := oldIdx[id], newIdx[id]
+		switch {
+		case oldP == nil:
+			diffs = append(diffs, PolicyDiff{PolicyID: id, Kind: PolicyKind(newP), Change: ChangeAdded, Rules: diffRules(nil, policyRules(newP))})
+		case newP == nil:
+			diffs = append(diffs, PolicyDiff{PolicyID: id, Kind: PolicyKind(oldP), Change: ChangeRemoved, Rules: diffRules(policyRules(oldP), nil)})
+		default:
+			rules := diffRules(policyRules(oldP), policyRules(newP))
+			if len(rules) > 0 || !proto.Equal(normalisePolicy(oldP), normalisePolicy(newP)) {
+				diffs = append(diffs, PolicyDiff{PolicyID: id, Kind: PolicyKind(newP), Change: ChangeModified, Rules: rules})
+			}
+		}
+	}
+
+	return diffs, nil
+}
+
+func indexPolicies(policies []*policyv1.Policy) (map[string]*policyv1.Policy, error) {
+	idx := make(map[string]*policyv1.Policy, len(policies))
+	for _, p := range policies {
+		id, err := policyID(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := idx[id]; ok {
+			return nil, fmt.Errorf("duplicate policy %q", id)
+		}
+
+		idx[id] = p
+	}
+
+	return idx, nil
+}
+
+func policyID(p *policyv1.Policy) (string, error) {
+	withScope := func(id, scope string) string {
+		if scope == "" {
+			return id
+		}
+		return id + "/" + scope
+	}
+
+	switch pt := p.GetPolicyType().(type) {
+	case *policyv1.Policy_ResourcePolicy:
+		return withScope(fmt.Sprintf("resource.%s.v%s", pt.ResourcePolicy.Resource, pt.ResourcePolicy.Version), pt.ResourcePolicy.Scope), nil
+	case *policyv1.Policy_PrincipalPolicy:
+		return withScope(fmt.Sprintf("principal.%s.v%s", pt.PrincipalPolicy.Principal, pt.PrincipalPolicy.Version), pt.PrincipalPolicy.Scope), nil
+	case *policyv1.Policy_DerivedRoles:
+		return "derived_roles." + pt.DerivedRoles.Name, nil
+	case *policyv1.Policy_ExportVariables:
+		return "export_variables." + pt.ExportVariables.Name, nil
+	default:
+		return "", fmt.Errorf("unsupported policy type %T", pt)
+	}
+}
+
+type ruleEntry struct {
+	def     proto.Message
+	actions []string
+}
+
+// policyRules extracts the rules of the policy keyed by their identifier.
+func policyRules(p *policyv1.Policy) map[string]ruleEntry {
+	rules := make(map[string]ruleEntry)
+	switch pt := normalisePolicy(p).GetPolicyType().(type) {
+	case *policyv1.Policy_ResourcePolicy:
+		for i, r := range pt.ResourcePolicy.Rules {
+			key := r.Name
+			if key == "" {
+				key = fmt.Sprintf("#%d", i)
+			}
+			rules[key] = ruleEntry{def: r, actions: r.Actions}
+		}
+	case *policyv1.Policy_PrincipalPolicy:
+		for _, r := range pt.PrincipalPolicy.Rules {
+			for _, a := range r.Actions {
+				name := a.Name
+				if name == "" {
+					name = a.Action
+				}
+				rules[r.Resource+"/"+name] = ruleEntry{def: a, actions: []string{a.Action}}
+			}
+		}
+	case *policyv1.Policy_DerivedRoles:
+		for _, rd := range pt.DerivedRoles.Definitions {
+			rules[rd.Name] = ruleEntry{def: rd}
+		}
+	case *policyv1.Policy_ExportVariables:
+		for name, expr := range pt.ExportVariables.Definitions {
+			rules[name] = ruleEntry{def: structpb.NewStringValue(expr)}
+		}
+	}
+
+	return rules
+}
+
+func diffRules(oldRules, newRules map[string]ruleEntry) []RuleDiff {
+	keys := make([]string, 0, len(oldRules)+len(newRules))
+	for k := range oldRules {
+		keys = append(keys, k)
+	}
+	for k := range newRules {
+		if _, ok := oldRules[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffs []RuleDiff
+	for _, k := range keys {
+		oldR, inOld := oldRules[k]
+		newR, inNew := newRules[k]
+		switch {
+		case !inOld:
+			diffs = append(diffs, RuleDiff{Rule: k, Change: ChangeAdded, AddedActions: newR.actions})
+		case !inNew:
+			diffs = append(diffs, RuleDiff{Rule: k, Change: ChangeRemoved, RemovedActions: oldR.actions})
+		case !proto.Equal(oldR.def, newR.def):
+			diffs = append(diffs, RuleDiff{
+				Rule:           k,
+				Change:         ChangeModified,
+				AddedActions:   difference(newR.actions, oldR.actions),
+				RemovedActions: difference(oldR.actions, newR.actions),
+			})
+		}
+	}
+
+	return diffs
+}
+
+// difference returns the elements of a that are not in b.
+func difference(a, b []string) []string {
+	exclude := make(map[string]struct{}, len(b))
+	for _, s := range b {
+		exclude[s] = struct{}{}
+	}
+
+	var out []string
+	for _, s := range a {
+		if _, ok := exclude[s]; !ok {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+// normalisePolicy returns a copy of the policy with cosmetic fields cleared and unordered lists sorted.
+func normalisePolicy(p *policyv1.Policy) *policyv1.Policy {
+	np := proto.Clone(p).(*policyv1.Policy) //nolint:forcetypeassert
+	np.Metadata = nil
+	np.Description = ""
+	np.JsonSchema = ""
+
+	switch pt := np.PolicyType.(type) {
+	case *policyv1.Policy_ResourcePolicy:
+		sort.Strings(pt.ResourcePolicy.ImportDerivedRoles)
+		for _, r := range pt.ResourcePolicy.Rules {
+			sort.Strings(r.Actions)
+			sort.Strings(r.Roles)
+			sort.Strings(r.DerivedRoles)
+		}
+	case *policyv1.Policy_DerivedRoles:
+		for _, rd := range pt.DerivedRoles.Definitions {
+			sort.Strings(rd.ParentRoles)
+		}
+	}
+
+	return np
+}
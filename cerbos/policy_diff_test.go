@@ -0,0 +1,91 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	policyv1 "github.com/cerbos/cerbos/api/genpb/cerbos/policy/v1"
+)
+
+func TestDiffPolicies(t *testing.T) {
+	mkPolicies := func(t *testing.T, ps *cerbos.PolicySet) []*policyv1.Policy {
+		t.Helper()
+		require.NoError(t, ps.Validate())
+		return ps.GetPolicies()
+	}
+
+	oldPolicies := mkPolicies(t, cerbos.NewPolicySet().
+		AddResourcePolicies(cerbos.NewResourcePolicy("leave_request", "default").
+			AddResourceRules(
+				cerbos.NewAllowResourceRule("view", "create").WithName("employee").WithRoles("employee", "manager"),
+				cerbos.NewAllowResourceRule("approve").WithName("manager").WithRoles("manager"),
+				cerbos.NewDenyResourceRule("delete").WithName("nobody").WithRoles("employee"),
+			)).
+		AddDerivedRoles(cerbos.NewDerivedRoles("common_roles").AddRole("owner", []string{"user"})).
+		AddExportVariables(cerbos.NewExportVariables("common_vars").AddVariable("foo", "42")))
+
+	newPolicies := mkPolicies(t, cerbos.NewPolicySet().
+		AddResourcePolicies(cerbos.NewResourcePolicy("leave_request", "default").
+			AddResourceRules(
+				// Same actions and roles in a different order: not a change.
+				cerbos.NewAllowResourceRule("create", "view").WithName("employee").WithRoles("manager", "employee"),
+				cerbos.NewAllowResourceRule("approve", "reject").WithName("manager").WithRoles("manager"),
+				cerbos.NewAllowResourceRule("view").WithName("auditor").WithRoles("auditor"),
+			)).
+		AddPrincipalPolicies(cerbos.NewPrincipalPolicy("donald_duck", "default").
+			AddPrincipalRules(cerbos.NewPrincipalRule("leave_request").AllowAction("view"))).
+		AddExportVariables(cerbos.NewExportVariables("common_vars").AddVariable("foo", "43")))
+
+	have, err := cerbos.DiffPolicies(oldPolicies, newPolicies)
+	require.NoError(t, err)
+
+	want := []cerbos.PolicyDiff{
+		{
+			PolicyID: "derived_roles.common_roles",
+			Kind:     cerbos.PolicyKindDerivedRoles,
+			Change:   cerbos.ChangeRemoved,
+			Rules:    []cerbos.RuleDiff{{Rule: "owner", Change: cerbos.ChangeRemoved}},
+		},
+		{
+			PolicyID: "export_variables.common_vars",
+			Kind:     cerbos.PolicyKindExportVariables,
+			Change:   cerbos.ChangeModified,
+			Rules:    []cerbos.RuleDiff{{Rule: "foo", Change: cerbos.ChangeModified}},
+		},
+		{
+			PolicyID: "principal.donald_duck.vdefault",
+			Kind:     cerbos.PolicyKindPrincipal,
+			Change:   cerbos.ChangeAdded,
+			Rules:    []cerbos.RuleDiff{{Rule: "leave_request/view", Change: cerbos.ChangeAdded, AddedActions: []string{"view"}}},
+		},
+		{
+			PolicyID: "resource.leave_request.vdefault",
+			Kind:     cerbos.PolicyKindResource,
+			Change:   cerbos.ChangeModified,
+			Rules: []cerbos.RuleDiff{
+				{Rule: "auditor", Change: cerbos.ChangeAdded, AddedActions: []string{"view"}},
+				{Rule: "manager", Change: cerbos.ChangeModified, AddedActions: []string{"reject"}},
+				{Rule: "nobody", Change: cerbos.ChangeRemoved, RemovedActions: []string{"delete"}},
+			},
+		},
+	}
+	require.Equal(t, want, have)
+
+	t.Run("identical", func(t *testing.T) {
+		have, err := cerbos.DiffPolicies(oldPolicies, oldPolicies)
+		require.NoError(t, err)
+		require.Empty(t, have)
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		_, err := cerbos.DiffPolicies(append(oldPolicies, oldPolicies[0]), newPolicies)
+		require.Error(t, err)
+	})
+}
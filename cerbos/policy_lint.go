@@ -0,0 +1,139 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"fmt"
+	"sort"
+
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	policyv1 "github.com/cerbos/cerbos/api/genpb/cerbos/policy/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const wildcard = "*"
+
+// RuleConflict describes an ALLOW rule and a DENY rule of the same resource policy that apply to the same action
+// for the same principals. Because DENY takes precedence, the ALLOW rule has no effect for the overlapping roles,
+// which often indicates a mistake by the policy author.
+type RuleConflict struct {
+	PolicyID  string
+	Action    string
+	AllowRule string
+	DenyRule  string
+	// Roles are the roles or derived roles that both rules apply to.
+	Roles []string
+}
+
+func (rc RuleConflict) String() string {
+	return fmt.Sprintf("%s: action %q is allowed by rule %s and denied by rule %s for %v", rc.PolicyID, rc.Action, rc.AllowRule, rc.DenyRule, rc.Roles)
+}
+
+// FindConflictingRules reports pairs of resource rules where the same action is both allowed and denied for overlapping roles
+// or derived roles. Rules are identified by their name or, if they are unnamed, by their position in the policy.
+//
+// Conditions are not evaluated. A pair of rules is only reported if neither has a condition or if both have identical conditions,
+// so that every reported conflict is certain. Roles and derived roles are compared by name without resolving derived role definitions.
+// Policies other than resource policies are ignored.
+func FindConflictingRules(policies []*policyv1.Policy) []RuleConflict {
+	var conflicts []RuleConflict
+	for _, p := range policies {
+		rp := p.GetResourcePolicy()
+		if rp == nil {
+			continue
+		}
+
+		id, _ := policyID(p)
+		for i, allow := range rp.Rules {
+			if allow.Effect != effectv1.Effect_EFFECT_ALLOW {
+				continue
+			}
+
+			for j, deny := range rp.Rules {
+				if deny.Effect != effectv1.Effect_EFFECT_DENY || !sameCondition(allow.Condition, deny.Condition) {
+					continue
+				}
+
+				roles := append(overlap(allow.Roles, deny.Roles), overlap(allow.DerivedRoles, deny.DerivedRoles)...)
+				if len(roles) == 0 {
+					continue
+				}
+
+				for _, action := range overlap(allow.Actions, deny.Actions) {
+					conflicts = append(conflicts, RuleConflict{
+						PolicyID:  id,
+						Action:    action,
+						AllowRule: ruleName(allow.Name, i),
+						DenyRule:  ruleName(deny.Name, j),
+						Roles:     roles,
+					})
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+func ruleName(name string, idx int) string {
+	if name == "" {
+		return fmt.Sprintf("#%d", idx)
+	}
+
+	return name
+}
+
+func sameCondition(a, b *policyv1.Condition) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return proto.Equal(a, b)
+}
+
+// overlap returns the sorted values matched by both lists, taking the wildcard into account.
+func overlap(a, b []string) []string {
+	aSet, bSet := stringSet(a), stringSet(b)
+	_, aAll := aSet[wildcard]
+	_, bAll := bSet[wildcard]
+
+	var out []string
+	switch {
+	case aAll && bAll:
+		return []string{wildcard}
+	case aAll:
+		out = append(out, b...)
+	case bAll:
+		out = append(out, a...)
+	default:
+		for v := range aSet {
+			if _, ok := bSet[v]; ok {
+				out = append(out, v)
+			}
+		}
+	}
+
+	sort.Strings(out)
+	return dedup(out)
+}
+
+func stringSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+
+	return set
+}
+
+func dedup(sorted []string) []string {
+	out := sorted[:0]
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,97 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+)
+
+func TestFindConflictingRules(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rules []*cerbos.ResourceRule
+		want  []cerbos.RuleConflict
+	}{
+		{
+			name: "unconditional_conflict",
+			rules: []*cerbos.ResourceRule{
+				cerbos.NewAllowResourceRule("view", "approve").WithName("allow-employee").WithRoles("employee", "manager"),
+				cerbos.NewDenyResourceRule("approve").WithName("deny-employee").WithRoles("employee"),
+			},
+			want: []cerbos.RuleConflict{
+				{PolicyID: "resource.leave_request.vdefault", Action: "approve", AllowRule: "allow-employee", DenyRule: "deny-employee", Roles: []string{"employee"}},
+			},
+		},
+		{
+			name: "wildcards",
+			rules: []*cerbos.ResourceRule{
+				cerbos.NewAllowResourceRule("view").WithRoles("employee"),
+				cerbos.NewDenyResourceRule("*").WithRoles("*"),
+			},
+			want: []cerbos.RuleConflict{
+				{PolicyID: "resource.leave_request.vdefault", Action: "view", AllowRule: "#0", DenyRule: "#1", Roles: []string{"employee"}},
+			},
+		},
+		{
+			name: "derived_roles",
+			rules: []*cerbos.ResourceRule{
+				cerbos.NewAllowResourceRule("view").WithName("allow-owner").WithDerivedRoles("owner"),
+				cerbos.NewDenyResourceRule("view").WithName("deny-owner").WithDerivedRoles("owner"),
+			},
+			want: []cerbos.RuleConflict{
+				{PolicyID: "resource.leave_request.vdefault", Action: "view", AllowRule: "allow-owner", DenyRule: "deny-owner", Roles: []string{"owner"}},
+			},
+		},
+		{
+			name: "identical_conditions",
+			rules: []*cerbos.ResourceRule{
+				cerbos.NewAllowResourceRule("view").WithName("allow").WithRoles("employee").WithCondition(cerbos.MatchExpr("R.attr.public")),
+				cerbos.NewDenyResourceRule("view").WithName("deny").WithRoles("employee").WithCondition(cerbos.MatchExpr("R.attr.public")),
+			},
+			want: []cerbos.RuleConflict{
+				{PolicyID: "resource.leave_request.vdefault", Action: "view", AllowRule: "allow", DenyRule: "deny", Roles: []string{"employee"}},
+			},
+		},
+		{
+			name: "different_conditions",
+			rules: []*cerbos.ResourceRule{
+				cerbos.NewAllowResourceRule("view").WithRoles("employee"),
+				cerbos.NewDenyResourceRule("view").WithRoles("employee").WithCondition(cerbos.MatchExpr("R.attr.status == 'DRAFT'")),
+			},
+		},
+		{
+			name: "different_actions",
+			rules: []*cerbos.ResourceRule{
+				cerbos.NewAllowResourceRule("view").WithRoles("employee"),
+				cerbos.NewDenyResourceRule("delete").WithRoles("employee"),
+			},
+		},
+		{
+			name: "different_roles",
+			rules: []*cerbos.ResourceRule{
+				cerbos.NewAllowResourceRule("view").WithRoles("employee"),
+				cerbos.NewDenyResourceRule("view").WithRoles("contractor").WithDerivedRoles("employee"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ps := cerbos.NewPolicySet().
+				AddResourcePolicies(cerbos.NewResourcePolicy("leave_request", "default").AddResourceRules(tc.rules...)).
+				AddPrincipalPolicies(cerbos.NewPrincipalPolicy("donald_duck", "default").
+					AddPrincipalRules(cerbos.NewPrincipalRule("leave_request").AllowAction("view")))
+			require.NoError(t, ps.Validate())
+
+			require.Equal(t, tc.want, cerbos.FindConflictingRules(ps.GetPolicies()))
+		})
+	}
+}
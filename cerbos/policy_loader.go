@@ -0,0 +1,150 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	policyv1 "github.com/cerbos/cerbos/api/genpb/cerbos/policy/v1"
+	"go.uber.org/multierr"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+const schemaRefPrefix = "cerbos:///"
+
+// LoadPoliciesWithSchemas loads all the policies found under the root directory of policyFS along with the schemas they reference.
+// Schema references must be of the form cerbos:///path/to/schema.json and are resolved relative to the root of schemaFS,
+// which is usually the _schemas directory of the policy repository. The ID of each loaded schema is its path in schemaFS,
+// matching the ID that the Cerbos server expects. Each referenced schema is loaded only once regardless of how many policies refer to it.
+//
+// All problems are reported together in the returned error, including policies that cannot be parsed, references using an
+// unsupported scheme, and references that cannot be resolved to a valid JSON document in schemaFS.
+func LoadPoliciesWithSchemas(policyFS, schemaFS fs.FS, root string) (*PolicySet, *SchemaSet, error) {
+	policies := NewPolicySet()
+	refs := make(map[string][]string)
+
+	err := walkPolicyFiles(policyFS, root, func(p string) {
+		policy, err := internal.ReadPolicyFromFile(policyFS, p)
+		if err != nil {
+			policies.err = multierr.Append(policies.err, fmt.Errorf("failed to load policy from %s: %w", p, err))
+			return
+		}
+
+		policies.AddPolicies(policy)
+		for _, ref := range schemaRefs(policy) {
+			refs[ref] = append(refs[ref], p)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sortedRefs := make([]string, 0, len(refs))
+	for ref := range refs {
+		sortedRefs = append(sortedRefs, ref)
+	}
+	sort.Strings(sortedRefs)
+
+	schemas := NewSchemaSet()
+	for _, ref := range sortedRefs {
+		referrers := strings.Join(refs[ref], ", ")
+
+		path, ok := strings.CutPrefix(ref, schemaRefPrefix)
+		if !ok || path == "" {
+			schemas.err = multierr.Append(schemas.err, fmt.Errorf("unsupported schema reference %q in %s: must start with %s", ref, referrers, schemaRefPrefix))
+			continue
+		}
+
+		schema, err := internal.ReadSchemaFromFile(schemaFS, path)
+		if err != nil {
+			schemas.err = multierr.Append(schemas.err, fmt.Errorf("failed to resolve schema reference %q in %s: %w", ref, referrers, err))
+			continue
+		}
+
+		if !json.Valid(schema.Definition) {
+			schemas.err = multierr.Append(schemas.err, fmt.Errorf("schema referenced as %q in %s is not valid JSON", ref, referrers))
+			continue
+		}
+
+		schemas.AddSchemas(schema)
+	}
+
+	return policies, schemas, multierr.Combine(policies.Err(), schemas.Err())
+}
+
+// LoadPoliciesFromDir loads all the policy files found under the root directory of fsys. Files with .yaml, .yml or .json
+// extensions are loaded, except for hidden files and test suites. Hidden directories, testdata and _schemas directories are skipped.
+// The path of each file is recorded as the source file in the metadata of the policy.
+//
+// Files that can't be read are reported together in the returned error, which names each failing path. The policies that were
+// loaded successfully are returned even if some files failed.
+func LoadPoliciesFromDir(fsys fs.FS, root string) ([]*policyv1.Policy, error) {
+	var policies []*policyv1.Policy
+	var errs error
+
+	err := walkPolicyFiles(fsys, root, func(p string) {
+		policy, err := internal.ReadPolicyFromFile(fsys, p)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to load policy from %s: %w", p, err))
+			return
+		}
+
+		policies = append(policies, policy)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return policies, errs
+}
+
+// walkPolicyFiles calls fn with the path of each policy file found under the root directory of fsys.
+func walkPolicyFiles(fsys fs.FS, root string, fn func(string)) error {
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if p != root && internal.SkipPolicyDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if internal.IsPolicyFile(p) {
+			fn(p)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+
+	return nil
+}
+
+func schemaRefs(p *policyv1.Policy) []string {
+	rp := p.GetResourcePolicy()
+	if rp == nil || rp.Schemas == nil {
+		return nil
+	}
+
+	var refs []string
+	if ref := rp.Schemas.GetPrincipalSchema().GetRef(); ref != "" {
+		refs = append(refs, ref)
+	}
+
+	if ref := rp.Schemas.GetResourceSchema().GetRef(); ref != "" {
+		refs = append(refs, ref)
+	}
+
+	return refs
+}
@@ -0,0 +1,111 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	"github.com/cerbos/cerbos-sdk-go/internal/tests"
+)
+
+const schemaRefPolicy = `---
+apiVersion: api.cerbos.dev/v1
+resourcePolicy:
+  resource: leave_request
+  version: default
+  schemas:
+    principalSchema:
+      ref: cerbos:///principal.json
+    resourceSchema:
+      ref: %s
+  rules:
+    - actions: ["view"]
+      effect: EFFECT_ALLOW
+      roles: ["employee"]
+`
+
+func TestLoadPoliciesWithSchemas(t *testing.T) {
+	t.Run("testdata", func(t *testing.T) {
+		dir := tests.PathToTestDataDir(t, "policies")
+		policies, schemas, err := cerbos.LoadPoliciesWithSchemas(os.DirFS(dir), os.DirFS(filepath.Join(dir, "_schemas")), ".")
+		require.NoError(t, err)
+		require.NotZero(t, policies.Size())
+
+		ids := make([]string, 0, schemas.Size())
+		for _, s := range schemas.GetSchemas() {
+			ids = append(ids, s.Id)
+		}
+		sort.Strings(ids)
+		require.Equal(t, []string{"principal.json", "resources/leave_request.json", "resources/purchase_order.json"}, ids)
+	})
+
+	schemaFS := fstest.MapFS{
+		"principal.json": {Data: []byte(`{"type": "object"}`)},
+		"invalid.json":   {Data: []byte(`{"type":`)},
+	}
+
+	testCases := []struct {
+		name    string
+		ref     string
+		wantErr string
+	}{
+		{name: "missing", ref: "cerbos:///resources/missing.json", wantErr: `failed to resolve schema reference "cerbos:///resources/missing.json" in policies/policy.yaml`},
+		{name: "invalid_json", ref: "cerbos:///invalid.json", wantErr: "is not valid JSON"},
+		{name: "unsupported_scheme", ref: "https://example.com/schema.json", wantErr: "unsupported schema reference"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policyFS := fstest.MapFS{
+				"policies/policy.yaml": {Data: []byte(fmt.Sprintf(schemaRefPolicy, tc.ref))},
+			}
+
+			policies, schemas, err := cerbos.LoadPoliciesWithSchemas(policyFS, schemaFS, "policies")
+			require.ErrorContains(t, err, tc.wantErr)
+			require.Equal(t, 1, policies.Size())
+			require.Equal(t, 1, schemas.Size())
+		})
+	}
+}
+
+func TestLoadPoliciesFromDir(t *testing.T) {
+	policy := fmt.Sprintf(schemaRefPolicy, "cerbos:///resource.json")
+	policyFS := fstest.MapFS{
+		"policies/leave_request.yaml":               {Data: []byte(policy)},
+		"policies/nested/leave_request.yml":         {Data: []byte(policy)},
+		"policies/nested/broken.json":               {Data: []byte(`{"apiVersion":`)},
+		"policies/.hidden.yaml":                     {Data: []byte("not a policy")},
+		"policies/_schemas/resource.json":           {Data: []byte(`{"type": "object"}`)},
+		"policies/tests/leave_request_test.yaml":    {Data: []byte("not a policy")},
+		"policies/testdata/leave_request.yaml":      {Data: []byte("not a policy")},
+		"policies/nested/unrelated.txt":             {Data: []byte("not a policy")},
+		"policies/nested/.git/leave_request.yaml":   {Data: []byte("not a policy")},
+		"policies/nested/invalid_leave_request.yml": {Data: []byte("apiVersion: api.cerbos.dev/v1\nfoo: bar\n")},
+	}
+
+	policies, err := cerbos.LoadPoliciesFromDir(policyFS, "policies")
+	require.ErrorContains(t, err, "policies/nested/broken.json")
+	require.ErrorContains(t, err, "policies/nested/invalid_leave_request.yml")
+	require.Len(t, multierr.Errors(err), 2)
+
+	sources := make([]string, len(policies))
+	for i, p := range policies {
+		sources[i] = p.GetMetadata().GetSourceFile()
+	}
+	require.ElementsMatch(t, []string{"policies/leave_request.yaml", "policies/nested/leave_request.yml"}, sources)
+
+	_, err = cerbos.LoadPoliciesFromDir(policyFS, "missing")
+	require.Error(t, err)
+}
@@ -0,0 +1,59 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// requestIDHistorySize is the number of recent request IDs remembered for detecting reuse.
+const requestIDHistorySize = 4096
+
+// requestIDTracker decorates request IDs with a process-wide sequence number and detects reuse of recent IDs.
+type requestIDTracker struct {
+	logger  Logger
+	seen    map[string]struct{}
+	history []string
+	seq     atomic.Uint64
+	next    int
+	mu      sync.Mutex
+	counter bool
+}
+
+func newRequestIDTracker(logger Logger, counter bool) *requestIDTracker {
+	t := &requestIDTracker{logger: logger, counter: counter}
+	if logger != nil {
+		t.seen = make(map[string]struct{}, requestIDHistorySize)
+		t.history = make([]string, requestIDHistorySize)
+	}
+
+	return t
+}
+
+func (t *requestIDTracker) track(id string) string {
+	if t.counter {
+		id = id + "-" + strconv.FormatUint(t.seq.Add(1), 10)
+	}
+
+	if t.logger == nil {
+		return id
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.seen[id]; ok {
+		t.logger.Warn("Request ID reused by unrelated requests", "request_id", id)
+		return id
+	}
+
+	delete(t.seen, t.history[t.next])
+	t.history[t.next] = id
+	t.seen[id] = struct{}{}
+	t.next = (t.next + 1) % requestIDHistorySize
+
+	return id
+}
@@ -0,0 +1,117 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+type recordingLogger struct {
+	warnings []string
+	mu       sync.Mutex
+}
+
+func (*recordingLogger) Debug(string, ...any) {}
+
+func (l *recordingLogger) Warn(msg string, _ ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, msg)
+}
+
+func (*recordingLogger) Error(string, ...any) {}
+
+func (l *recordingLogger) Warnings() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.warnings
+}
+
+func TestRequestIDTracking(t *testing.T) {
+	requestIDs := make(chan string, 2)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			requestIDs <- req.RequestId
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	fixedID := cerbos.RequestIDGenerator(func(context.Context) string { return "fixed" })
+	principal := cerbos.NewPrincipal("john", "employee")
+	batch := cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view")
+
+	t.Run("reuse_detection", func(t *testing.T) {
+		logger := &recordingLogger{}
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithRequestIDReuseDetection(logger))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		for i := 0; i < 2; i++ {
+			_, err = c.With(fixedID).CheckResources(context.Background(), principal, batch)
+			require.NoError(t, err)
+			require.Equal(t, "fixed", <-requestIDs)
+		}
+		require.Len(t, logger.Warnings(), 1)
+
+		_, err = c.CheckResources(context.Background(), principal, batch)
+		require.NoError(t, err)
+		<-requestIDs
+		require.Len(t, logger.Warnings(), 1, "Generated request IDs should be unique")
+	})
+
+	t.Run("counter", func(t *testing.T) {
+		logger := &recordingLogger{}
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithRequestIDCounter(), cerbos.WithRequestIDReuseDetection(logger))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		for _, want := range []string{"fixed-1", "fixed-2"} {
+			_, err = c.With(fixedID).CheckResources(context.Background(), principal, batch)
+			require.NoError(t, err)
+			require.Equal(t, want, <-requestIDs)
+		}
+		require.Empty(t, logger.Warnings())
+	})
+}
+
+func TestRequestIDGenerator(t *testing.T) {
+	requestIDs := make(chan string, 1)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(_ context.Context, req *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			requestIDs <- req.RequestId
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	type correlationIDKey struct{}
+	withCorrelationID := c.With(cerbos.RequestIDGenerator(func(ctx context.Context) string {
+		id, _ := ctx.Value(correlationIDKey{}).(string)
+		return id
+	}))
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	batch := cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view")
+
+	_, err = withCorrelationID.CheckResources(context.WithValue(context.Background(), correlationIDKey{}, "corr-1"), principal, batch)
+	require.NoError(t, err)
+	require.Equal(t, "corr-1", <-requestIDs)
+
+	_, err = withCorrelationID.CheckResources(context.Background(), principal, batch)
+	require.NoError(t, err)
+	require.NotEmpty(t, <-requestIDs, "A request ID should be generated when the generator returns an empty string")
+}
@@ -5,6 +5,7 @@ package cerbos
 
 import (
 	"context"
+	"time"
 
 	"google.golang.org/grpc/metadata"
 
@@ -31,6 +32,16 @@ func AuxDataJWT(token, keySetID string) RequestOpt {
 	}
 }
 
+// AuxDataJWTFromContext sets the JWT to be used as auxiliary data for the request to the string stored in the context of the call
+// under the given key, for example by middleware that authenticated the incoming request. The key must be comparable, as for context.WithValue.
+// If the context doesn't hold a non-empty string under the key, the request is sent without a JWT, or with the default set using WithDefaultAuxData.
+// A JWT set using AuxDataJWT takes precedence.
+func AuxDataJWTFromContext(ctxKey any) RequestOpt {
+	return func(opts *internal.ReqOpt) {
+		opts.AuxDataJWTCtxKey = ctxKey
+	}
+}
+
 // IncludeMeta sets the flag on requests that support it to signal that evaluation metadata should be sent back with the response.
 func IncludeMeta(f bool) RequestOpt {
 	return func(opt *internal.ReqOpt) {
@@ -40,16 +51,71 @@ func IncludeMeta(f bool) RequestOpt {
 
 // Headers sets the gRPC header metadata for each request.
 // Input should be a list of key-value pairs.
+// The metadata is merged with any outgoing metadata attached to the context of the call, replacing the values of the same keys.
 func Headers(keyValues ...string) RequestOpt {
 	return func(opt *internal.ReqOpt) {
 		opt.Metadata = metadata.Pairs(keyValues...)
 	}
 }
 
-// RequestIDGenerator is invoked on every request to generate a request ID.
-// If not defined, a random request ID is generated by the SDK client.
+// Metadata adds the given gRPC header metadata to each request, for example to propagate a tenant ID or tracing baggage
+// read by interceptors in front of the server. Unlike Headers, it adds to the metadata set by previous options instead of replacing it.
+// The metadata is merged with any outgoing metadata attached to the context of the call, replacing the values of the same keys.
+func Metadata(md map[string]string) RequestOpt {
+	return func(opt *internal.ReqOpt) {
+		if opt.Metadata == nil {
+			opt.Metadata = metadata.New(nil)
+		} else {
+			opt.Metadata = opt.Metadata.Copy()
+		}
+
+		for k, v := range md {
+			opt.Metadata.Set(k, v)
+		}
+	}
+}
+
+// RequestIDGenerator is invoked on every request to generate a request ID. Use it to propagate an existing correlation ID,
+// such as one set in the context by HTTP middleware, so that decisions can be correlated with the rest of the request.
+// If not defined, or if the generator returns an empty string, a random request ID is generated by the SDK client.
 func RequestIDGenerator(generator func(context.Context) string) RequestOpt {
 	return func(opt *internal.ReqOpt) {
 		opt.RequestIDGenerator = generator
 	}
 }
+
+// PolicyVersion sets the policy version to use for resources in the request that don't specify one.
+// It overrides the client-wide default set using WithPolicyVersion.
+func PolicyVersion(version string) RequestOpt {
+	return func(opt *internal.ReqOpt) {
+		opt.PolicyVersion = version
+	}
+}
+
+// DefaultAction sets the action to use for PlanResources calls made without an explicit action.
+func DefaultAction(action string) RequestOpt {
+	return func(opt *internal.ReqOpt) {
+		opt.DefaultAction = action
+	}
+}
+
+// DetachedContext makes calls run independently of the cancellation and deadline of the context passed by the caller,
+// with the given timeout instead. Values and outgoing metadata attached to the caller's context are preserved.
+// It's intended for calls that must outlive the request that triggered them, such as warming caches in the background.
+//
+// Detached calls are not stopped when the caller gives up on them, so issuing them from goroutines without bounding
+// their number can cause goroutines to pile up when the server is slow. The timeout must be positive; otherwise the option has no effect.
+func DetachedContext(timeout time.Duration) RequestOpt {
+	return func(opt *internal.ReqOpt) {
+		opt.DetachedTimeout = timeout
+	}
+}
+
+// StrictValidation makes CheckResources and IsAllowed calls fail with an error wrapping ErrSchemaValidationFailed
+// if the server reports schema validation errors for any of the resources or the principal, instead of returning
+// the outcome of the checks. Schema validation errors are only reported if schema enforcement is enabled on the server.
+func StrictValidation() RequestOpt {
+	return func(opt *internal.ReqOpt) {
+		opt.StrictValidation = true
+	}
+}
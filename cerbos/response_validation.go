@@ -0,0 +1,105 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+// ResponseValidationError is returned by clients configured with WithResponseValidation when a response from the server
+// is malformed.
+type ResponseValidationError struct {
+	Err    error
+	Method string
+}
+
+func (e *ResponseValidationError) Error() string {
+	return fmt.Sprintf("invalid response from %s: %v", e.Method, e.Err)
+}
+
+func (e *ResponseValidationError) Unwrap() error {
+	return e.Err
+}
+
+// WithResponseValidation checks the structure of CheckResources and PlanResources responses before returning them,
+// and fails the call with a ResponseValidationError if they are malformed. For example, a CheckResources response must
+// contain exactly one result for each resource in the request, and every result must identify its resource.
+// It's a safeguard against misbehaving intermediaries such as proxies, which can otherwise cause confusing errors
+// when the response is used.
+func WithResponseValidation() Opt {
+	return func(c *config) {
+		c.responseValidation = true
+	}
+}
+
+func responseValidationInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			return err
+		}
+
+		var err error
+		switch resp := reply.(type) {
+		case *responsev1.CheckResourcesResponse:
+			r, _ := req.(*requestv1.CheckResourcesRequest)
+			err = validateCheckResourcesResponse(r, resp)
+		case *responsev1.PlanResourcesResponse:
+			err = validatePlanResourcesResponse(resp)
+		}
+
+		if err != nil {
+			return &ResponseValidationError{Method: method, Err: err}
+		}
+
+		return nil
+	}
+}
+
+func validateCheckResourcesResponse(req *requestv1.CheckResourcesRequest, resp *responsev1.CheckResourcesResponse) error {
+	if req != nil && len(resp.Results) != len(req.Resources) {
+		return fmt.Errorf("expected %d results, got %d", len(req.Resources), len(resp.Results))
+	}
+
+	for i, result := range resp.Results {
+		if result.GetResource().GetId() == "" || result.GetResource().GetKind() == "" {
+			return fmt.Errorf("result #%d does not identify a resource", i)
+		}
+
+		for action, effect := range result.Actions {
+			if effect != effectv1.Effect_EFFECT_ALLOW && effect != effectv1.Effect_EFFECT_DENY {
+				return fmt.Errorf("result #%d has unexpected effect %s for action %q", i, effect, action)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validatePlanResourcesResponse(resp *responsev1.PlanResourcesResponse) error {
+	filter := resp.GetFilter()
+	if filter == nil {
+		return errors.New("missing filter")
+	}
+
+	switch filter.Kind {
+	case enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED, enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED:
+		return nil
+	case enginev1.PlanResourcesFilter_KIND_CONDITIONAL:
+		if filter.Condition == nil {
+			return errors.New("conditional filter has no condition")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unexpected filter kind %s", filter.Kind)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	retryAfterKey = "retry-after"
+
+	defaultRetryBackoff = 50 * time.Millisecond
+	retryBackoffJitter  = 0.10
+)
+
+type retryHintKey struct{}
+
+// retryHint holds the delay requested by the server before the next attempt of a call.
+type retryHint struct {
+	delay time.Duration
+	mu    sync.Mutex
+}
+
+func (rh *retryHint) set(delay time.Duration) {
+	rh.mu.Lock()
+	rh.delay = delay
+	rh.mu.Unlock()
+}
+
+func (rh *retryHint) take() time.Duration {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+
+	delay := rh.delay
+	rh.delay = 0
+	return delay
+}
+
+// mkRetryInterceptors returns the interceptors that retry failed calls, honouring retry-after hints sent by the server in
+// trailing metadata and the retry budget, if there's one. It returns three interceptors that must be chained together in order:
+// the first sets up the per-call hint and budget state and settles the budget once the call completes, the second is the
+// retry interceptor itself, and the third runs on every attempt to read the hint from the trailer and stop the call when the
+// budget is exhausted. Interceptors that should see each attempt go after the third one.
+func mkRetryInterceptors(conf *config) []grpc.UnaryClientInterceptor {
+	defaultBackoff := conf.retryBackoff
+	if defaultBackoff == nil {
+		defaultBackoff = grpc_retry.BackoffLinearWithJitter(defaultRetryBackoff, retryBackoffJitter)
+	}
+
+	var budget *retryBudget
+	if conf.retryBudget != nil {
+		budget = newRetryBudget(conf.retryBudget)
+	}
+
+	backoff := func(ctx context.Context, attempt uint) time.Duration {
+		// The backoff is only consulted when a call is about to be retried, which makes it the place to spend the budget.
+		if call, ok := ctx.Value(retryBudgetCallKey{}).(*retryBudgetCall); ok && !budget.withdraw() {
+			call.deny()
+			return 0
+		}
+
+		if hint, ok := ctx.Value(retryHintKey{}).(*retryHint); ok {
+			if delay := hint.take(); delay > 0 {
+				return delay
+			}
+		}
+
+		return defaultBackoff(ctx, attempt)
+	}
+
+	return []grpc.UnaryClientInterceptor{
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			ctx = context.WithValue(ctx, retryHintKey{}, &retryHint{})
+			if budget == nil {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+
+			callCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			call := &retryBudgetCall{cancel: cancel}
+			err := invoker(context.WithValue(callCtx, retryBudgetCallKey{}, call), method, req, reply, cc, opts...)
+			if err == nil {
+				budget.deposit()
+				return nil
+			}
+
+			if denied, lastErr := call.result(); denied && ctx.Err() == nil {
+				return fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, lastErr)
+			}
+
+			return err
+		},
+		grpc_retry.UnaryClientInterceptor(mkRetryCallOpts(conf, backoff)...),
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			call, hasBudget := ctx.Value(retryBudgetCallKey{}).(*retryBudgetCall)
+			if hasBudget {
+				if denied, _ := call.result(); denied {
+					// Returning a context error makes the retry interceptor give up without further attempts.
+					return status.FromContextError(ctx.Err()).Err()
+				}
+			}
+
+			var trailer metadata.MD
+			err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+			if hasBudget {
+				call.done(err)
+			}
+
+			if err != nil {
+				if hint, ok := ctx.Value(retryHintKey{}).(*retryHint); ok {
+					hint.set(parseRetryAfter(trailer.Get(retryAfterKey)))
+				}
+			}
+
+			return err
+		},
+	}
+}
+
+// mkRetryCallOpts returns the options for the retry interceptors. If backoff is nil, the default backoff of the interceptors is used.
+func mkRetryCallOpts(conf *config, backoff grpc_retry.BackoffFunc) []grpc_retry.CallOption {
+	opts := []grpc_retry.CallOption{
+		grpc_retry.WithMax(conf.maxRetries),
+		grpc_retry.WithPerRetryTimeout(conf.retryTimeout),
+	}
+
+	if backoff != nil {
+		opts = append(opts, grpc_retry.WithBackoff(backoff))
+	}
+
+	if len(conf.retryCodes) > 0 {
+		opts = append(opts, grpc_retry.WithCodes(conf.retryCodes...))
+	}
+
+	return opts
+}
+
+// parseRetryAfter parses a retry-after value given either as a number of seconds or as a Go duration string.
+func parseRetryAfter(values []string) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+
+	if secs, err := strconv.ParseFloat(values[0], 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+
+	if d, err := time.ParseDuration(values[0]); err == nil && d > 0 {
+		return d
+	}
+
+	return 0
+}
@@ -0,0 +1,116 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrRetryBudgetExhausted is returned when a call fails and can't be retried because the budget set using WithRetryBudget
+// has been used up. The error returned by the last attempt is wrapped alongside it.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// WithRetryBudget caps the retries made by the client as a fraction of its successful calls, so that an outage of the server
+// isn't made worse by every failed call being retried. It works like the retry throttling of gRPC: the client holds a
+// budget of minRetries tokens, every retry spends one token and every successful call earns ratio tokens back, up to the
+// initial budget. For example, a ratio of 0.1 allows one retry for every ten successful calls once the budget has been spent.
+// When there are no tokens left, calls fail immediately with an error wrapping both ErrRetryBudgetExhausted and the error
+// returned by the last attempt instead of being retried. The budget is shared by all calls made by the client.
+func WithRetryBudget(ratio float64, minRetries int) Opt {
+	return func(c *config) {
+		c.retryBudget = &retryBudgetConf{ratio: ratio, minRetries: minRetries}
+	}
+}
+
+type retryBudgetConf struct {
+	ratio      float64
+	minRetries int
+}
+
+func (rbc *retryBudgetConf) validate() error {
+	if rbc.ratio <= 0 {
+		return fmt.Errorf("retry budget ratio must be greater than 0: %v", rbc.ratio)
+	}
+
+	if rbc.minRetries < 1 {
+		return fmt.Errorf("retry budget must allow at least 1 retry: %d", rbc.minRetries)
+	}
+
+	return nil
+}
+
+// retryBudget is a token bucket that limits the number of retries made by a client.
+type retryBudget struct {
+	ratio     float64
+	maxTokens float64
+	tokens    float64
+	mu        sync.Mutex
+}
+
+func newRetryBudget(conf *retryBudgetConf) *retryBudget {
+	return &retryBudget{
+		ratio:     conf.ratio,
+		maxTokens: float64(conf.minRetries),
+		tokens:    float64(conf.minRetries),
+	}
+}
+
+// withdraw spends a token for a retry and returns false if there are none left.
+func (rb *retryBudget) withdraw() bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if rb.tokens < 1 {
+		return false
+	}
+
+	rb.tokens--
+	return true
+}
+
+// deposit earns tokens for a successful call.
+func (rb *retryBudget) deposit() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.tokens += rb.ratio
+	if rb.tokens > rb.maxTokens {
+		rb.tokens = rb.maxTokens
+	}
+}
+
+type retryBudgetCallKey struct{}
+
+// retryBudgetCall tracks whether a single logical call was denied a retry.
+type retryBudgetCall struct {
+	lastErr error
+	cancel  context.CancelFunc
+	denied  bool
+	mu      sync.Mutex
+}
+
+// deny stops the call because the budget doesn't allow another retry.
+func (rbc *retryBudgetCall) deny() {
+	rbc.mu.Lock()
+	rbc.denied = true
+	rbc.mu.Unlock()
+
+	rbc.cancel()
+}
+
+func (rbc *retryBudgetCall) done(err error) {
+	rbc.mu.Lock()
+	rbc.lastErr = err
+	rbc.mu.Unlock()
+}
+
+func (rbc *retryBudgetCall) result() (bool, error) {
+	rbc.mu.Lock()
+	defer rbc.mu.Unlock()
+
+	return rbc.denied, rbc.lastErr
+}
@@ -0,0 +1,226 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+func TestRetryAfter(t *testing.T) {
+	const retryAfter = 300 * time.Millisecond
+
+	var calls atomic.Int32
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(ctx context.Context, _ *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			if calls.Add(1) == 1 {
+				_ = grpc.SetTrailer(ctx, metadata.Pairs("retry-after", "0.3"))
+				return nil, status.Error(codes.ResourceExhausted, "slow down")
+			}
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	start := time.Now()
+	_, err = c.CheckResources(context.Background(), cerbos.NewPrincipal("john", "employee"), cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view"))
+	require.NoError(t, err)
+	require.EqualValues(t, 2, calls.Load())
+	require.GreaterOrEqual(t, time.Since(start), retryAfter, "Retry happened before the delay requested by the server")
+}
+
+func TestRetryableStatusCodes(t *testing.T) {
+	testCases := []struct {
+		name      string
+		opts      []cerbos.Opt
+		code      codes.Code
+		wantCalls int32
+	}{
+		{name: "default_retryable", code: codes.Unavailable, wantCalls: 3},
+		{name: "default_not_retryable", code: codes.Internal, wantCalls: 1},
+		{name: "custom_retryable", code: codes.Internal, opts: []cerbos.Opt{cerbos.WithRetryableStatusCodes(codes.Internal)}, wantCalls: 3},
+		{name: "custom_not_retryable", code: codes.Unavailable, opts: []cerbos.Opt{cerbos.WithRetryableStatusCodes(codes.Internal)}, wantCalls: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			addr := startFakeServer(t, &fakeServer{
+				checkResources: func(context.Context, *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+					calls.Add(1)
+					return nil, status.Error(tc.code, "boom")
+				},
+			})
+
+			c, err := cerbos.New(addr, append([]cerbos.Opt{cerbos.WithPlaintext(), cerbos.WithMaxRetries(3)}, tc.opts...)...)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = c.Close() })
+
+			_, err = c.IsAllowed(context.Background(), cerbos.NewPrincipal("john", "employee"), cerbos.NewResource("leave_request", "XX125"), "view")
+			require.Equal(t, tc.code, status.Code(err))
+			require.Equal(t, tc.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var calls atomic.Int32
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(context.Context, *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			if calls.Add(1) < 3 {
+				return nil, status.Error(codes.Unavailable, "boom")
+			}
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	attempts := make(chan uint, 2)
+	backoff := func(_ context.Context, attempt uint) time.Duration {
+		attempts <- attempt
+		return time.Millisecond
+	}
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(3), cerbos.WithRetryBackoff(backoff))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	_, err = c.CheckResources(context.Background(), cerbos.NewPrincipal("john", "employee"), cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view"))
+	require.NoError(t, err)
+	require.EqualValues(t, 3, calls.Load())
+	require.Equal(t, uint(1), <-attempts)
+	require.Equal(t, uint(2), <-attempts)
+}
+
+func TestMaxTotalAttempts(t *testing.T) {
+	testCases := []struct {
+		name      string
+		opts      []cerbos.Opt
+		code      codes.Code
+		wantCalls int32
+	}{
+		{
+			name:      "retries",
+			code:      codes.Unavailable,
+			opts:      []cerbos.Opt{cerbos.WithMaxRetries(5), cerbos.WithMaxTotalAttempts(2)},
+			wantCalls: 2,
+		},
+		{
+			name: "credential_refresh",
+			code: codes.Unauthenticated,
+			opts: []cerbos.Opt{
+				cerbos.WithMaxTotalAttempts(1),
+				cerbos.WithCredentialRefresh(func(context.Context) error { return nil }),
+			},
+			wantCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			addr := startFakeServer(t, &fakeServer{
+				checkResources: func(context.Context, *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+					calls.Add(1)
+					return nil, status.Error(tc.code, "boom")
+				},
+			})
+
+			c, err := cerbos.New(addr, append([]cerbos.Opt{cerbos.WithPlaintext()}, tc.opts...)...)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = c.Close() })
+
+			_, err = c.IsAllowed(context.Background(), cerbos.NewPrincipal("john", "employee"), cerbos.NewResource("leave_request", "XX125"), "view")
+			require.ErrorIs(t, err, cerbos.ErrMaxAttemptsExceeded)
+			require.Equal(t, tc.code, status.Code(err))
+			require.ErrorContains(t, err, "boom")
+			require.Equal(t, tc.wantCalls, calls.Load())
+		})
+	}
+
+	t.Run("success_within_limit", func(t *testing.T) {
+		var calls atomic.Int32
+		addr := startFakeServer(t, &fakeServer{
+			checkResources: func(context.Context, *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+				if calls.Add(1) == 1 {
+					return nil, status.Error(codes.Unavailable, "boom")
+				}
+				return &responsev1.CheckResourcesResponse{}, nil
+			},
+		})
+
+		c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(5), cerbos.WithMaxTotalAttempts(2))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.CheckResources(context.Background(), cerbos.NewPrincipal("john", "employee"), cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view"))
+		require.NoError(t, err)
+		require.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestRetryBudget(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	addr := startFakeServer(t, &fakeServer{
+		checkResources: func(context.Context, *requestv1.CheckResourcesRequest) (*responsev1.CheckResourcesResponse, error) {
+			calls.Add(1)
+			if failing.Load() {
+				return nil, status.Error(codes.Unavailable, "boom")
+			}
+			return &responsev1.CheckResourcesResponse{}, nil
+		},
+	})
+
+	c, err := cerbos.New(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(5), cerbos.WithRetryBudget(0.5, 2))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	check := func() error {
+		_, err := c.CheckResources(context.Background(), cerbos.NewPrincipal("john", "employee"), cerbos.NewResourceBatch().Add(cerbos.NewResource("leave_request", "XX125"), "view"))
+		return err
+	}
+
+	t.Run("spends_budget", func(t *testing.T) {
+		calls.Store(0)
+		err := check()
+		require.ErrorIs(t, err, cerbos.ErrRetryBudgetExhausted)
+		require.Equal(t, codes.Unavailable, status.Code(err))
+		require.EqualValues(t, 3, calls.Load())
+	})
+
+	t.Run("fails_fast", func(t *testing.T) {
+		calls.Store(0)
+		require.ErrorIs(t, check(), cerbos.ErrRetryBudgetExhausted)
+		require.EqualValues(t, 1, calls.Load())
+	})
+
+	t.Run("earns_budget", func(t *testing.T) {
+		failing.Store(false)
+		require.NoError(t, check())
+		require.NoError(t, check())
+
+		failing.Store(true)
+		calls.Store(0)
+		require.ErrorIs(t, check(), cerbos.ErrRetryBudgetExhausted)
+		require.EqualValues(t, 2, calls.Load())
+	})
+}
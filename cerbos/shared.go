@@ -0,0 +1,139 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
+)
+
+var sharedClients = &clientRegistry{entries: make(map[string]*sharedEntry)}
+
+// SharedClient returns a client for the given address that shares its underlying connection with other clients
+// created with the same address and options. Connections are reference counted: calling Close on a shared client
+// releases its reference and the connection is only closed when the last reference is released.
+//
+// Options are compared by value. Options that take functions or interfaces can't be compared, so they disable sharing:
+// when any of WithStatsHandler, WithStreamInterceptors, WithUnaryInterceptors, WithCredentialRefresh, WithRetryBackoff,
+// WithBearerToken, WithTokenSource, WithTracing, WithMetrics, WithContextDecorator, WithPanicRecovery, WithOnConnect,
+// WithCompatibilityCheck, WithRequestIDReuseDetection or WithLogger is used, a new client that doesn't share its connection is returned.
+func SharedClient(address string, opts ...Opt) (*GRPCClient, error) {
+	return sharedClients.acquire(address, opts...)
+}
+
+// CloseSharedClients forcibly closes all connections created by SharedClient regardless of outstanding references.
+// Clients obtained from SharedClient before this call must not be used afterwards.
+func CloseSharedClients() error {
+	return sharedClients.closeAll()
+}
+
+type sharedEntry struct {
+	client *GRPCClient
+	refs   int
+}
+
+type clientRegistry struct {
+	entries map[string]*sharedEntry
+	mu      sync.Mutex
+}
+
+func (cr *clientRegistry) acquire(address string, opts ...Opt) (*GRPCClient, error) {
+	key, ok := sharedClientKey(mkConfig(address, opts...))
+	if !ok {
+		return New(address, opts...)
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	entry, ok := cr.entries[key]
+	if !ok {
+		c, err := New(address, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		entry = &sharedEntry{client: c}
+		cr.entries[key] = entry
+	}
+
+	entry.refs++
+
+	var once sync.Once
+	handle := *entry.client
+	handle.lifecycle = newLifecycle()
+	handle.closeFn = func() (err error) {
+		once.Do(func() { err = cr.release(key, entry) })
+		return err
+	}
+
+	return &handle, nil
+}
+
+func (cr *clientRegistry) release(key string, entry *sharedEntry) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	// the entry could have been force closed and replaced by a new one in the meantime
+	if cr.entries[key] != entry {
+		return nil
+	}
+
+	entry.refs--
+	if entry.refs > 0 {
+		return nil
+	}
+
+	delete(cr.entries, key)
+	return entry.client.Close()
+}
+
+func (cr *clientRegistry) closeAll() (err error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	for key, entry := range cr.entries {
+		err = multierr.Append(err, entry.client.Close())
+		delete(cr.entries, key)
+	}
+
+	return err
+}
+
+// sharedClientKey returns a key identifying the connection described by the config, or false if the config
+// has options that can't be compared.
+func sharedClientKey(conf *config) (string, bool) {
+	if conf.statsHandler != nil || len(conf.streamInterceptors) > 0 || len(conf.unaryInterceptors) > 0 ||
+		conf.credentialRefresh != nil || conf.retryBackoff != nil || conf.tokenSource != nil || conf.tracerProvider != nil ||
+		conf.metricsRegisterer != nil || conf.ctxDecorator != nil || conf.panicHandler != nil || conf.onConnect != nil ||
+		conf.compatLogger != nil || conf.reqIDLogger != nil || conf.logger != nil {
+		return "", false
+	}
+
+	// Pointers are replaced with the values they point to so that equal options produce equal keys.
+	key := *conf
+	key.defaultAuxData, key.batchConf, key.adaptiveTimeout, key.retryBudget, key.keepAlive, key.maxConnIdleTime = nil, nil, nil, nil, nil, nil
+
+	var auxData []byte
+	if conf.defaultAuxData != nil {
+		var err error
+		if auxData, err = (proto.MarshalOptions{Deterministic: true}).Marshal(conf.defaultAuxData); err != nil {
+			return "", false
+		}
+	}
+
+	return fmt.Sprintf("%+v|%x|%+v|%+v|%+v|%+v|%v", key, auxData,
+		deref(conf.batchConf), deref(conf.adaptiveTimeout), deref(conf.retryBudget), deref(conf.keepAlive), deref(conf.maxConnIdleTime)), true
+}
+
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+
+	return *p
+}
@@ -0,0 +1,105 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+func TestSharedClient(t *testing.T) {
+	t.Cleanup(func() { _ = cerbos.CloseSharedClients() })
+
+	const addr = "passthrough:///127.0.0.1:1"
+
+	isClosed := func(t *testing.T, c *cerbos.GRPCClient) bool {
+		t.Helper()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := c.ServerInfo(ctx)
+		require.Error(t, err)
+		return status.Code(err) == codes.Canceled
+	}
+
+	c1, err := cerbos.SharedClient(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+	require.NoError(t, err)
+
+	c2, err := cerbos.SharedClient(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+	require.NoError(t, err)
+
+	require.NoError(t, c1.Close())
+	require.NoError(t, c1.Close(), "Closing the same handle twice should be harmless")
+	require.False(t, isClosed(t, c2), "Connection closed while references remain")
+
+	require.NoError(t, c2.Close())
+	require.True(t, isClosed(t, c2), "Connection not closed after releasing the last reference")
+
+	c3, err := cerbos.SharedClient(addr, cerbos.WithPlaintext(), cerbos.WithMaxRetries(0))
+	require.NoError(t, err)
+	require.False(t, isClosed(t, c3), "New connection should be created after the previous one was closed")
+
+	require.NoError(t, cerbos.CloseSharedClients())
+	require.True(t, isClosed(t, c3), "Connection not closed by CloseSharedClients")
+}
+
+func TestSharedClientOptions(t *testing.T) {
+	t.Cleanup(func() { _ = cerbos.CloseSharedClients() })
+
+	conns := make(chan struct{}, 4)
+	addr := startFakeServer(t, &fakeServer{
+		serverInfo: func(context.Context, *requestv1.ServerInfoRequest) (*responsev1.ServerInfoResponse, error) {
+			return &responsev1.ServerInfoResponse{Version: "0.36.0"}, nil
+		},
+	}, grpc.StatsHandler(connCounter(conns)))
+
+	connect := func(t *testing.T, opts ...cerbos.Opt) {
+		t.Helper()
+
+		c, err := cerbos.SharedClient(addr, append([]cerbos.Opt{cerbos.WithPlaintext()}, opts...)...)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		_, err = c.ServerInfo(context.Background())
+		require.NoError(t, err)
+	}
+
+	t.Run("comparable", func(t *testing.T) {
+		keepAlive := func() cerbos.Opt {
+			return cerbos.WithKeepAlive(keepalive.ClientParameters{Time: time.Minute})
+		}
+
+		connect(t, keepAlive(), cerbos.WithDefaultAuxData(&requestv1.AuxData{Jwt: &requestv1.AuxData_JWT{Token: "token"}}))
+		connect(t, keepAlive(), cerbos.WithDefaultAuxData(&requestv1.AuxData{Jwt: &requestv1.AuxData_JWT{Token: "token"}}))
+		require.Len(t, conns, 1, "Clients with equal options should share a connection")
+	})
+
+	t.Run("not_comparable", func(t *testing.T) {
+		for len(conns) > 0 {
+			<-conns
+		}
+
+		interceptor := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		connect(t, cerbos.WithUnaryInterceptors(interceptor))
+		connect(t, cerbos.WithUnaryInterceptors(interceptor))
+		require.Len(t, conns, 2, "Clients with options that can't be compared should not share a connection")
+	})
+}
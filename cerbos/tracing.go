@@ -0,0 +1,78 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"path"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+const tracerName = "github.com/cerbos/cerbos-sdk-go/cerbos"
+
+// WithTracing creates an OpenTelemetry span for every unary call made by the client, named after the RPC method
+// (for example, cerbos.CheckResources for calls made by IsAllowed and CheckResources). Spans are children of the span
+// in the call context, cover all retries of the call and are tagged with Cerbos-specific attributes such as the request ID,
+// the number of resources and the number of allowed and denied actions. Use it alongside transport-level instrumentation
+// such as otelgrpc, whose spans are nested under the spans created by the client.
+func WithTracing(tp trace.TracerProvider) Opt {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+func tracingInterceptor(tp trace.TracerProvider) grpc.UnaryClientInterceptor {
+	tracer := tp.Tracer(tracerName)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := tracer.Start(ctx, "cerbos."+path.Base(method), trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+
+		switch r := req.(type) {
+		case *requestv1.CheckResourcesRequest:
+			span.SetAttributes(
+				attribute.String("cerbos.request_id", r.RequestId),
+				attribute.Int("cerbos.resource_count", len(r.Resources)),
+			)
+		case *requestv1.PlanResourcesRequest:
+			span.SetAttributes(
+				attribute.String("cerbos.request_id", r.RequestId),
+				attribute.String("cerbos.resource_kind", r.GetResource().GetKind()),
+			)
+		}
+
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+			return err
+		}
+
+		switch r := reply.(type) {
+		case *responsev1.CheckResourcesResponse:
+			var allowed, denied int
+			for _, result := range r.Results {
+				for _, effect := range result.Actions {
+					if effect == effectv1.Effect_EFFECT_ALLOW {
+						allowed++
+					} else {
+						denied++
+					}
+				}
+			}
+
+			span.SetAttributes(attribute.Int("cerbos.allow_count", allowed), attribute.Int("cerbos.deny_count", denied))
+		case *responsev1.PlanResourcesResponse:
+			span.SetAttributes(attribute.String("cerbos.filter_kind", r.GetFilter().GetKind().String()))
+		}
+
+		return nil
+	}
+}
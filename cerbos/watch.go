@@ -0,0 +1,148 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbos
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"time"
+
+	policyv1 "github.com/cerbos/cerbos/api/genpb/cerbos/policy/v1"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+// watchPollInterval is the interval between successive scans of a watched directory.
+// A change is only reported after the file has remained unchanged for a full interval.
+const watchPollInterval = 250 * time.Millisecond
+
+// PolicyChange describes a change to a policy file in a watched directory.
+// Policy is nil for removed files and for files that could not be parsed, in which case Err is set.
+type PolicyChange struct {
+	Err    error
+	Policy *policyv1.Policy
+	Path   string
+	Type   ChangeType
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// WatchPoliciesDir watches the policy files in the given directory and its sub-directories and emits a PolicyChange
+// whenever a file is added, modified or removed. Only changes made after the watch has started are reported.
+// Renaming a file is reported as the removal of the old path followed by the addition of the new path.
+// Rapid successive edits to a file are coalesced into a single change. The returned channel is closed when the context is cancelled.
+//
+// The directory is polled rather than watched using OS notifications so that any fs.FS implementation can be used.
+// This is intended for development workflows and not for watching large policy repositories.
+func WatchPoliciesDir(ctx context.Context, fsys fs.FS, dir string) (<-chan PolicyChange, error) {
+	initial, err := scanPoliciesDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	changes := make(chan PolicyChange)
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+
+		prev := initial
+		emitted := make(map[string]fileState, len(initial))
+		for p, state := range initial {
+			emitted[p] = state
+		}
+
+		emit := func(c PolicyChange) bool {
+			select {
+			case changes <- c:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			curr, err := scanPoliciesDir(fsys, dir)
+			if err != nil {
+				if !emit(PolicyChange{Path: dir, Err: fmt.Errorf("failed to scan %s: %w", dir, err)}) {
+					return
+				}
+				continue
+			}
+
+			for p := range emitted {
+				if _, ok := curr[p]; !ok {
+					delete(emitted, p)
+					if !emit(PolicyChange{Path: p, Type: ChangeRemoved}) {
+						return
+					}
+				}
+			}
+
+			for p, state := range curr {
+				if prevState, ok := prev[p]; !ok || prevState != state {
+					continue // still changing
+				}
+
+				changeType := ChangeModified
+				if emittedState, ok := emitted[p]; !ok {
+					changeType = ChangeAdded
+				} else if emittedState == state {
+					continue
+				}
+
+				emitted[p] = state
+				policy, err := internal.ReadPolicyFromFile(fsys, p)
+				if !emit(PolicyChange{Path: p, Type: changeType, Policy: policy, Err: err}) {
+					return
+				}
+			}
+
+			prev = curr
+		}
+	}()
+
+	return changes, nil
+}
+
+func scanPoliciesDir(fsys fs.FS, dir string) (map[string]fileState, error) {
+	state := make(map[string]fileState)
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if p != dir && internal.SkipPolicyDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if !internal.IsPolicyFile(p) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		state[p] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+
+	return state, err
+}
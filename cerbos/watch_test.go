@@ -0,0 +1,86 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build tests
+
+package cerbos_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+)
+
+const watchedPolicy = `---
+apiVersion: api.cerbos.dev/v1
+resourcePolicy:
+  resource: leave_request
+  version: %s
+  rules:
+    - actions: ["view"]
+      effect: EFFECT_ALLOW
+      roles: ["employee"]
+`
+
+func TestWatchPoliciesDir(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy := func(t *testing.T, name, version string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(fmt.Sprintf(watchedPolicy, version)), 0o600))
+	}
+
+	writePolicy(t, "existing.yaml", "default")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := cerbos.WatchPoliciesDir(ctx, os.DirFS(dir), ".")
+	require.NoError(t, err)
+
+	next := func(t *testing.T) cerbos.PolicyChange {
+		t.Helper()
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "Timed out waiting for change")
+			return cerbos.PolicyChange{}
+		}
+	}
+
+	writePolicy(t, "new.yaml", "default")
+	c := next(t)
+	require.Equal(t, cerbos.ChangeAdded, c.Type)
+	require.Equal(t, "new.yaml", c.Path)
+	require.NoError(t, c.Err)
+	require.Equal(t, "default", c.Policy.GetResourcePolicy().GetVersion())
+
+	writePolicy(t, "new.yaml", "staging")
+	c = next(t)
+	require.Equal(t, cerbos.ChangeModified, c.Type)
+	require.Equal(t, "staging", c.Policy.GetResourcePolicy().GetVersion())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("{"), 0o600))
+	c = next(t)
+	require.Equal(t, cerbos.ChangeAdded, c.Type)
+	require.Equal(t, "broken.yaml", c.Path)
+	require.Error(t, c.Err)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "existing.yaml")))
+	c = next(t)
+	require.Equal(t, cerbos.ChangeRemoved, c.Type)
+	require.Equal(t, "existing.yaml", c.Path)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		_, ok := <-changes
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+}
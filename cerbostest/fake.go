@@ -0,0 +1,278 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbostest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+var (
+	_ cerbos.Client[*FakeClient, *FakePrincipalCtx] = (*FakeClient)(nil)
+	_ cerbos.PrincipalContext                       = (*FakePrincipalCtx)(nil)
+)
+
+// FakeVersion is the server version reported by FakeClient.ServerInfo.
+const FakeVersion = "fake"
+
+// FakeCall is a call made to a FakeClient.
+type FakeCall struct {
+	Principal *cerbos.Principal
+	// Resources holds the resources and actions checked by IsAllowed and CheckResources.
+	Resources *cerbos.ResourceBatch
+	// Resource holds the resource passed to PlanResources.
+	Resource *cerbos.Resource
+	// Method is the name of the client method that was called, such as IsAllowed.
+	Method string
+	// Action holds the action passed to PlanResources.
+	Action string
+}
+
+type ruleKey struct {
+	principalID string
+	kind        string
+	action      string
+}
+
+type fakeState struct {
+	rules         map[ruleKey]effectv1.Effect
+	calls         []FakeCall
+	defaultEffect effectv1.Effect
+	mu            sync.RWMutex
+}
+
+// FakeClient is an in-memory implementation of the Cerbos client API for unit testing code that depends on a client,
+// without running a policy decision point. Instead of evaluating policies, it looks up the effect of each action from
+// rules keyed by principal ID, resource kind and action, falling back to a default effect (deny, unless changed using
+// DefaultEffect). Request options set using With are accepted but have no effect on the outcome.
+//
+// Inputs are validated in the same way as the real client. A FakeClient is safe for concurrent use.
+type FakeClient struct {
+	state *fakeState
+}
+
+// NewFakeClient creates a fake client that denies everything until rules are added.
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		state: &fakeState{
+			rules:         make(map[ruleKey]effectv1.Effect),
+			defaultEffect: effectv1.Effect_EFFECT_DENY,
+		},
+	}
+}
+
+// Allow allows the principal with the given ID to perform the actions on resources of the given kind.
+func (fc *FakeClient) Allow(principalID, kind string, actions ...string) *FakeClient {
+	return fc.setRule(principalID, kind, actions, effectv1.Effect_EFFECT_ALLOW)
+}
+
+// Deny denies the principal with the given ID from performing the actions on resources of the given kind.
+// It's only necessary when the default effect is allow.
+func (fc *FakeClient) Deny(principalID, kind string, actions ...string) *FakeClient {
+	return fc.setRule(principalID, kind, actions, effectv1.Effect_EFFECT_DENY)
+}
+
+// DefaultEffect sets the effect for combinations of principal, resource kind and action that don't have a rule.
+func (fc *FakeClient) DefaultEffect(effect effectv1.Effect) *FakeClient {
+	fc.state.mu.Lock()
+	defer fc.state.mu.Unlock()
+
+	fc.state.defaultEffect = effect
+	return fc
+}
+
+func (fc *FakeClient) setRule(principalID, kind string, actions []string, effect effectv1.Effect) *FakeClient {
+	fc.state.mu.Lock()
+	defer fc.state.mu.Unlock()
+
+	for _, a := range actions {
+		fc.state.rules[ruleKey{principalID: principalID, kind: kind, action: a}] = effect
+	}
+
+	return fc
+}
+
+// Calls returns the calls made to the client, in order.
+func (fc *FakeClient) Calls() []FakeCall {
+	fc.state.mu.RLock()
+	defer fc.state.mu.RUnlock()
+
+	calls := make([]FakeCall, len(fc.state.calls))
+	copy(calls, fc.state.calls)
+	return calls
+}
+
+// Reset discards the recorded calls. Rules and the default effect are kept.
+func (fc *FakeClient) Reset() {
+	fc.state.mu.Lock()
+	defer fc.state.mu.Unlock()
+
+	fc.state.calls = nil
+}
+
+func (fc *FakeClient) IsAllowed(_ context.Context, principal *cerbos.Principal, resource *cerbos.Resource, action string) (bool, error) {
+	if resource == nil {
+		return false, errors.New("invalid resource: resource is nil")
+	}
+
+	batch := cerbos.NewResourceBatch().Add(resource, action)
+	resp, err := fc.check("IsAllowed", principal, batch)
+	if err != nil {
+		return false, err
+	}
+
+	return resp.GetResource(resource.ID(), cerbos.MatchResourceKind(resource.Kind())).IsAllowed(action), nil
+}
+
+func (fc *FakeClient) CheckResources(_ context.Context, principal *cerbos.Principal, resources *cerbos.ResourceBatch) (*cerbos.CheckResourcesResponse, error) {
+	return fc.check("CheckResources", principal, resources)
+}
+
+func (fc *FakeClient) check(method string, principal *cerbos.Principal, batch *cerbos.ResourceBatch) (*cerbos.CheckResourcesResponse, error) {
+	if err := validPrincipal(principal); err != nil {
+		return nil, err
+	}
+
+	if batch == nil {
+		return nil, errors.New("invalid resource batch: batch is nil")
+	}
+
+	if err := batch.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid resource batch: %w", err)
+	}
+
+	fc.state.mu.Lock()
+	defer fc.state.mu.Unlock()
+
+	fc.state.calls = append(fc.state.calls, FakeCall{Method: method, Principal: principal, Resources: batch})
+
+	results := make([]*responsev1.CheckResourcesResponse_ResultEntry, len(batch.Batch))
+	for i, entry := range batch.Batch {
+		actions := make(map[string]effectv1.Effect, len(entry.Actions))
+		for _, a := range entry.Actions {
+			actions[a] = fc.state.effect(principal.ID(), entry.Resource.GetKind(), a)
+		}
+
+		results[i] = &responsev1.CheckResourcesResponse_ResultEntry{
+			Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{
+				Id:            entry.Resource.GetId(),
+				Kind:          entry.Resource.GetKind(),
+				PolicyVersion: entry.Resource.GetPolicyVersion(),
+				Scope:         entry.Resource.GetScope(),
+			},
+			Actions: actions,
+		}
+	}
+
+	return &cerbos.CheckResourcesResponse{
+		CheckResourcesResponse: &responsev1.CheckResourcesResponse{Results: results},
+	}, nil
+}
+
+// PlanResources returns an always allowed or an always denied plan, depending on the effect of the action
+// for the principal and the resource kind.
+func (fc *FakeClient) PlanResources(_ context.Context, principal *cerbos.Principal, resource *cerbos.Resource, action string) (*cerbos.PlanResourcesResponse, error) {
+	if err := validPrincipal(principal); err != nil {
+		return nil, err
+	}
+
+	if resource == nil {
+		return nil, errors.New("invalid resource: resource is nil")
+	}
+
+	if resource.Kind() == "" {
+		return nil, errors.New("invalid resource: kind is empty")
+	}
+
+	if action == "" {
+		return nil, errors.New("action is empty")
+	}
+
+	fc.state.mu.Lock()
+	defer fc.state.mu.Unlock()
+
+	fc.state.calls = append(fc.state.calls, FakeCall{Method: "PlanResources", Principal: principal, Resource: resource, Action: action})
+
+	kind := enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED
+	if fc.state.effect(principal.ID(), resource.Kind(), action) == effectv1.Effect_EFFECT_ALLOW {
+		kind = enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED
+	}
+
+	return &cerbos.PlanResourcesResponse{
+		PlanResourcesResponse: &responsev1.PlanResourcesResponse{
+			Action:        action,
+			ResourceKind:  resource.Kind(),
+			PolicyVersion: resource.Obj.GetPolicyVersion(),
+			Filter:        &enginev1.PlanResourcesFilter{Kind: kind},
+		},
+	}, nil
+}
+
+// ServerInfo returns FakeVersion as the server version.
+func (fc *FakeClient) ServerInfo(context.Context) (*cerbos.ServerInfo, error) {
+	fc.state.mu.Lock()
+	defer fc.state.mu.Unlock()
+
+	fc.state.calls = append(fc.state.calls, FakeCall{Method: "ServerInfo"})
+	return &cerbos.ServerInfo{ServerInfoResponse: &responsev1.ServerInfoResponse{Version: FakeVersion}}, nil
+}
+
+// With returns the client itself. Request options have no effect on the fake client.
+func (fc *FakeClient) With(...cerbos.RequestOpt) *FakeClient {
+	return fc
+}
+
+func (fc *FakeClient) WithPrincipal(principal *cerbos.Principal) *FakePrincipalCtx {
+	return &FakePrincipalCtx{client: fc, principal: principal}
+}
+
+// effect must be called with the lock held.
+func (fs *fakeState) effect(principalID, kind, action string) effectv1.Effect {
+	if effect, ok := fs.rules[ruleKey{principalID: principalID, kind: kind, action: action}]; ok {
+		return effect
+	}
+
+	return fs.defaultEffect
+}
+
+func validPrincipal(principal *cerbos.Principal) error {
+	if principal == nil {
+		return errors.New("invalid principal: principal is nil")
+	}
+
+	if err := principal.Validate(); err != nil {
+		return fmt.Errorf("invalid principal: %w", err)
+	}
+
+	return nil
+}
+
+// FakePrincipalCtx provides convenience methods to access the fake client in the context of a single principal.
+type FakePrincipalCtx struct {
+	client    *FakeClient
+	principal *cerbos.Principal
+}
+
+func (pc *FakePrincipalCtx) Principal() *cerbos.Principal {
+	return pc.principal
+}
+
+func (pc *FakePrincipalCtx) IsAllowed(ctx context.Context, resource *cerbos.Resource, action string) (bool, error) {
+	return pc.client.IsAllowed(ctx, pc.principal, resource, action)
+}
+
+func (pc *FakePrincipalCtx) CheckResources(ctx context.Context, batch *cerbos.ResourceBatch) (*cerbos.CheckResourcesResponse, error) {
+	return pc.client.CheckResources(ctx, pc.principal, batch)
+}
+
+func (pc *FakePrincipalCtx) PlanResources(ctx context.Context, resource *cerbos.Resource, action string) (*cerbos.PlanResourcesResponse, error) {
+	return pc.client.PlanResources(ctx, pc.principal, resource, action)
+}
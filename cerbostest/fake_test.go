@@ -0,0 +1,110 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbostest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	"github.com/cerbos/cerbos-sdk-go/cerbostest"
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+)
+
+func TestFakeClient(t *testing.T) {
+	ctx := context.Background()
+	john := cerbos.NewPrincipal("john", "employee")
+	resource := cerbos.NewResource("leave_request", "XX125")
+
+	fc := cerbostest.NewFakeClient().Allow("john", "leave_request", "view", "create")
+
+	t.Run("is_allowed", func(t *testing.T) {
+		allowed, err := fc.IsAllowed(ctx, john, resource, "view")
+		require.NoError(t, err)
+		require.True(t, allowed)
+
+		allowed, err = fc.WithPrincipal(john).IsAllowed(ctx, resource, "approve")
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+
+	t.Run("check_resources", func(t *testing.T) {
+		batch := cerbos.NewResourceBatch().
+			Add(resource, "view", "approve").
+			Add(cerbos.NewResource("expense_report", "E1"), "view")
+
+		resp, err := fc.With(cerbos.IncludeMeta(true)).CheckResources(ctx, john, batch)
+		require.NoError(t, err)
+
+		leave := resp.GetResource("XX125", cerbos.MatchResourceKind("leave_request"))
+		require.True(t, leave.IsAllowed("view"))
+		require.False(t, leave.IsAllowed("approve"))
+		require.False(t, resp.GetResource("E1").IsAllowed("view"))
+	})
+
+	t.Run("plan_resources", func(t *testing.T) {
+		plan, err := fc.PlanResources(ctx, john, cerbos.NewResource("leave_request", ""), "create")
+		require.NoError(t, err)
+		require.Equal(t, enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED, plan.Kind())
+
+		plan, err = fc.PlanResources(ctx, john, cerbos.NewResource("leave_request", ""), "delete")
+		require.NoError(t, err)
+		require.Equal(t, enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED, plan.Kind())
+	})
+
+	t.Run("default_effect", func(t *testing.T) {
+		fc := cerbostest.NewFakeClient().DefaultEffect(effectv1.Effect_EFFECT_ALLOW).Deny("john", "leave_request", "delete")
+
+		allowed, err := fc.IsAllowed(ctx, john, resource, "approve")
+		require.NoError(t, err)
+		require.True(t, allowed)
+
+		allowed, err = fc.IsAllowed(ctx, john, resource, "delete")
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+
+	t.Run("calls", func(t *testing.T) {
+		fc := cerbostest.NewFakeClient()
+
+		_, err := fc.IsAllowed(ctx, john, resource, "view")
+		require.NoError(t, err)
+
+		_, err = fc.PlanResources(ctx, john, cerbos.NewResource("leave_request", ""), "view")
+		require.NoError(t, err)
+
+		info, err := fc.ServerInfo(ctx)
+		require.NoError(t, err)
+		require.Equal(t, cerbostest.FakeVersion, info.Version)
+
+		calls := fc.Calls()
+		require.Len(t, calls, 3)
+		require.Equal(t, "IsAllowed", calls[0].Method)
+		require.Equal(t, "john", calls[0].Principal.ID())
+		require.Equal(t, "XX125", calls[0].Resources.Batch[0].Resource.Id)
+		require.Equal(t, "PlanResources", calls[1].Method)
+		require.Equal(t, "view", calls[1].Action)
+		require.Equal(t, "ServerInfo", calls[2].Method)
+
+		fc.Reset()
+		require.Empty(t, fc.Calls())
+	})
+
+	t.Run("invalid_input", func(t *testing.T) {
+		fc := cerbostest.NewFakeClient()
+
+		_, err := fc.IsAllowed(ctx, cerbos.NewPrincipal("john"), resource, "view")
+		require.Error(t, err)
+
+		_, err = fc.CheckResources(ctx, john, cerbos.NewResourceBatch())
+		require.Error(t, err)
+
+		_, err = fc.PlanResources(ctx, john, cerbos.NewResource("", ""), "view")
+		require.Error(t, err)
+		require.Empty(t, fc.Calls())
+	})
+}
@@ -0,0 +1,146 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cerbostest provides helpers for testing code that uses the Cerbos SDK.
+package cerbostest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+)
+
+// Recorder captures the requests sent by a Cerbos client so that tests can make assertions about them.
+// Attach it to a client using cerbos.WithUnaryInterceptors(recorder.UnaryInterceptor()).
+type Recorder struct {
+	lastCheck *requestv1.CheckResourcesRequest
+	lastPlan  *requestv1.PlanResourcesRequest
+	calls     []RecordedCall
+	mu        sync.Mutex
+}
+
+// RecordedCall is a CheckResources request captured by a Recorder.
+type RecordedCall struct {
+	Request *requestv1.CheckResourcesRequest
+}
+
+// NewRecorder creates a new request recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// UnaryInterceptor returns a client interceptor that records each request before passing it on.
+func (r *Recorder) UnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		r.mu.Lock()
+		switch rr := req.(type) {
+		case *requestv1.CheckResourcesRequest:
+			r.lastCheck = proto.Clone(rr).(*requestv1.CheckResourcesRequest) //nolint:forcetypeassert
+			r.calls = append(r.calls, RecordedCall{Request: r.lastCheck})
+		case *requestv1.PlanResourcesRequest:
+			r.lastPlan = proto.Clone(rr).(*requestv1.PlanResourcesRequest) //nolint:forcetypeassert
+		}
+		r.mu.Unlock()
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// LastCheck returns the most recent CheckResources request or nil if none has been made.
+func (r *Recorder) LastCheck() *requestv1.CheckResourcesRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lastCheck
+}
+
+// LastPlan returns the most recent PlanResources request or nil if none has been made.
+func (r *Recorder) LastPlan() *requestv1.PlanResourcesRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lastPlan
+}
+
+// Calls returns all the CheckResources requests recorded since the recorder was created or last reset, in the order they were made.
+// Each attempt of a retried call is recorded separately.
+func (r *Recorder) Calls() []RecordedCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]RecordedCall, len(r.calls))
+	copy(calls, r.calls)
+	return calls
+}
+
+// Reset discards all recorded requests.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastCheck = nil
+	r.lastPlan = nil
+	r.calls = nil
+}
+
+// ExpectCheck asserts that the most recent CheckResources request was made for the given principal and
+// included the given resource with all of the given actions. Mismatches are reported with a diff.
+func (r *Recorder) ExpectCheck(t testing.TB, principal *cerbos.Principal, resource *cerbos.Resource, actions ...string) bool {
+	t.Helper()
+
+	req := r.LastCheck()
+	if req == nil {
+		t.Errorf("Expected a CheckResources request but none was made")
+		return false
+	}
+
+	ok := true
+	if !proto.Equal(principal.Proto(), req.Principal) {
+		t.Errorf("Principal mismatch (-want +got):\n%s", cmp.Diff(principal.Proto(), req.Principal, protocmp.Transform()))
+		ok = false
+	}
+
+	var entry *requestv1.CheckResourcesRequest_ResourceEntry
+	for _, e := range req.Resources {
+		if e.GetResource().GetKind() == resource.Kind() && e.GetResource().GetId() == resource.ID() {
+			entry = e
+			break
+		}
+	}
+
+	if entry == nil {
+		found := make([]string, len(req.Resources))
+		for i, e := range req.Resources {
+			found[i] = e.GetResource().GetKind() + ":" + e.GetResource().GetId()
+		}
+		t.Errorf("Resource %s:%s not found in request. Resources in request: %v", resource.Kind(), resource.ID(), found)
+		return false
+	}
+
+	if !proto.Equal(resource.Proto(), entry.Resource) {
+		t.Errorf("Resource mismatch (-want +got):\n%s", cmp.Diff(resource.Proto(), entry.Resource, protocmp.Transform()))
+		ok = false
+	}
+
+	requested := make(map[string]struct{}, len(entry.Actions))
+	for _, a := range entry.Actions {
+		requested[a] = struct{}{}
+	}
+
+	for _, action := range actions {
+		if _, isRequested := requested[action]; !isRequested {
+			t.Errorf("Action %q not requested for resource %s:%s. Requested actions: %v", action, resource.Kind(), resource.ID(), entry.Actions)
+			ok = false
+		}
+	}
+
+	return ok
+}
@@ -0,0 +1,66 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbostest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	"github.com/cerbos/cerbos-sdk-go/cerbostest"
+)
+
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (*recordingT) Helper() {}
+
+func (rt *recordingT) Errorf(format string, args ...any) {
+	rt.errors = append(rt.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExpectCheck(t *testing.T) {
+	rec := cerbostest.NewRecorder()
+
+	// Requests are recorded before they are sent, so a server isn't required.
+	c, err := cerbos.New("passthrough:///127.0.0.1:1", cerbos.WithPlaintext(), cerbos.WithMaxRetries(0), cerbos.WithUnaryInterceptors(rec.UnaryInterceptor()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	principal := cerbos.NewPrincipal("john", "employee").WithAttr("department", "marketing")
+	resource := cerbos.NewResource("leave_request", "XX125").WithAttr("owner", "john")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _ = c.IsAllowed(ctx, principal, resource, "view")
+
+	require.True(t, rec.ExpectCheck(t, principal, resource, "view"))
+
+	rt := &recordingT{TB: t}
+	require.False(t, rec.ExpectCheck(rt, cerbos.NewPrincipal("john", "manager"), resource, "view", "approve"))
+	require.Len(t, rt.errors, 2)
+	require.Contains(t, rt.errors[0], "Principal mismatch")
+	require.Contains(t, rt.errors[1], `Action "approve" not requested`)
+
+	rt = &recordingT{TB: t}
+	require.False(t, rec.ExpectCheck(rt, principal, resource, "view", "approve"), "A missing action should fail the expectation")
+	require.Len(t, rt.errors, 1)
+	require.Contains(t, rt.errors[0], `Action "approve" not requested`)
+
+	rt = &recordingT{TB: t}
+	require.False(t, rec.ExpectCheck(rt, principal, cerbos.NewResource("leave_request", "XX999"), "view"))
+	require.Len(t, rt.errors, 1)
+	require.Contains(t, rt.errors[0], "leave_request:XX125")
+
+	rec.Reset()
+	rt = &recordingT{TB: t}
+	require.False(t, rec.ExpectCheck(rt, principal, resource, "view"))
+	require.Nil(t, rec.LastCheck())
+}
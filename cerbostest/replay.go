@@ -0,0 +1,108 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbostest
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+	requestv1 "github.com/cerbos/cerbos/api/genpb/cerbos/request/v1"
+)
+
+// DecisionClient is the subset of the Cerbos client API required to replay recorded calls.
+// It's implemented by *cerbos.GRPCClient.
+type DecisionClient interface {
+	CheckResources(ctx context.Context, principal *cerbos.Principal, resourceBatch *cerbos.ResourceBatch) (*cerbos.CheckResourcesResponse, error)
+}
+
+// Divergence describes a check for which two clients returned different effects.
+type Divergence struct {
+	PrincipalID  string
+	ResourceKind string
+	ResourceID   string
+	Action       string
+	EffectA      effectv1.Effect
+	EffectB      effectv1.Effect
+	// Call is the index of the recorded call that produced the divergence.
+	Call int
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("call %d: principal=%s resource=%s:%s action=%s: %s != %s",
+		d.Call, d.PrincipalID, d.ResourceKind, d.ResourceID, d.Action, d.EffectA, d.EffectB)
+}
+
+// Diff replays the recorded calls against the two clients and reports every check for which they returned different effects.
+// It's intended for validating a Cerbos upgrade or a policy change before rolling it out: record the calls made by an application
+// using a Recorder, then replay them against a client connected to the current deployment and a client connected to the new one.
+//
+// Only the principal and the resources of the recorded calls are replayed. Auxiliary data and other request-level settings are not,
+// so configure the clients with the request options required to reproduce them. Replay stops at the first call that fails.
+func Diff(ctx context.Context, recorded []RecordedCall, a, b DecisionClient) ([]Divergence, error) {
+	var divergences []Divergence
+	for i, call := range recorded {
+		if call.Request == nil {
+			continue
+		}
+
+		principal := &cerbos.Principal{Obj: proto.Clone(call.Request.Principal).(*enginev1.Principal)} //nolint:forcetypeassert
+		batch := &cerbos.ResourceBatch{Batch: cloneEntries(call.Request.Resources)}
+
+		respA, err := a.CheckResources(ctx, principal, batch)
+		if err != nil {
+			return divergences, fmt.Errorf("call %d failed on client A: %w", i, err)
+		}
+
+		respB, err := b.CheckResources(ctx, principal, batch)
+		if err != nil {
+			return divergences, fmt.Errorf("call %d failed on client B: %w", i, err)
+		}
+
+		for _, entry := range batch.Batch {
+			kind, id := entry.Resource.GetKind(), entry.Resource.GetId()
+			resultA := respA.GetResource(id, cerbos.MatchResourceKind(kind))
+			resultB := respB.GetResource(id, cerbos.MatchResourceKind(kind))
+
+			for _, action := range entry.Actions {
+				effectA, effectB := effectOf(resultA, action), effectOf(resultB, action)
+				if effectA != effectB {
+					divergences = append(divergences, Divergence{
+						Call:         i,
+						PrincipalID:  principal.ID(),
+						ResourceKind: kind,
+						ResourceID:   id,
+						Action:       action,
+						EffectA:      effectA,
+						EffectB:      effectB,
+					})
+				}
+			}
+		}
+	}
+
+	return divergences, nil
+}
+
+func cloneEntries(entries []*requestv1.CheckResourcesRequest_ResourceEntry) []*requestv1.CheckResourcesRequest_ResourceEntry {
+	out := make([]*requestv1.CheckResourcesRequest_ResourceEntry, len(entries))
+	for i, e := range entries {
+		out[i] = proto.Clone(e).(*requestv1.CheckResourcesRequest_ResourceEntry) //nolint:forcetypeassert
+	}
+
+	return out
+}
+
+// effectOf returns the effect of the action in the result or EFFECT_UNSPECIFIED if the result or the action is missing.
+func effectOf(result *cerbos.ResourceResult, action string) effectv1.Effect {
+	if result.Err() != nil || result.CheckResourcesResponse_ResultEntry == nil {
+		return effectv1.Effect_EFFECT_UNSPECIFIED
+	}
+
+	return result.Actions[action]
+}
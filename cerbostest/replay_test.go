@@ -0,0 +1,89 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package cerbostest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	"github.com/cerbos/cerbos-sdk-go/cerbostest"
+	effectv1 "github.com/cerbos/cerbos/api/genpb/cerbos/effect/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+)
+
+// staticClient allows the actions in the allowed set and denies everything else.
+type staticClient struct {
+	err     error
+	allowed map[string]bool
+}
+
+func (sc staticClient) CheckResources(_ context.Context, _ *cerbos.Principal, batch *cerbos.ResourceBatch) (*cerbos.CheckResourcesResponse, error) {
+	if sc.err != nil {
+		return nil, sc.err
+	}
+
+	resp := &responsev1.CheckResourcesResponse{}
+	for _, entry := range batch.Batch {
+		actions := make(map[string]effectv1.Effect, len(entry.Actions))
+		for _, a := range entry.Actions {
+			actions[a] = effectv1.Effect_EFFECT_DENY
+			if sc.allowed[entry.Resource.Kind+":"+a] {
+				actions[a] = effectv1.Effect_EFFECT_ALLOW
+			}
+		}
+
+		resp.Results = append(resp.Results, &responsev1.CheckResourcesResponse_ResultEntry{
+			Resource: &responsev1.CheckResourcesResponse_ResultEntry_Resource{Id: entry.Resource.Id, Kind: entry.Resource.Kind},
+			Actions:  actions,
+		})
+	}
+
+	return &cerbos.CheckResourcesResponse{CheckResourcesResponse: resp}, nil
+}
+
+func TestDiff(t *testing.T) {
+	rec := cerbostest.NewRecorder()
+
+	// Requests are recorded before they are sent, so a server isn't required.
+	c, err := cerbos.New("passthrough:///127.0.0.1:1", cerbos.WithPlaintext(), cerbos.WithMaxRetries(0), cerbos.WithUnaryInterceptors(rec.UnaryInterceptor()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	principal := cerbos.NewPrincipal("john", "employee")
+	_, _ = c.IsAllowed(ctx, principal, cerbos.NewResource("leave_request", "XX125"), "view")
+	_, _ = c.CheckResources(ctx, principal, cerbos.NewResourceBatch().
+		Add(cerbos.NewResource("leave_request", "XX125"), "view", "approve").
+		Add(cerbos.NewResource("purchase_order", "PO1"), "view"))
+
+	calls := rec.Calls()
+	require.Len(t, calls, 2)
+
+	current := staticClient{allowed: map[string]bool{"leave_request:view": true, "purchase_order:view": true}}
+	next := staticClient{allowed: map[string]bool{"leave_request:view": true, "leave_request:approve": true}}
+
+	divergences, err := cerbostest.Diff(context.Background(), calls, current, next)
+	require.NoError(t, err)
+	require.Equal(t, []cerbostest.Divergence{
+		{Call: 1, PrincipalID: "john", ResourceKind: "leave_request", ResourceID: "XX125", Action: "approve", EffectA: effectv1.Effect_EFFECT_DENY, EffectB: effectv1.Effect_EFFECT_ALLOW},
+		{Call: 1, PrincipalID: "john", ResourceKind: "purchase_order", ResourceID: "PO1", Action: "view", EffectA: effectv1.Effect_EFFECT_ALLOW, EffectB: effectv1.Effect_EFFECT_DENY},
+	}, divergences)
+
+	divergences, err = cerbostest.Diff(context.Background(), calls, current, current)
+	require.NoError(t, err)
+	require.Empty(t, divergences)
+
+	_, err = cerbostest.Diff(context.Background(), calls, current, staticClient{err: errors.New("boom")})
+	require.ErrorContains(t, err, "call 0 failed on client B")
+
+	rec.Reset()
+	require.Empty(t, rec.Calls())
+}
@@ -0,0 +1,161 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// AttrTag is the struct tag used to customise how struct fields are converted to attributes.
+const AttrTag = "cerbos"
+
+var (
+	errNotStruct = errors.New("value must be a struct or a non-nil pointer to a struct")
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// StructToAttributes converts the exported fields of a struct to a map of attributes.
+// The attribute name can be set using the `cerbos` struct tag, which also supports the omitempty option.
+// Fields tagged with `cerbos:"-"` are skipped and the fields of embedded structs are promoted.
+func StructToAttributes(v any) (map[string]any, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, errNotStruct
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, errNotStruct
+	}
+
+	attrs := make(map[string]any)
+	if err := collectFields(rv, attrs); err != nil {
+		return nil, err
+	}
+
+	return attrs, nil
+}
+
+func collectFields(rv reflect.Value, attrs map[string]any) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get(AttrTag)
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+
+		if field.Anonymous && name == "" {
+			embedded := fv
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				if err := collectFields(embedded, attrs); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+
+		val, err := attrValue(fv)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		attrs[name] = val
+	}
+
+	return nil
+}
+
+func attrValue(rv reflect.Value) (any, error) {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return attrValue(rv.Elem())
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return rv.Interface(), nil
+		}
+
+		attrs := make(map[string]any)
+		if err := collectFields(rv, attrs); err != nil {
+			return nil, err
+		}
+		return attrs, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+
+		out := make([]any, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			v, err := attrValue(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", rv.Type().Key())
+		}
+
+		if rv.IsNil() {
+			return nil, nil
+		}
+
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			v, err := attrValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			out[iter.Key().String()] = v
+		}
+		return out, nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", rv.Type())
+	}
+}
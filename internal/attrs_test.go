@@ -0,0 +1,72 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+type Audit struct {
+	CreatedBy string `cerbos:"createdBy"`
+}
+
+type address struct {
+	City string `cerbos:"city"`
+}
+
+type leaveRequest struct {
+	Audit
+	CreatedAt time.Time         `cerbos:"createdAt"`
+	Address   *address          `cerbos:"address"`
+	Labels    map[string]string `cerbos:"labels,omitempty"`
+	Owner     string            `cerbos:"owner"`
+	Secret    string            `cerbos:"-"`
+	Tags      []string
+	Days      int     `cerbos:"days"`
+	Ratio     float64 `cerbos:"ratio,omitempty"`
+	internal  string
+}
+
+func TestStructToAttributes(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	lr := leaveRequest{
+		Audit:     Audit{CreatedBy: "admin"},
+		CreatedAt: createdAt,
+		Address:   &address{City: "London"},
+		Owner:     "john",
+		Secret:    "hunter2",
+		Tags:      []string{"a", "b"},
+		Days:      3,
+		internal:  "ignored",
+	}
+
+	want := map[string]any{
+		"createdBy": "admin",
+		"createdAt": createdAt,
+		"address":   map[string]any{"city": "London"},
+		"owner":     "john",
+		"Tags":      []any{"a", "b"},
+		"days":      int64(3),
+	}
+
+	for _, v := range []any{lr, &lr} {
+		have, err := internal.StructToAttributes(v)
+		require.NoError(t, err)
+		require.Equal(t, want, have)
+	}
+
+	_, err := internal.StructToAttributes("not a struct")
+	require.Error(t, err)
+
+	_, err = internal.StructToAttributes((*leaveRequest)(nil))
+	require.Error(t, err)
+
+	_, err = internal.StructToAttributes(struct{ Fn func() }{Fn: func() {}})
+	require.Error(t, err)
+}
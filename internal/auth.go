@@ -49,6 +49,12 @@ func (OSEnvironment) LookupEnv(k string) (string, bool) { return os.LookupEnv(k)
 // - Environment variables
 // - netrc file.
 func LoadBasicAuthData(env Environment, providedServer, providedUsername, providedPassword string) (server, username, password string, err error) {
+	return LoadBasicAuthDataForMachine(env, providedServer, "", providedUsername, providedPassword)
+}
+
+// LoadBasicAuthDataForMachine is like LoadBasicAuthData but looks up the netrc entry for the given machine name
+// instead of the one derived from the server address. If the machine name is empty, it's derived from the server address.
+func LoadBasicAuthDataForMachine(env Environment, providedServer, machine, providedUsername, providedPassword string) (server, username, password string, err error) {
 	server = coalesceWithEnv(env, providedServer, ServerEnvVar)
 	if server == "" {
 		return "", "", "", errServerNotDefined
@@ -61,14 +67,15 @@ func LoadBasicAuthData(env Environment, providedServer, providedUsername, provid
 		return
 	}
 
-	username, password, err = loadCredsFromNetrc(env, server)
+	username, password, err = loadCredsFromNetrc(env, server, machine)
 	return
 }
 
-func loadCredsFromNetrc(env Environment, server string) (username, password string, err error) {
-	machineName, err := ExtractMachineName(server)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to parse server target '%s': %w", server, err)
+func loadCredsFromNetrc(env Environment, server, machineName string) (username, password string, err error) {
+	if machineName == "" {
+		if machineName, err = ExtractMachineName(server); err != nil {
+			return "", "", fmt.Errorf("failed to parse server target '%s': %w", server, err)
+		}
 	}
 
 	var netrcPath string
@@ -132,7 +139,7 @@ func ExtractMachineName(target string) (string, error) {
 		return u.Host, nil
 	case "unix", "unix-abstract":
 		return "", errNetrcUnsupportedForUDS
-	case "dns":
+	case "dns", "passthrough":
 		addr := remainder
 		if strings.HasPrefix(addr, "//") {
 			_, hostName, ok := strings.Cut(remainder[2:], "/")
@@ -128,6 +128,12 @@ func TestLoadBasicAuth(t *testing.T) {
 			providedServer: "",
 			wantErr:        true,
 		},
+		{
+			name:           "netrc fallback (unix socket)",
+			env:            mockEnv{internal.NetrcEnvVar: netrcPath},
+			providedServer: "unix:/var/run/cerbos.sock",
+			wantErr:        true,
+		},
 		{
 			name:           "no netrc file",
 			env:            mockEnv{internal.NetrcEnvVar: "test", internal.ServerEnvVar: "dns:///server:3592"},
@@ -154,6 +160,60 @@ func TestLoadBasicAuth(t *testing.T) {
 	}
 }
 
+func TestLoadBasicAuthForMachine(t *testing.T) {
+	netrcPath := mkNetrc(t)
+	testCases := []struct {
+		name           string
+		env            internal.Environment
+		providedServer string
+		machine        string
+		wantErr        bool
+		wantServer     string
+		wantUser       string
+		wantPass       string
+	}{
+		{
+			name:           "netrc fallback",
+			env:            mockEnv{internal.NetrcEnvVar: netrcPath},
+			providedServer: "unix:/var/run/cerbos.sock",
+			machine:        "server",
+			wantUser:       "netrcuser",
+			wantPass:       "netrcpass",
+			wantServer:     "unix:/var/run/cerbos.sock",
+		},
+		{
+			name:           "no netrc entry",
+			env:            mockEnv{internal.NetrcEnvVar: netrcPath},
+			providedServer: "server:3592",
+			machine:        "someserver",
+			wantErr:        true,
+		},
+		{
+			name:           "no machine",
+			env:            mockEnv{internal.NetrcEnvVar: netrcPath},
+			providedServer: "server:3592",
+			wantUser:       "netrcuser",
+			wantPass:       "netrcpass",
+			wantServer:     "server:3592",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			haveServer, haveUser, havePass, haveErr := internal.LoadBasicAuthDataForMachine(tc.env, tc.providedServer, tc.machine, "", "")
+			if tc.wantErr {
+				require.Error(t, haveErr)
+				return
+			}
+
+			require.NoError(t, haveErr)
+			require.Equal(t, tc.wantServer, haveServer)
+			require.Equal(t, tc.wantUser, haveUser)
+			require.Equal(t, tc.wantPass, havePass)
+		})
+	}
+}
+
 func mkNetrc(t *testing.T) string {
 	t.Helper()
 
@@ -193,6 +253,10 @@ func TestExtractMachineName(t *testing.T) {
 			target: "dns://192.168.1.1/myserver:3593",
 			want:   "myserver",
 		},
+		{
+			target: "passthrough:///myserver:3593",
+			want:   "myserver",
+		},
 		{
 			target: "10.0.1.2",
 			want:   "10.0.1.2",
@@ -6,10 +6,14 @@ package internal
 import (
 	"bufio"
 	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"io/fs"
+	"path"
+	"strings"
 	"unicode"
 
 	policyv1 "github.com/cerbos/cerbos/api/genpb/cerbos/policy/v1"
@@ -17,6 +21,8 @@ import (
 	"github.com/ghodss/yaml"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 const (
@@ -27,11 +33,51 @@ const (
 
 var (
 	jsonStart           = []byte("{")
+	yamlTagIndicator    = []byte("!")
 	yamlSep             = []byte("---")
 	yamlComment         = []byte("#")
 	ErrMultipleYAMLDocs = errors.New("more than one YAML document detected")
+	ErrFileTooLarge     = fmt.Errorf("file exceeds the maximum size of %d bytes", maxFileSize)
+
+	// supportedYAMLTags are the tags that have an equivalent JSON representation.
+	supportedYAMLTags = map[string]struct{}{
+		"!!binary":    {},
+		"!!bool":      {},
+		"!!float":     {},
+		"!!int":       {},
+		"!!map":       {},
+		"!!merge":     {},
+		"!!null":      {},
+		"!!seq":       {},
+		"!!str":       {},
+		"!!timestamp": {},
+	}
 )
 
+// IsPolicyFile returns true if the file at the given path is a candidate for loading as a policy.
+// Hidden files and test suites are excluded.
+func IsPolicyFile(p string) bool {
+	base := path.Base(p)
+	if strings.HasPrefix(base, ".") {
+		return false
+	}
+
+	ext := path.Ext(base)
+	switch ext {
+	case ".yaml", ".yml", ".json":
+		return !strings.HasSuffix(strings.TrimSuffix(base, ext), "_test")
+	default:
+		return false
+	}
+}
+
+// SkipPolicyDir returns true if the directory with the given name should not be searched for policies.
+func SkipPolicyDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "_schemas" || name == "testdata"
+}
+
+// ReadPolicyFromFile reads a policy from the given file. Files larger than 4MiB are rejected with ErrFileTooLarge,
+// which is exported as cerbos.ErrPolicyFileTooLarge.
 func ReadPolicyFromFile(fsys fs.FS, path string) (*policyv1.Policy, error) {
 	f, err := fsys.Open(path)
 	if err != nil {
@@ -40,10 +86,47 @@ func ReadPolicyFromFile(fsys fs.FS, path string) (*policyv1.Policy, error) {
 
 	defer f.Close()
 
-	return ReadPolicy(f)
+	policy, err := ReadPolicy(f)
+	if err != nil {
+		return nil, err
+	}
+
+	SetPolicyProvenance(policy, path)
+	return policy, nil
 }
 
-// ReadPolicy reads a policy from the given reader.
+// SetPolicyProvenance records the source file and the hash of the policy in its metadata.
+// Values that are already present in the metadata are not overwritten.
+func SetPolicyProvenance(policy *policyv1.Policy, source string) {
+	if policy.Metadata == nil {
+		policy.Metadata = &policyv1.Metadata{}
+	}
+
+	if policy.Metadata.SourceFile == "" {
+		policy.Metadata.SourceFile = source
+	}
+
+	if policy.Metadata.Hash == nil {
+		policy.Metadata.Hash = wrapperspb.UInt64(PolicyHash(policy))
+	}
+}
+
+// PolicyHash returns a hash of the policy definition. The metadata of the policy is not included in the hash.
+func PolicyHash(policy *policyv1.Policy) uint64 {
+	p := proto.Clone(policy).(*policyv1.Policy) //nolint:forcetypeassert
+	p.Metadata = nil
+
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(p)
+	if err != nil {
+		return 0
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return h.Sum64()
+}
+
+// ReadPolicy reads a policy from the given reader. Policies larger than 4MiB are rejected with ErrFileTooLarge.
 func ReadPolicy(src io.Reader) (*policyv1.Policy, error) {
 	policy := &policyv1.Policy{}
 	if err := ReadJSONOrYAML(src, policy); err != nil {
@@ -53,21 +136,101 @@ func ReadPolicy(src io.Reader) (*policyv1.Policy, error) {
 	return policy, nil
 }
 
+// ReadPolicies reads all the policies from the given reader, which can contain multiple YAML documents separated by "---" lines.
+// Empty documents are skipped. Errors are reported with the index of the document that caused them.
+// JSON input must contain a single policy.
+func ReadPolicies(src io.Reader) ([]*policyv1.Policy, error) {
+	buf := bufio.NewReaderSize(newSizeLimitedReader(src, maxFileSize), bufSize)
+	if isJSON(buf) {
+		policy := &policyv1.Policy{}
+		if err := newJSONDecoder(buf, protojson.UnmarshalOptions{}).decode(policy); err != nil {
+			return nil, err
+		}
+
+		return []*policyv1.Policy{policy}, nil
+	}
+
+	docs, err := splitYAMLDocs(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []*policyv1.Policy
+	for i, doc := range docs {
+		if isEmptyYAMLDoc(doc) {
+			continue
+		}
+
+		policy := &policyv1.Policy{}
+		if err := decodeYAMLDoc(doc, policy, protojson.UnmarshalOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to read document %d: %w", i, err)
+		}
+		policies = append(policies, policy)
+	}
+
+	if len(policies) == 0 {
+		return nil, errors.New("no policies found")
+	}
+
+	return policies, nil
+}
+
+// ReadPolicyLenient reads a policy from the given reader, discarding any fields unknown to this version of the SDK.
+// This is useful for loading policies authored for newer versions of Cerbos but note that it also hides typos in field names.
+func ReadPolicyLenient(src io.Reader) (*policyv1.Policy, error) {
+	policy := &policyv1.Policy{}
+	if err := ReadJSONOrYAMLLenient(src, policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
 func ReadJSONOrYAML(src io.Reader, dest proto.Message) error {
-	d := mkDecoder(io.LimitReader(src, maxFileSize))
+	d := mkDecoder(newSizeLimitedReader(src, maxFileSize), protojson.UnmarshalOptions{})
 	return d.decode(dest)
 }
 
-func mkDecoder(src io.Reader) decoder {
-	buf := bufio.NewReaderSize(src, bufSize)
-	prelude, _ := buf.Peek(bufSize)
-	trimmed := bytes.TrimLeftFunc(prelude, unicode.IsSpace)
+// ReadJSONOrYAMLLenient is like ReadJSONOrYAML but discards unknown fields instead of failing.
+func ReadJSONOrYAMLLenient(src io.Reader, dest proto.Message) error {
+	d := mkDecoder(newSizeLimitedReader(src, maxFileSize), protojson.UnmarshalOptions{DiscardUnknown: true})
+	return d.decode(dest)
+}
+
+// sizeLimitedReader returns ErrFileTooLarge if the underlying reader has more than the allowed number of bytes.
+type sizeLimitedReader struct {
+	src       io.Reader
+	remaining int64
+}
+
+func newSizeLimitedReader(src io.Reader, limit int64) *sizeLimitedReader {
+	// Allow reading one byte past the limit to detect whether the source is too large.
+	return &sizeLimitedReader{src: io.LimitReader(src, limit+1), remaining: limit}
+}
+
+func (r *sizeLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return 0, ErrFileTooLarge
+	}
+
+	return n, err
+}
 
-	if bytes.HasPrefix(trimmed, jsonStart) {
-		return newJSONDecoder(buf)
+func mkDecoder(src io.Reader, opts protojson.UnmarshalOptions) decoder {
+	buf := bufio.NewReaderSize(src, bufSize)
+	if isJSON(buf) {
+		return newJSONDecoder(buf, opts)
 	}
 
-	return newYAMLDecoder(buf)
+	return newYAMLDecoder(buf, opts)
+}
+
+// isJSON returns true if the buffered data looks like a JSON object.
+func isJSON(buf *bufio.Reader) bool {
+	prelude, _ := buf.Peek(bufSize)
+	return bytes.HasPrefix(bytes.TrimLeftFunc(prelude, unicode.IsSpace), jsonStart)
 }
 
 type decoder interface {
@@ -80,69 +243,166 @@ func (df decoderFunc) decode(dest proto.Message) error {
 	return df(dest)
 }
 
-func newJSONDecoder(src *bufio.Reader) decoderFunc {
+func newJSONDecoder(src *bufio.Reader, opts protojson.UnmarshalOptions) decoderFunc {
 	return func(dest proto.Message) error {
 		jsonBytes, err := io.ReadAll(src)
 		if err != nil {
 			return err
 		}
 
-		if err := protojson.Unmarshal(jsonBytes, dest); err != nil {
+		if err := opts.Unmarshal(jsonBytes, dest); err != nil {
 			return fmt.Errorf("failed to unmarshal JSON: %w", err)
 		}
 		return nil
 	}
 }
 
-func newYAMLDecoder(src *bufio.Reader) decoderFunc {
+func newYAMLDecoder(src *bufio.Reader, opts protojson.UnmarshalOptions) decoderFunc {
 	return func(dest proto.Message) error {
-		buf := new(bytes.Buffer)
-		numDocs := 0
-
-		s := bufio.NewScanner(src)
-		seenContent := false
-		for s.Scan() {
-			line := s.Bytes()
-			trimmedLine := bytes.TrimSpace(line)
-
-			// ignore comments
-			if bytes.HasPrefix(trimmedLine, yamlComment) {
-				continue
-			}
+		docs, err := splitYAMLDocs(src)
+		if err != nil {
+			return err
+		}
 
-			// ignore empty lines at the beginning of the file
-			if !seenContent && len(trimmedLine) == 0 {
-				continue
-			}
-			seenContent = true
+		if len(docs) > 1 {
+			return ErrMultipleYAMLDocs
+		}
 
-			if bytes.HasPrefix(line, yamlSep) {
-				numDocs++
-				if numDocs > 1 || (numDocs == 1 && buf.Len() > 0) {
-					return ErrMultipleYAMLDocs
-				}
-			}
+		var doc []byte
+		if len(docs) == 1 {
+			doc = docs[0]
+		}
 
-			if _, err := buf.Write(line); err != nil {
-				return fmt.Errorf("failed to buffer YAML data: %w", err)
-			}
-			_ = buf.WriteByte(newline)
+		return decodeYAMLDoc(doc, dest, opts)
+	}
+}
+
+// splitYAMLDocs reads a YAML stream and returns the documents separated by "---" lines.
+// Comment lines and empty lines at the beginning of the stream are dropped.
+func splitYAMLDocs(src io.Reader) ([][]byte, error) {
+	var docs [][]byte
+	buf := new(bytes.Buffer)
+
+	s := bufio.NewScanner(src)
+	seenContent := false
+	for s.Scan() {
+		line := s.Bytes()
+		trimmedLine := bytes.TrimSpace(line)
+
+		// ignore comments
+		if bytes.HasPrefix(trimmedLine, yamlComment) {
+			continue
 		}
 
-		if err := s.Err(); err != nil {
-			return fmt.Errorf("failed to read from source: %w", err)
+		// ignore empty lines at the beginning of the file
+		if !seenContent && len(trimmedLine) == 0 {
+			continue
 		}
+		seenContent = true
 
-		jsonBytes, err := yaml.YAMLToJSON(buf.Bytes())
-		if err != nil {
-			return fmt.Errorf("failed to convert YAML to JSON: %w", err)
+		if bytes.HasPrefix(line, yamlSep) && buf.Len() > 0 {
+			docs = append(docs, buf.Bytes())
+			buf = new(bytes.Buffer)
 		}
 
-		if err := protojson.Unmarshal(jsonBytes, dest); err != nil {
-			return fmt.Errorf("failed to unmarshal JSON: %w", err)
+		if _, err := buf.Write(line); err != nil {
+			return nil, fmt.Errorf("failed to buffer YAML data: %w", err)
 		}
+		_ = buf.WriteByte(newline)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read from source: %w", err)
+	}
+
+	if buf.Len() > 0 {
+		docs = append(docs, buf.Bytes())
+	}
+
+	return docs, nil
+}
+
+// isEmptyYAMLDoc returns true if the document only consists of the separator and blank lines.
+func isEmptyYAMLDoc(doc []byte) bool {
+	return len(bytes.TrimSpace(bytes.TrimPrefix(doc, yamlSep))) == 0
+}
+
+func decodeYAMLDoc(doc []byte, dest proto.Message, opts protojson.UnmarshalOptions) error {
+	yamlBytes, err := resolveYAMLTags(doc)
+	if err != nil {
+		return err
+	}
+
+	jsonBytes, err := yaml.YAMLToJSON(yamlBytes)
+	if err != nil {
+		return fmt.Errorf("failed to convert YAML to JSON: %w", err)
+	}
+
+	if err := opts.Unmarshal(jsonBytes, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return nil
+}
+
+// resolveYAMLTags checks that the document only uses tags that can be converted to JSON and rewrites values tagged with
+// !!binary as strings containing the base64-encoded data, which is how binary data is represented in JSON.
+// Without this, the YAML to JSON conversion would silently decode binary values to raw bytes and drop unknown tags.
+//
+// Supported tags are the standard tags !!str, !!int, !!float, !!bool, !!null, !!timestamp, !!map, !!seq, !!merge and !!binary.
+// Other tags, including custom tags such as !secret and the !!set, !!omap and !!pairs collection tags, are rejected.
+func resolveYAMLTags(doc []byte) ([]byte, error) {
+	if !bytes.Contains(doc, yamlTagIndicator) {
+		return doc, nil
+	}
+
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(doc, &root); err != nil {
+		// Leave reporting syntax errors to the YAML to JSON conversion.
+		return doc, nil //nolint:nilerr
+	}
+
+	rewrite := false
+	var walk func(*yamlv3.Node) error
+	walk = func(n *yamlv3.Node) error {
+		if n.Kind != yamlv3.DocumentNode && n.Kind != yamlv3.AliasNode {
+			if _, ok := supportedYAMLTags[n.Tag]; !ok {
+				return fmt.Errorf("unsupported YAML tag %q at line %d", n.Tag, n.Line)
+			}
+		}
+
+		if n.Tag == "!!binary" {
+			value := strings.Join(strings.Fields(n.Value), "")
+			if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+				return fmt.Errorf("invalid !!binary value at line %d: %w", n.Line, err)
+			}
+
+			n.Tag, n.Value, n.Style = "!!str", value, yamlv3.DoubleQuotedStyle
+			rewrite = true
+		}
+
+		for _, c := range n.Content {
+			if err := walk(c); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	}
+
+	if err := walk(&root); err != nil {
+		return nil, err
+	}
+
+	if !rewrite {
+		return doc, nil
+	}
+
+	out, err := yamlv3.Marshal(&root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite YAML tags: %w", err)
+	}
+
+	return out, nil
 }
 
 func ReadSchemaFromFile(fsys fs.FS, path string) (*schemav1.Schema, error) {
@@ -0,0 +1,202 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package internal_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+const policyWithUnknownField = `---
+apiVersion: api.cerbos.dev/v1
+resourcePolicy:
+  resource: leave_request
+  version: default
+  someFutureField: true
+  rules:
+    - actions: ["view"]
+      effect: EFFECT_ALLOW
+      roles: ["employee"]
+`
+
+const jsonPolicyWithUnknownField = `{
+  "apiVersion": "api.cerbos.dev/v1",
+  "resourcePolicy": {
+    "resource": "leave_request",
+    "version": "default",
+    "someFutureField": true,
+    "rules": [{"actions": ["view"], "effect": "EFFECT_ALLOW", "roles": ["employee"]}]
+  }
+}`
+
+func TestReadPolicyLenient(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{name: "yaml", input: policyWithUnknownField},
+		{name: "json", input: jsonPolicyWithUnknownField},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := internal.ReadPolicy(strings.NewReader(tc.input))
+			require.Error(t, err)
+
+			p, err := internal.ReadPolicyLenient(strings.NewReader(tc.input))
+			require.NoError(t, err)
+			require.Equal(t, "leave_request", p.GetResourcePolicy().GetResource())
+			require.Len(t, p.GetResourcePolicy().GetRules(), 1)
+		})
+	}
+}
+
+func TestIsPolicyFile(t *testing.T) {
+	testCases := []struct {
+		path string
+		want bool
+	}{
+		{path: "policy.yaml", want: true},
+		{path: "dir/policy.yml", want: true},
+		{path: "dir/policy.json", want: true},
+		{path: "dir/.hidden.yaml", want: false},
+		{path: "dir/policy_test.yaml", want: false},
+		{path: "dir/README.md", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			require.Equal(t, tc.want, internal.IsPolicyFile(tc.path))
+		})
+	}
+}
+
+func TestReadPolicyTooLarge(t *testing.T) {
+	const maxFileSize = 4 * 1024 * 1024
+
+	// Pad the policies with trailing newlines so that they are otherwise valid.
+	pad := func(policy string, size int) string {
+		return policy + strings.Repeat("\n", size-len(policy))
+	}
+
+	jsonPolicy := strings.Replace(jsonPolicyWithUnknownField, `"someFutureField": true,`, "", 1)
+	yamlPolicy := strings.Replace(policyWithUnknownField, "  someFutureField: true\n", "", 1)
+
+	testCases := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "json_at_limit", input: pad(jsonPolicy, maxFileSize)},
+		{name: "json_over_limit", input: pad(jsonPolicy, maxFileSize+1), wantErr: true},
+		{name: "yaml_at_limit", input: pad(yamlPolicy, maxFileSize)},
+		{name: "yaml_over_limit", input: pad(yamlPolicy, maxFileSize+1), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := internal.ReadPolicy(strings.NewReader(tc.input))
+			if tc.wantErr {
+				require.ErrorIs(t, err, internal.ErrFileTooLarge)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestReadPolicyYAMLTags(t *testing.T) {
+	const policyTmpl = `---
+apiVersion: api.cerbos.dev/v1
+metadata:
+  annotations:
+    key: %s
+resourcePolicy:
+  resource: leave_request
+  version: !!str 20210210
+  rules:
+    - actions: ["view"]
+      effect: EFFECT_ALLOW
+      roles: ["employee"]
+`
+
+	testCases := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "str", value: "!!str true", want: "true"},
+		{name: "binary", value: "!!binary aGVsbG8gd29ybGQ=", want: "aGVsbG8gd29ybGQ="},
+		{name: "binary_block", value: "!!binary |\n      aGVsbG8g\n      d29ybGQ=", want: "aGVsbG8gd29ybGQ="},
+		{name: "invalid_binary", value: "!!binary not_base64", wantErr: "invalid !!binary value at line 5"},
+		{name: "custom_tag", value: "!secret foo", wantErr: `unsupported YAML tag "!secret" at line 5`},
+		{name: "set", value: "!!set {a, b}", wantErr: `unsupported YAML tag "!!set" at line 5`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := internal.ReadPolicy(strings.NewReader(fmt.Sprintf(policyTmpl, tc.value)))
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, p.GetMetadata().GetAnnotations()["key"])
+			require.Equal(t, "20210210", p.GetResourcePolicy().GetVersion())
+		})
+	}
+}
+
+func TestReadPolicies(t *testing.T) {
+	const multiDoc = `# Leave request policies
+
+---
+apiVersion: api.cerbos.dev/v1
+derivedRoles:
+  name: common_roles
+  definitions:
+    - name: owner
+      parentRoles: ["user"]
+---
+# The resource policy
+apiVersion: api.cerbos.dev/v1
+resourcePolicy:
+  resource: leave_request
+  version: default
+  importDerivedRoles: ["common_roles"]
+  rules:
+    - actions: ["view"]
+      effect: EFFECT_ALLOW
+      derivedRoles: ["owner"]
+---
+`
+
+	policies, err := internal.ReadPolicies(strings.NewReader(multiDoc))
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+	require.Equal(t, "common_roles", policies[0].GetDerivedRoles().GetName())
+	require.Equal(t, "leave_request", policies[1].GetResourcePolicy().GetResource())
+
+	_, err = internal.ReadPolicy(strings.NewReader(multiDoc))
+	require.ErrorIs(t, err, internal.ErrMultipleYAMLDocs)
+
+	jsonPolicy := strings.Replace(jsonPolicyWithUnknownField, `"someFutureField": true,`, "", 1)
+	policies, err = internal.ReadPolicies(strings.NewReader(jsonPolicy))
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+
+	_, err = internal.ReadPolicies(strings.NewReader(multiDoc + "apiVersion: api.cerbos.dev/v1\nfoo: bar\n"))
+	require.ErrorContains(t, err, "failed to read document 2")
+
+	_, err = internal.ReadPolicies(strings.NewReader("# nothing here\n---\n"))
+	require.Error(t, err)
+}
@@ -5,6 +5,7 @@ package internal
 
 import (
 	"context"
+	"time"
 
 	"google.golang.org/grpc/metadata"
 
@@ -14,9 +15,14 @@ import (
 
 type ReqOpt struct {
 	AuxData            *requestv1.AuxData
+	AuxDataJWTCtxKey   any
 	Metadata           metadata.MD
 	RequestIDGenerator func(context.Context) string
+	PolicyVersion      string
+	DefaultAction      string
+	DetachedTimeout    time.Duration
 	IncludeMeta        bool
+	StrictValidation   bool
 }
 
 func (o *ReqOpt) Context(ctx context.Context) context.Context {
@@ -24,14 +30,71 @@ func (o *ReqOpt) Context(ctx context.Context) context.Context {
 		return ctx
 	}
 
-	return metadata.NewOutgoingContext(ctx, o.Metadata)
+	// Merge with the metadata already attached to the context, replacing the values of keys set by the options.
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return metadata.NewOutgoingContext(ctx, o.Metadata)
+	}
+
+	md = md.Copy()
+	for k, v := range o.Metadata {
+		md[k] = v
+	}
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// AuxDataFor returns the auxiliary data to send with a request made with the given context.
+// Auxiliary data set explicitly takes precedence over a JWT read from the context.
+func (o *ReqOpt) AuxDataFor(ctx context.Context) *requestv1.AuxData {
+	if o == nil {
+		return nil
+	}
+
+	if o.AuxData != nil || o.AuxDataJWTCtxKey == nil {
+		return o.AuxData
+	}
+
+	token, ok := ctx.Value(o.AuxDataJWTCtxKey).(string)
+	if !ok || token == "" {
+		return nil
+	}
+
+	return &requestv1.AuxData{Jwt: &requestv1.AuxData_JWT{Token: token}}
 }
 
 func (o *ReqOpt) RequestID(ctx context.Context) string {
 	if o != nil && o.RequestIDGenerator != nil {
-		return o.RequestIDGenerator(ctx)
+		if reqID := o.RequestIDGenerator(ctx); reqID != "" {
+			return reqID
+		}
 	}
 
 	reqID := xid.New()
 	return reqID.String()
 }
+
+// DetachContext returns a context that carries the values of the parent context but is never cancelled and has no deadline.
+func DetachContext(parent context.Context) context.Context {
+	return detachedContext{parent: parent}
+}
+
+type detachedContext struct {
+	parent context.Context //nolint:containedctx
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (dc detachedContext) Value(key any) any {
+	return dc.parent.Value(key)
+}
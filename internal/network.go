@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"runtime"
 	"runtime/debug"
+	"sync"
 )
 
 func DefaultTLSConfig() *tls.Config {
@@ -31,16 +32,56 @@ func DefaultTLSConfig() *tls.Config {
 	}
 }
 
+const (
+	sdkModulePath     = "github.com/cerbos/cerbos-sdk-go"
+	unknownSDKVersion = "unknown"
+)
+
+var (
+	sdkVersionOnce sync.Once
+	sdkVersionStr  string
+)
+
+func sdkVersion() string {
+	sdkVersionOnce.Do(func() {
+		sdkVersionStr = unknownSDKVersion
+		if info, ok := debug.ReadBuildInfo(); ok {
+			sdkVersionStr = SDKVersion(info)
+		}
+	})
+
+	return sdkVersionStr
+}
+
 func UserAgent(impl string) string {
-	version := "unknown"
-	if info, ok := debug.ReadBuildInfo(); ok {
-		for _, dep := range info.Deps {
-			if dep.Path == "github.com/cerbos/cerbos-sdk-go" {
-				version = dep.Version
-				break
-			}
+	return fmt.Sprintf("cerbos-sdk-go/%s-%s (%s; %s)", impl, sdkVersion(), runtime.GOOS, runtime.GOARCH)
+}
+
+// SDKVersion returns the version of the SDK module recorded in the given build info.
+// If the module is replaced, the version of the replacement is used. Returns "unknown" if the version can't be determined,
+// which is the case when the SDK itself is the main module (such as when running its tests).
+func SDKVersion(info *debug.BuildInfo) string {
+	moduleVersion := func(m *debug.Module) string {
+		for m.Replace != nil {
+			m = m.Replace
+		}
+
+		if m.Version == "" || m.Version == "(devel)" {
+			return unknownSDKVersion
+		}
+
+		return m.Version
+	}
+
+	if info.Main.Path == sdkModulePath {
+		return moduleVersion(&info.Main)
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == sdkModulePath {
+			return moduleVersion(dep)
 		}
 	}
 
-	return fmt.Sprintf("cerbos-sdk-go/%s-%s (%s; %s)", impl, version, runtime.GOOS, runtime.GOARCH)
+	return unknownSDKVersion
 }
@@ -0,0 +1,62 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package internal_test
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+func TestSDKVersion(t *testing.T) {
+	const sdk = "github.com/cerbos/cerbos-sdk-go"
+
+	testCases := []struct {
+		name string
+		info *debug.BuildInfo
+		want string
+	}{
+		{
+			name: "dependency",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/app"},
+				Deps: []*debug.Module{{Path: "github.com/rs/xid", Version: "v1.5.0"}, {Path: sdk, Version: "v0.2.5"}},
+			},
+			want: "v0.2.5",
+		},
+		{
+			name: "replaced",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/app"},
+				Deps: []*debug.Module{{Path: sdk, Version: "v0.2.5", Replace: &debug.Module{Path: "example.com/fork", Version: "v0.2.6-fork"}}},
+			},
+			want: "v0.2.6-fork",
+		},
+		{
+			name: "main_module",
+			info: &debug.BuildInfo{Main: debug.Module{Path: sdk, Version: "(devel)"}},
+			want: "unknown",
+		},
+		{
+			name: "missing",
+			info: &debug.BuildInfo{Main: debug.Module{Path: "example.com/app"}},
+			want: "unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, internal.SDKVersion(tc.info))
+		})
+	}
+
+	ua := internal.UserAgent("grpc")
+	require.True(t, strings.HasPrefix(ua, "cerbos-sdk-go/grpc-"))
+	require.Contains(t, ua, runtime.GOOS)
+}
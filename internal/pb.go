@@ -4,6 +4,7 @@
 package internal
 
 import (
+	"math"
 	"reflect"
 	"time"
 
@@ -11,35 +12,56 @@ import (
 )
 
 func ToStructPB(v any) (*structpb.Value, error) {
+	return ToStructPBWithTimeEncoder(v, nil)
+}
+
+// ToStructPBWithTimeEncoder converts the value to a protobuf value using the given function to encode any time.Time values,
+// including those nested in slices and maps. Times are encoded as RFC3339 strings if the function is nil.
+func ToStructPBWithTimeEncoder(v any, encodeTime func(time.Time) any) (*structpb.Value, error) {
+	if t, ok := v.(time.Time); ok {
+		if encodeTime == nil {
+			return structpb.NewStringValue(t.Format(time.RFC3339)), nil
+		}
+
+		return structpb.NewValue(encodeTime(t))
+	}
+
 	val, err := structpb.NewValue(v)
 	if err == nil {
 		return val, nil
 	}
 
-	if t, ok := v.(time.Time); ok {
-		return structpb.NewStringValue(t.Format(time.RFC3339)), nil
-	}
-
 	vv := reflect.ValueOf(v)
 	switch vv.Kind() {
 	case reflect.Array, reflect.Slice:
-		arr := make([]any, vv.Len())
+		list := &structpb.ListValue{Values: make([]*structpb.Value, vv.Len())}
 		for i := 0; i < vv.Len(); i++ {
-			el := vv.Index(i)
-			arr[i] = el.Interface()
+			el, err := ToStructPBWithTimeEncoder(vv.Index(i).Interface(), encodeTime)
+			if err != nil {
+				return nil, err
+			}
+			list.Values[i] = el
 		}
 
-		return structpb.NewValue(arr)
+		return structpb.NewListValue(list), nil
 	case reflect.Map:
 		if vv.Type().Key().Kind() == reflect.String {
-			m := make(map[string]any)
+			st := &structpb.Struct{Fields: make(map[string]*structpb.Value, vv.Len())}
 
 			iter := vv.MapRange()
 			for iter.Next() {
-				m[iter.Key().String()] = iter.Value().Interface()
+				el, err := ToStructPBWithTimeEncoder(iter.Value().Interface(), encodeTime)
+				if err != nil {
+					return nil, err
+				}
+				st.Fields[iter.Key().String()] = el
 			}
 
-			return structpb.NewValue(m)
+			return structpb.NewStructValue(st), nil
+		}
+	case reflect.Pointer:
+		if t, ok := v.(*time.Time); ok && t != nil {
+			return ToStructPBWithTimeEncoder(*t, encodeTime)
 		}
 	default:
 		return nil, err
@@ -47,3 +69,58 @@ func ToStructPB(v any) (*structpb.Value, error) {
 
 	return nil, err
 }
+
+// AttrString returns the string value of the attribute with the given key.
+func AttrString(attr map[string]*structpb.Value, key string) (string, bool) {
+	v, ok := attr[key].GetKind().(*structpb.Value_StringValue)
+	if !ok {
+		return "", false
+	}
+
+	return v.StringValue, true
+}
+
+// AttrInt returns the value of the attribute with the given key if it is a number with no fractional part.
+func AttrInt(attr map[string]*structpb.Value, key string) (int64, bool) {
+	v, ok := attr[key].GetKind().(*structpb.Value_NumberValue)
+	if !ok {
+		return 0, false
+	}
+
+	n := v.NumberValue
+	if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+		return 0, false
+	}
+
+	return int64(n), true
+}
+
+// AttrBool returns the boolean value of the attribute with the given key.
+func AttrBool(attr map[string]*structpb.Value, key string) (bool, bool) {
+	v, ok := attr[key].GetKind().(*structpb.Value_BoolValue)
+	if !ok {
+		return false, false
+	}
+
+	return v.BoolValue, true
+}
+
+// AttrStringSlice returns the value of the attribute with the given key if it is a list consisting only of strings.
+func AttrStringSlice(attr map[string]*structpb.Value, key string) ([]string, bool) {
+	v, ok := attr[key].GetKind().(*structpb.Value_ListValue)
+	if !ok {
+		return nil, false
+	}
+
+	values := v.ListValue.GetValues()
+	out := make([]string, len(values))
+	for i, item := range values {
+		s, ok := item.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return nil, false
+		}
+		out[i] = s.StringValue
+	}
+
+	return out, true
+}
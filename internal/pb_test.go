@@ -0,0 +1,92 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+func TestAttrGetters(t *testing.T) {
+	attr := make(map[string]*structpb.Value)
+	for k, v := range map[string]any{
+		"str":      "foo",
+		"int":      42,
+		"float":    1.5,
+		"bool":     true,
+		"strSlice": []any{"a", "b"},
+		"mixed":    []any{"a", 1},
+	} {
+		pbVal, err := internal.ToStructPB(v)
+		require.NoError(t, err)
+		attr[k] = pbVal
+	}
+
+	testCases := []struct {
+		name   string
+		get    func(string) (any, bool)
+		key    string
+		want   any
+		wantOK bool
+	}{
+		{name: "string", get: wrap(internal.AttrString, attr), key: "str", want: "foo", wantOK: true},
+		{name: "string_mismatch", get: wrap(internal.AttrString, attr), key: "int", want: "", wantOK: false},
+		{name: "int", get: wrap(internal.AttrInt, attr), key: "int", want: int64(42), wantOK: true},
+		{name: "int_fractional", get: wrap(internal.AttrInt, attr), key: "float", want: int64(0), wantOK: false},
+		{name: "bool", get: wrap(internal.AttrBool, attr), key: "bool", want: true, wantOK: true},
+		{name: "bool_missing", get: wrap(internal.AttrBool, attr), key: "missing", want: false, wantOK: false},
+		{name: "string_slice", get: wrap(internal.AttrStringSlice, attr), key: "strSlice", want: []string{"a", "b"}, wantOK: true},
+		{name: "string_slice_mixed", get: wrap(internal.AttrStringSlice, attr), key: "mixed", want: []string(nil), wantOK: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			have, ok := tc.get(tc.key)
+			require.Equal(t, tc.wantOK, ok)
+			require.Equal(t, tc.want, have)
+		})
+	}
+
+	_, ok := internal.AttrString(nil, "str")
+	require.False(t, ok)
+}
+
+func wrap[T any](fn func(map[string]*structpb.Value, string) (T, bool), attr map[string]*structpb.Value) func(string) (any, bool) {
+	return func(key string) (any, bool) {
+		return fn(attr, key)
+	}
+}
+
+func TestToStructPBWithTimeEncoder(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	input := map[string]any{
+		"at":   ts,
+		"list": []time.Time{ts},
+	}
+
+	testCases := []struct {
+		encoder func(time.Time) any
+		want    any
+		name    string
+	}{
+		{name: "default", want: "2024-01-02T03:04:05Z"},
+		{name: "unix", encoder: func(t time.Time) any { return t.Unix() }, want: float64(ts.Unix())},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			have, err := internal.ToStructPBWithTimeEncoder(input, tc.encoder)
+			require.NoError(t, err)
+
+			m := have.GetStructValue().AsMap()
+			require.Equal(t, tc.want, m["at"])
+			require.Equal(t, []any{tc.want}, m["list"])
+		})
+	}
+}
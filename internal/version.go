@@ -0,0 +1,71 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const semVerParts = 3
+
+// SemVer is a semantic version reduced to its major, minor and patch components.
+type SemVer struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseSemVer parses a version string such as v0.36.0 or 0.36.0-prerelease.
+// Pre-release and build metadata suffixes are ignored.
+func ParseSemVer(version string) (SemVer, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if idx := strings.IndexAny(v, "-+"); idx >= 0 {
+		v = v[:idx]
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) != semVerParts {
+		return SemVer{}, fmt.Errorf("invalid version %q", version)
+	}
+
+	var nums [semVerParts]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return SemVer{}, fmt.Errorf("invalid version %q", version)
+		}
+		nums[i] = n
+	}
+
+	return SemVer{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+// Compare returns -1, 0 or 1 depending on whether v is older than, equal to or newer than other.
+func (v SemVer) Compare(other SemVer) int {
+	switch {
+	case v.Major != other.Major:
+		return cmpInt(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return cmpInt(v.Minor, other.Minor)
+	default:
+		return cmpInt(v.Patch, other.Patch)
+	}
+}
+
+func (v SemVer) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
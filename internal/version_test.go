@@ -0,0 +1,45 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/internal"
+)
+
+func TestSemVer(t *testing.T) {
+	testCases := []struct {
+		a       string
+		b       string
+		want    int
+		wantErr bool
+	}{
+		{a: "0.36.0", b: "0.36.0", want: 0},
+		{a: "v0.36.0", b: "0.35.9", want: 1},
+		{a: "0.36.0-prerelease", b: "0.36.0", want: 0},
+		{a: "0.9.1", b: "0.10.0", want: -1},
+		{a: "1.0.0+build.1", b: "0.99.99", want: 1},
+		{a: "dev", b: "0.36.0", wantErr: true},
+		{a: "0.36", b: "0.36.0", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			a, err := internal.ParseSemVer(tc.a)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			b, err := internal.ParseSemVer(tc.b)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.want, a.Compare(b))
+		})
+	}
+}
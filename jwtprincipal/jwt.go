@@ -0,0 +1,157 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package jwtprincipal builds Cerbos principals from the claims of JSON Web Tokens.
+package jwtprincipal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+)
+
+const defaultIDClaim = "sub"
+
+// ClaimMapping describes how the claims of a token map to the principal.
+// Claims are referenced by path: nested claims are separated by dots, for example realm_access.roles.
+type ClaimMapping struct {
+	// Attributes maps principal attribute names to claim paths. Claims that are not present in the token are skipped.
+	Attributes map[string]string
+	// ID is the path of the claim holding the principal ID. Defaults to sub.
+	ID string
+	// Roles is the path of the claim holding the roles. The claim can be an array of strings or a string of space-separated roles,
+	// such as the scope claim of OAuth 2.0 access tokens.
+	Roles string
+	// DefaultRoles are the roles given to the principal if the token doesn't have the roles claim.
+	DefaultRoles []string
+}
+
+type config struct {
+	keySet jwk.Set
+}
+
+type Opt func(*config)
+
+// WithKeySet verifies the signature of the token using the given key set.
+func WithKeySet(keySet jwk.Set) Opt {
+	return func(c *config) {
+		c.keySet = keySet
+	}
+}
+
+// PrincipalFromJWT parses the token and builds a principal from its claims according to the mapping.
+// The expiry and not-before times of the token are always validated. The signature is only verified if a key set
+// is provided using WithKeySet: only skip verification if the token has already been verified, for example by an API gateway.
+func PrincipalFromJWT(token string, mapping ClaimMapping, opts ...Opt) (*cerbos.Principal, error) {
+	conf := &config{}
+	for _, o := range opts {
+		o(conf)
+	}
+
+	parseOpts := []jwt.ParseOption{jwt.WithValidate(true)}
+	if conf.keySet != nil {
+		parseOpts = append(parseOpts, jwt.WithKeySet(conf.keySet))
+	} else {
+		parseOpts = append(parseOpts, jwt.WithVerify(false))
+	}
+
+	parsed, err := jwt.ParseString(token, parseOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	claims, err := parsed.AsMap(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token claims: %w", err)
+	}
+
+	idClaim := mapping.ID
+	if idClaim == "" {
+		idClaim = defaultIDClaim
+	}
+
+	idVal, ok := lookup(claims, idClaim)
+	if !ok {
+		return nil, fmt.Errorf("token does not have the %q claim", idClaim)
+	}
+
+	id, ok := idVal.(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("claim %q is not a non-empty string", idClaim)
+	}
+
+	roles := mapping.DefaultRoles
+	if mapping.Roles != "" {
+		if rolesVal, ok := lookup(claims, mapping.Roles); ok {
+			if roles, err = toRoles(rolesVal); err != nil {
+				return nil, fmt.Errorf("invalid roles claim %q: %w", mapping.Roles, err)
+			}
+		}
+	}
+
+	if len(roles) == 0 {
+		return nil, errors.New("principal has no roles")
+	}
+
+	principal := cerbos.NewPrincipal(id, roles...)
+	for attr, path := range mapping.Attributes {
+		if v, ok := lookup(claims, path); ok {
+			principal.WithAttr(attr, v)
+		}
+	}
+
+	if err := principal.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid principal: %w", err)
+	}
+
+	return principal, nil
+}
+
+// lookup finds the value of the claim at the given path.
+func lookup(claims map[string]any, path string) (any, bool) {
+	// Claim names can contain dots, so try the full path first.
+	if v, ok := claims[path]; ok {
+		return v, true
+	}
+
+	var current any = claims
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func toRoles(v any) ([]string, error) {
+	switch rv := v.(type) {
+	case string:
+		return strings.Fields(rv), nil
+	case []string:
+		return rv, nil
+	case []any:
+		roles := make([]string, len(rv))
+		for i, r := range rv {
+			s, ok := r.(string)
+			if !ok {
+				return nil, fmt.Errorf("role at index %d is a %T, not a string", i, r)
+			}
+			roles[i] = s
+		}
+		return roles, nil
+	default:
+		return nil, fmt.Errorf("expected a string or an array of strings, got %T", v)
+	}
+}
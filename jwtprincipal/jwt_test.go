@@ -0,0 +1,150 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package jwtprincipal_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cerbos/cerbos-sdk-go/jwtprincipal"
+)
+
+func mkKey(t *testing.T, id string) jwk.Key {
+	t.Helper()
+
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	key, err := jwk.FromRaw(raw)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, id))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.ES256))
+
+	return key
+}
+
+func mkToken(t *testing.T, key jwk.Key, claims map[string]any) string {
+	t.Helper()
+
+	token := jwt.New()
+	for k, v := range claims {
+		require.NoError(t, token.Set(k, v))
+	}
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, key))
+	require.NoError(t, err)
+
+	return string(signed)
+}
+
+func TestPrincipalFromJWT(t *testing.T) {
+	key := mkKey(t, "key-1")
+	pubKey, err := key.PublicKey()
+	require.NoError(t, err)
+
+	keySet := jwk.NewSet()
+	require.NoError(t, keySet.AddKey(pubKey))
+
+	mapping := jwtprincipal.ClaimMapping{
+		Roles:      "realm_access.roles",
+		Attributes: map[string]string{"department": "org.department", "email": "email"},
+	}
+
+	t.Run("array_roles", func(t *testing.T) {
+		token := mkToken(t, key, map[string]any{
+			"sub":          "john",
+			"email":        "john@example.com",
+			"realm_access": map[string]any{"roles": []string{"employee", "manager"}},
+			"org":          map[string]any{"department": "marketing"},
+		})
+
+		p, err := jwtprincipal.PrincipalFromJWT(token, mapping, jwtprincipal.WithKeySet(keySet))
+		require.NoError(t, err)
+		require.Equal(t, "john", p.ID())
+		require.Equal(t, []string{"employee", "manager"}, p.Roles())
+
+		dept, ok := p.AttrString("department")
+		require.True(t, ok)
+		require.Equal(t, "marketing", dept)
+
+		email, ok := p.AttrString("email")
+		require.True(t, ok)
+		require.Equal(t, "john@example.com", email)
+	})
+
+	t.Run("string_roles", func(t *testing.T) {
+		token := mkToken(t, key, map[string]any{"client_id": "reporting-service", "scope": "reader auditor"})
+
+		p, err := jwtprincipal.PrincipalFromJWT(token, jwtprincipal.ClaimMapping{ID: "client_id", Roles: "scope"})
+		require.NoError(t, err)
+		require.Equal(t, "reporting-service", p.ID())
+		require.Equal(t, []string{"reader", "auditor"}, p.Roles())
+	})
+
+	t.Run("default_roles", func(t *testing.T) {
+		token := mkToken(t, key, map[string]any{"sub": "john"})
+
+		p, err := jwtprincipal.PrincipalFromJWT(token, jwtprincipal.ClaimMapping{Roles: "roles", DefaultRoles: []string{"user"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user"}, p.Roles())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		otherKey := mkKey(t, "key-1")
+
+		testCases := []struct {
+			name    string
+			token   string
+			mapping jwtprincipal.ClaimMapping
+			wantErr string
+		}{
+			{
+				name:    "wrong_key",
+				token:   mkToken(t, otherKey, map[string]any{"sub": "john", "roles": []string{"user"}}),
+				mapping: jwtprincipal.ClaimMapping{Roles: "roles"},
+				wantErr: "invalid token",
+			},
+			{
+				name:    "expired",
+				token:   mkToken(t, key, map[string]any{"sub": "john", "roles": []string{"user"}, jwt.ExpirationKey: time.Now().Add(-time.Hour)}),
+				mapping: jwtprincipal.ClaimMapping{Roles: "roles"},
+				wantErr: "invalid token",
+			},
+			{
+				name:    "missing_id",
+				token:   mkToken(t, key, map[string]any{"roles": []string{"user"}}),
+				mapping: jwtprincipal.ClaimMapping{Roles: "roles"},
+				wantErr: `token does not have the "sub" claim`,
+			},
+			{
+				name:    "no_roles",
+				token:   mkToken(t, key, map[string]any{"sub": "john"}),
+				mapping: jwtprincipal.ClaimMapping{Roles: "roles"},
+				wantErr: "principal has no roles",
+			},
+			{
+				name:    "invalid_roles",
+				token:   mkToken(t, key, map[string]any{"sub": "john", "roles": 42}),
+				mapping: jwtprincipal.ClaimMapping{Roles: "roles"},
+				wantErr: `invalid roles claim "roles"`,
+			},
+		}
+
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := jwtprincipal.PrincipalFromJWT(tc.token, tc.mapping, jwtprincipal.WithKeySet(keySet))
+				require.ErrorContains(t, err, tc.wantErr)
+			})
+		}
+	})
+}
@@ -0,0 +1,103 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package graphql converts query plans produced by the Cerbos PlanResources API into GraphQL filter arguments.
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	"github.com/cerbos/cerbos-sdk-go/query/internal/planwalk"
+)
+
+var comparisonOps = map[string]string{
+	"eq": "_eq",
+	"ne": "_neq",
+	"lt": "_lt",
+	"le": "_lte",
+	"gt": "_gt",
+	"ge": "_gte",
+}
+
+// ToGraphQLFilter converts the query plan in the response to a filter object following the Hasura-style boolean expression
+// conventions used by many GraphQL servers for where arguments, such as {"_and": [{"owner": {"_eq": "john"}}, ...]}.
+//
+// The mapping translates Cerbos attribute paths such as request.resource.attr.owner to GraphQL field paths.
+// Dots in the field path denote nested objects, so author.id produces {"author": {"id": {...}}}.
+// Attributes under request.resource.attr that are not in the mapping use the attribute name as the field path.
+//
+// An always allowed plan produces an empty filter, which matches every row. An always denied plan produces {"_not": {}},
+// which matches no rows. An error is returned if the plan contains operators or variables that can't be represented,
+// including and and or expressions without operands.
+// Supported operators are and, or, not, eq, ne, lt, le, gt, ge and in.
+func ToGraphQLFilter(resp *cerbos.PlanResourcesResponse, mapping map[string]string) (map[string]any, error) {
+	if resp == nil || resp.PlanResourcesResponse == nil {
+		return nil, errors.New("response is nil")
+	}
+
+	switch resp.Kind() {
+	case enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED:
+		return map[string]any{}, nil
+	case enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED:
+		return map[string]any{"_not": map[string]any{}}, nil
+	case enginev1.PlanResourcesFilter_KIND_CONDITIONAL:
+		return planwalk.Walk[map[string]any](resp.GetFilter().GetCondition(), converter{mapping: mapping})
+	default:
+		return nil, fmt.Errorf("unexpected filter kind %s", resp.Kind())
+	}
+}
+
+type converter struct {
+	mapping map[string]string
+}
+
+func (converter) And(operands []map[string]any) map[string]any {
+	return map[string]any{"_and": toAnySlice(operands)}
+}
+
+func (converter) Or(operands []map[string]any) map[string]any {
+	return map[string]any{"_or": toAnySlice(operands)}
+}
+
+func (converter) Not(operand map[string]any) map[string]any {
+	return map[string]any{"_not": operand}
+}
+
+func (c converter) In(variable string, values []any) (map[string]any, error) {
+	return c.fieldFilter(variable, "_in", values)
+}
+
+func (c converter) Compare(variable, op string, value any) (map[string]any, error) {
+	return c.fieldFilter(variable, comparisonOps[op], value)
+}
+
+func toAnySlice(filters []map[string]any) []any {
+	out := make([]any, len(filters))
+	for i, f := range filters {
+		out[i] = f
+	}
+	return out
+}
+
+func (c converter) fieldFilter(variable, op string, value any) (map[string]any, error) {
+	path, ok := c.mapping[variable]
+	if !ok {
+		if !strings.HasPrefix(variable, planwalk.ResourceAttrPrefix) {
+			return nil, fmt.Errorf("no mapping for variable %q", variable)
+		}
+		path = strings.TrimPrefix(variable, planwalk.ResourceAttrPrefix)
+	}
+
+	var filter any = map[string]any{op: value}
+	fields := strings.Split(path, ".")
+	for i := len(fields) - 1; i >= 0; i-- {
+		filter = map[string]any{fields[i]: filter}
+	}
+
+	return filter.(map[string]any), nil //nolint:forcetypeassert
+}
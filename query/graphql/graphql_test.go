@@ -0,0 +1,131 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	"github.com/cerbos/cerbos-sdk-go/query/graphql"
+)
+
+func expr(op string, operands ...*enginev1.PlanResourcesFilter_Expression_Operand) *enginev1.PlanResourcesFilter_Expression_Operand {
+	return &enginev1.PlanResourcesFilter_Expression_Operand{
+		Node: &enginev1.PlanResourcesFilter_Expression_Operand_Expression{
+			Expression: &enginev1.PlanResourcesFilter_Expression{Operator: op, Operands: operands},
+		},
+	}
+}
+
+func variable(name string) *enginev1.PlanResourcesFilter_Expression_Operand {
+	return &enginev1.PlanResourcesFilter_Expression_Operand{
+		Node: &enginev1.PlanResourcesFilter_Expression_Operand_Variable{Variable: name},
+	}
+}
+
+func value(t *testing.T, v any) *enginev1.PlanResourcesFilter_Expression_Operand {
+	t.Helper()
+
+	val, err := structpb.NewValue(v)
+	require.NoError(t, err)
+
+	return &enginev1.PlanResourcesFilter_Expression_Operand{
+		Node: &enginev1.PlanResourcesFilter_Expression_Operand_Value{Value: val},
+	}
+}
+
+func plan(kind enginev1.PlanResourcesFilter_Kind, cond *enginev1.PlanResourcesFilter_Expression_Operand) *cerbos.PlanResourcesResponse {
+	return &cerbos.PlanResourcesResponse{
+		PlanResourcesResponse: &responsev1.PlanResourcesResponse{
+			Filter: &enginev1.PlanResourcesFilter{Kind: kind, Condition: cond},
+		},
+	}
+}
+
+func TestToGraphQLFilter(t *testing.T) {
+	mapping := map[string]string{
+		"request.resource.attr.author": "author.id",
+		"request.principal.id":         "viewer_id",
+	}
+
+	testCases := []struct {
+		name string
+		resp *cerbos.PlanResourcesResponse
+		want map[string]any
+	}{
+		{
+			name: "always_allowed",
+			resp: plan(enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED, nil),
+			want: map[string]any{},
+		},
+		{
+			name: "always_denied",
+			resp: plan(enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED, nil),
+			want: map[string]any{"_not": map[string]any{}},
+		},
+		{
+			name: "logical",
+			resp: plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, expr("or",
+				expr("and",
+					expr("eq", variable("request.resource.attr.author"), value(t, "john")),
+					expr("in", variable("request.resource.attr.status"), value(t, []any{"DRAFT", "PENDING"})),
+				),
+				expr("not", variable("request.resource.attr.archived")),
+			)),
+			want: map[string]any{
+				"_or": []any{
+					map[string]any{
+						"_and": []any{
+							map[string]any{"author": map[string]any{"id": map[string]any{"_eq": "john"}}},
+							map[string]any{"status": map[string]any{"_in": []any{"DRAFT", "PENDING"}}},
+						},
+					},
+					map[string]any{"_not": map[string]any{"archived": map[string]any{"_eq": true}}},
+				},
+			},
+		},
+		{
+			name: "reversed_comparison",
+			resp: plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, expr("lt", value(t, 10), variable("request.resource.attr.size"))),
+			want: map[string]any{"size": map[string]any{"_gt": float64(10)}},
+		},
+		{
+			name: "ne",
+			resp: plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, expr("ne", variable("request.principal.id"), value(t, "jane"))),
+			want: map[string]any{"viewer_id": map[string]any{"_neq": "jane"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			have, err := graphql.ToGraphQLFilter(tc.resp, mapping)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, have)
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for name, cond := range map[string]*enginev1.PlanResourcesFilter_Expression_Operand{
+			"unsupported_operator": expr("hasIntersection", variable("request.resource.attr.tags"), value(t, []any{"a"})),
+			"unmapped_variable":    expr("eq", variable("request.principal.attr.department"), value(t, "marketing")),
+			"in_without_list":      expr("in", variable("request.resource.attr.status"), value(t, "DRAFT")),
+			"two_variables":        expr("eq", variable("request.resource.attr.a"), variable("request.resource.attr.b")),
+			"empty_and":            expr("and"),
+			"empty_or":             expr("or"),
+		} {
+			_, err := graphql.ToGraphQLFilter(plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, cond), mapping)
+			require.Error(t, err, name)
+		}
+
+		_, err := graphql.ToGraphQLFilter(nil, mapping)
+		require.Error(t, err)
+	})
+}
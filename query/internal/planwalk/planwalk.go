@@ -0,0 +1,133 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package planwalk walks the conditions of query plans produced by the Cerbos PlanResources API on behalf of the
+// translators in the query packages, which only have to render each node.
+package planwalk
+
+import (
+	"fmt"
+
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+)
+
+// ResourceAttrPrefix is the prefix of the variables that refer to resource attributes.
+const ResourceAttrPrefix = "request.resource.attr."
+
+// reversedOps holds the comparison operators and the operators to use when the value is on the left of the comparison.
+var reversedOps = map[string]string{
+	"eq": "eq",
+	"ne": "ne",
+	"lt": "gt",
+	"le": "ge",
+	"gt": "lt",
+	"ge": "le",
+}
+
+// Visitor renders the nodes of a condition.
+type Visitor[T any] interface {
+	// And renders the conjunction of at least one rendered operand.
+	And(operands []T) T
+	// Or renders the disjunction of at least one rendered operand.
+	Or(operands []T) T
+	// Not renders the negation of the rendered operand.
+	Not(operand T) T
+	// In renders a check that the variable is one of the values.
+	In(variable string, values []any) (T, error)
+	// Compare renders a comparison of the variable with the value using one of the operators eq, ne, lt, le, gt or ge.
+	// Comparisons with the value on the left are reversed, so the variable is always on the left.
+	Compare(variable, op string, value any) (T, error)
+}
+
+// Walk renders the condition using the visitor. An error is returned if the condition contains unsupported operators
+// or operands. Supported operators are and, or, not, eq, ne, lt, le, gt, ge and in. A bare variable is a boolean attribute.
+func Walk[T any](operand *enginev1.PlanResourcesFilter_Expression_Operand, v Visitor[T]) (T, error) {
+	var zero T
+	switch node := operand.GetNode().(type) {
+	case *enginev1.PlanResourcesFilter_Expression_Operand_Variable:
+		return v.Compare(node.Variable, "eq", true)
+	case *enginev1.PlanResourcesFilter_Expression_Operand_Expression:
+		return walkExpr(node.Expression, v)
+	default:
+		return zero, fmt.Errorf("unsupported operand %v", operand)
+	}
+}
+
+func walkExpr[T any](expr *enginev1.PlanResourcesFilter_Expression, v Visitor[T]) (T, error) {
+	var zero T
+	operands := expr.GetOperands()
+	switch op := expr.GetOperator(); op {
+	case "and", "or":
+		if len(operands) == 0 {
+			return zero, fmt.Errorf("operator %q expects at least 1 operand", op)
+		}
+
+		out := make([]T, len(operands))
+		for i, o := range operands {
+			rendered, err := Walk(o, v)
+			if err != nil {
+				return zero, err
+			}
+			out[i] = rendered
+		}
+
+		if op == "and" {
+			return v.And(out), nil
+		}
+		return v.Or(out), nil
+	case "not":
+		if len(operands) != 1 {
+			return zero, fmt.Errorf("operator %q expects 1 operand, got %d", op, len(operands))
+		}
+
+		rendered, err := Walk(operands[0], v)
+		if err != nil {
+			return zero, err
+		}
+		return v.Not(rendered), nil
+	case "in":
+		variable, value, err := binaryOperands(op, operands, false)
+		if err != nil {
+			return zero, err
+		}
+
+		list, ok := value.([]any)
+		if !ok {
+			return zero, fmt.Errorf("operator %q expects a list value, got %T", op, value)
+		}
+		return v.In(variable, list)
+	default:
+		if _, ok := reversedOps[op]; !ok {
+			return zero, fmt.Errorf("unsupported operator %q", op)
+		}
+
+		variable, value, err := binaryOperands(op, operands, true)
+		if err != nil {
+			return zero, err
+		}
+
+		if operands[0].GetVariable() == "" {
+			op = reversedOps[op]
+		}
+		return v.Compare(variable, op, value)
+	}
+}
+
+// binaryOperands returns the variable and the value of an expression with two operands.
+// If reversible is true, the value may come before the variable.
+func binaryOperands(op string, operands []*enginev1.PlanResourcesFilter_Expression_Operand, reversible bool) (string, any, error) {
+	if len(operands) != 2 { //nolint:mnd
+		return "", nil, fmt.Errorf("operator %q expects 2 operands, got %d", op, len(operands))
+	}
+
+	left, right := operands[0], operands[1]
+	if reversible && left.GetVariable() == "" {
+		left, right = right, left
+	}
+
+	if left.GetVariable() == "" || right.GetValue() == nil {
+		return "", nil, fmt.Errorf("operator %q expects a variable and a value", op)
+	}
+
+	return left.GetVariable(), right.GetValue().AsInterface(), nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package planfilter converts query plans produced by the Cerbos PlanResources API into SQL conditions.
+package planfilter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	"github.com/cerbos/cerbos-sdk-go/query/internal/planwalk"
+)
+
+const (
+	sqlTrue  = "1 = 1"
+	sqlFalse = "1 = 0"
+)
+
+var (
+	comparisonOps = map[string]string{
+		"eq": "=",
+		"ne": "<>",
+		"lt": "<",
+		"le": "<=",
+		"gt": ">",
+		"ge": ">=",
+	}
+
+	identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ToSQL converts the query plan in the response to a SQL condition for use in a WHERE clause, along with the values of
+// its parameters. Values are never inlined in the condition: each one is represented by a ? placeholder, so the condition
+// can be passed to database/sql with the returned arguments. Rewrite the placeholders if the database driver expects
+// a different style, such as $1 for PostgreSQL.
+//
+// The mapping translates Cerbos attribute paths such as request.resource.attr.owner to column names, which are used as given
+// and may include a table name. Attributes under request.resource.attr that are not in the mapping use the attribute name as
+// the column name, provided that it's a plain SQL identifier.
+//
+// An always allowed plan produces a condition that is always true and an always denied plan produces one that is always false.
+// An error is returned if the plan contains operators or variables that can't be represented.
+// Supported operators are and, or, not, eq, ne, lt, le, gt, ge and in.
+func ToSQL(resp *cerbos.PlanResourcesResponse, mapping map[string]string) (string, []any, error) {
+	if resp == nil || resp.PlanResourcesResponse == nil {
+		return "", nil, errors.New("response is nil")
+	}
+
+	switch resp.Kind() {
+	case enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED:
+		return sqlTrue, nil, nil
+	case enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED:
+		return sqlFalse, nil, nil
+	case enginev1.PlanResourcesFilter_KIND_CONDITIONAL:
+		c := &converter{mapping: mapping}
+		cond, err := planwalk.Walk[string](resp.GetFilter().GetCondition(), c)
+		if err != nil {
+			return "", nil, err
+		}
+		return cond, c.args, nil
+	default:
+		return "", nil, fmt.Errorf("unexpected filter kind %s", resp.Kind())
+	}
+}
+
+type converter struct {
+	mapping map[string]string
+	args    []any
+}
+
+func (*converter) And(operands []string) string {
+	return "(" + strings.Join(operands, " AND ") + ")"
+}
+
+func (*converter) Or(operands []string) string {
+	return "(" + strings.Join(operands, " OR ") + ")"
+}
+
+func (*converter) Not(operand string) string {
+	return "NOT " + operand
+}
+
+func (c *converter) In(variable string, values []any) (string, error) {
+	column, err := c.column(variable)
+	if err != nil {
+		return "", err
+	}
+
+	if len(values) == 0 {
+		return sqlFalse, nil
+	}
+
+	c.args = append(c.args, values...)
+	return fmt.Sprintf("%s IN (%s)", column, strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")), nil
+}
+
+func (c *converter) Compare(variable, op string, value any) (string, error) {
+	column, err := c.column(variable)
+	if err != nil {
+		return "", err
+	}
+
+	if value == nil {
+		switch op {
+		case "eq":
+			return column + " IS NULL", nil
+		case "ne":
+			return column + " IS NOT NULL", nil
+		default:
+			return "", fmt.Errorf("operator %q can't be used with null", op)
+		}
+	}
+
+	if _, ok := value.([]any); ok {
+		return "", fmt.Errorf("operator %q can't be used with a list value", op)
+	}
+
+	c.args = append(c.args, value)
+	return fmt.Sprintf("%s %s ?", column, comparisonOps[op]), nil
+}
+
+func (c *converter) column(variable string) (string, error) {
+	if column, ok := c.mapping[variable]; ok {
+		return column, nil
+	}
+
+	attr, ok := strings.CutPrefix(variable, planwalk.ResourceAttrPrefix)
+	if !ok {
+		return "", fmt.Errorf("no mapping for variable %q", variable)
+	}
+
+	if !identifierRegex.MatchString(attr) {
+		return "", fmt.Errorf("no mapping for variable %q and the attribute name is not a valid column name", variable)
+	}
+
+	return attr, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2021-2024 Zenauth Ltd.
+// SPDX-License-Identifier: Apache-2.0
+
+package planfilter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	enginev1 "github.com/cerbos/cerbos/api/genpb/cerbos/engine/v1"
+	responsev1 "github.com/cerbos/cerbos/api/genpb/cerbos/response/v1"
+
+	"github.com/cerbos/cerbos-sdk-go/cerbos"
+	"github.com/cerbos/cerbos-sdk-go/query/planfilter"
+)
+
+func expr(op string, operands ...*enginev1.PlanResourcesFilter_Expression_Operand) *enginev1.PlanResourcesFilter_Expression_Operand {
+	return &enginev1.PlanResourcesFilter_Expression_Operand{
+		Node: &enginev1.PlanResourcesFilter_Expression_Operand_Expression{
+			Expression: &enginev1.PlanResourcesFilter_Expression{Operator: op, Operands: operands},
+		},
+	}
+}
+
+func variable(name string) *enginev1.PlanResourcesFilter_Expression_Operand {
+	return &enginev1.PlanResourcesFilter_Expression_Operand{
+		Node: &enginev1.PlanResourcesFilter_Expression_Operand_Variable{Variable: name},
+	}
+}
+
+func value(t *testing.T, v any) *enginev1.PlanResourcesFilter_Expression_Operand {
+	t.Helper()
+
+	val, err := structpb.NewValue(v)
+	require.NoError(t, err)
+
+	return &enginev1.PlanResourcesFilter_Expression_Operand{
+		Node: &enginev1.PlanResourcesFilter_Expression_Operand_Value{Value: val},
+	}
+}
+
+func plan(kind enginev1.PlanResourcesFilter_Kind, cond *enginev1.PlanResourcesFilter_Expression_Operand) *cerbos.PlanResourcesResponse {
+	return &cerbos.PlanResourcesResponse{
+		PlanResourcesResponse: &responsev1.PlanResourcesResponse{
+			Filter: &enginev1.PlanResourcesFilter{Kind: kind, Condition: cond},
+		},
+	}
+}
+
+func TestToSQL(t *testing.T) {
+	mapping := map[string]string{
+		"request.resource.attr.owner": "lr.owner_id",
+		"request.principal.id":        "viewer_id",
+	}
+
+	testCases := []struct {
+		name     string
+		resp     *cerbos.PlanResourcesResponse
+		wantCond string
+		wantArgs []any
+	}{
+		{
+			name:     "always_allowed",
+			resp:     plan(enginev1.PlanResourcesFilter_KIND_ALWAYS_ALLOWED, nil),
+			wantCond: "1 = 1",
+		},
+		{
+			name:     "always_denied",
+			resp:     plan(enginev1.PlanResourcesFilter_KIND_ALWAYS_DENIED, nil),
+			wantCond: "1 = 0",
+		},
+		{
+			name: "logical",
+			resp: plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, expr("or",
+				expr("and",
+					expr("eq", variable("request.resource.attr.owner"), value(t, "john")),
+					expr("in", variable("request.resource.attr.status"), value(t, []any{"DRAFT", "PENDING"})),
+				),
+				expr("not", variable("request.resource.attr.archived")),
+			)),
+			wantCond: "((lr.owner_id = ? AND status IN (?, ?)) OR NOT archived = ?)",
+			wantArgs: []any{"john", "DRAFT", "PENDING", true},
+		},
+		{
+			name:     "reversed_comparison",
+			resp:     plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, expr("lt", value(t, 10), variable("request.resource.attr.size"))),
+			wantCond: "size > ?",
+			wantArgs: []any{float64(10)},
+		},
+		{
+			name:     "ne",
+			resp:     plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, expr("ne", variable("request.principal.id"), value(t, "jane"))),
+			wantCond: "viewer_id <> ?",
+			wantArgs: []any{"jane"},
+		},
+		{
+			name:     "null",
+			resp:     plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, expr("eq", variable("request.resource.attr.deleted_at"), value(t, nil))),
+			wantCond: "deleted_at IS NULL",
+		},
+		{
+			name:     "empty_in",
+			resp:     plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, expr("in", variable("request.resource.attr.status"), value(t, []any{}))),
+			wantCond: "1 = 0",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cond, args, err := planfilter.ToSQL(tc.resp, mapping)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantCond, cond)
+			require.Equal(t, tc.wantArgs, args)
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for name, cond := range map[string]*enginev1.PlanResourcesFilter_Expression_Operand{
+			"unsupported_operator": expr("hasIntersection", variable("request.resource.attr.tags"), value(t, []any{"a"})),
+			"unmapped_variable":    expr("eq", variable("request.principal.attr.department"), value(t, "marketing")),
+			"invalid_column":       expr("eq", variable("request.resource.attr.a; DROP TABLE x"), value(t, "b")),
+			"in_without_list":      expr("in", variable("request.resource.attr.status"), value(t, "DRAFT")),
+			"two_variables":        expr("eq", variable("request.resource.attr.a"), variable("request.resource.attr.b")),
+			"null_comparison":      expr("lt", variable("request.resource.attr.a"), value(t, nil)),
+			"empty_and":            expr("and"),
+			"empty_or":             expr("or"),
+		} {
+			_, _, err := planfilter.ToSQL(plan(enginev1.PlanResourcesFilter_KIND_CONDITIONAL, cond), mapping)
+			require.Error(t, err, name)
+		}
+
+		_, _, err := planfilter.ToSQL(nil, mapping)
+		require.Error(t, err)
+	})
+}